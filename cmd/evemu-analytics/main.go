@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"game.com/m/internal/parser"
+)
+
+func main() {
+	config, err := parser.ParseArguments(os.Args, "analytics")
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+
+	files, err := parser.ExpandPaths(config.Paths, config.Recursive)
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+
+	var recordings []*parser.EvemuFile
+	for _, file := range files {
+		recording, err := parser.ReadInput(file)
+		if err != nil {
+			fmt.Printf("Ошибка чтения файла %s: %v\n", file, err)
+			os.Exit(1)
+		}
+		recordings = append(recordings, recording)
+	}
+
+	// Сбор распределений
+	analytics := parser.Analyze(recordings, config.Analytics)
+	for _, d := range append(analytics.Holds, analytics.Intervals...) {
+		if config.Analytics.BinWidth > 0 && d.BinWidth > config.Analytics.BinWidth {
+			fmt.Fprintf(os.Stderr, "Предупреждение: %s: столбцов гистограммы больше %d, ширина увеличена до %.6g с\n",
+				d.Name, parser.AnalyticsMaxBins, d.BinWidth)
+		}
+	}
+
+	write := analytics.WriteText
+	switch config.OutputFormat {
+	case "csv":
+		write = analytics.WriteCSV
+	case "json":
+		write = analytics.WriteJSON
+	}
+	if err := parser.WriteReport(config.OutputFile, config.Compress, write); err != nil {
+		fmt.Printf("Ошибка записи: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"game.com/m/internal/parser"
+)
+
+func main() {
+	config, err := parser.ParseArguments(os.Args, "bundle")
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+
+	if config.Bundle.Command == parser.BundlePack {
+		pack(config)
+		return
+	}
+
+	bundle, err := parser.OpenBundle(config.InputFile)
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+	defer bundle.Close()
+
+	switch config.Bundle.Command {
+	case parser.BundleUnpack:
+		if err := bundle.Unpack(config.OutputFile); err != nil {
+			fmt.Printf("Ошибка распаковки: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Распаковано записей: %d в %s\n", len(bundle.Manifest.Clips), config.OutputFile)
+	case parser.BundleList:
+		if config.OutputFormat == "json" {
+			err = bundle.Manifest.WriteJSON(os.Stdout)
+		} else {
+			err = bundle.Manifest.WriteText(os.Stdout)
+		}
+		if err != nil {
+			fmt.Printf("Ошибка вывода: %v\n", err)
+			os.Exit(1)
+		}
+	case parser.BundleVerify:
+		problems := bundle.Verify()
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+		if len(problems) > 0 {
+			// Ненулевой код выхода позволяет проверять архивы в CI
+			bundle.Close()
+			os.Exit(1)
+		}
+		fmt.Printf("Архив в порядке: записей %d\n", len(bundle.Manifest.Clips))
+	}
+}
+
+// pack собирает записи и заметки и записывает архив
+func pack(config parser.Args) {
+	contents, err := parser.CollectBundle(config.Paths)
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+	if config.Bundle.Description != "" {
+		contents.Description = strings.TrimSpace(config.Bundle.Description + "\n\n" + contents.Description)
+	}
+
+	var manifest *parser.BundleManifest
+	err = parser.WriteReport(config.OutputFile, false, func(w io.Writer) error {
+		manifest, err = parser.WriteBundle(w, contents)
+		return err
+	})
+	if err != nil {
+		fmt.Printf("Ошибка записи архива: %v\n", err)
+		os.Exit(1)
+	}
+	if !parser.IsStdio(config.OutputFile) {
+		fmt.Printf("Упаковано записей: %d в %s\n", len(manifest.Clips), config.OutputFile)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"game.com/m/internal/parser"
+)
+
+func main() {
+	config, err := parser.ParseArguments(os.Args, "convert")
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Формат входного файла определяется автоматически
+	base, err := parser.ReadInput(config.InputFile)
+	if err != nil {
+		fmt.Printf("Ошибка чтения файла: %v\n", err)
+		os.Exit(1)
+	}
+
+	// С --sidecar заголовок пишется в отдельный файл, а таблица - без
+	// преамбулы; заголовок сжимается вместе с таблицей
+	if config.Sidecar {
+		header := &parser.EvemuFile{Header: base.Header}
+		compress := config.Compress || parser.HasGzipSuffix(config.OutputFile)
+		if err := header.WriteOutput(parser.CSVSidecarPath(config.OutputFile), compress); err != nil {
+			fmt.Printf("Ошибка записи заголовка: %v\n", err)
+			os.Exit(1)
+		}
+		err = parser.WriteReport(config.OutputFile, config.Compress, func(w io.Writer) error {
+			return base.WriteCSV(w, false)
+		})
+	} else {
+		err = base.WriteOutputFormat(config.OutputFile, config.OutputFormat, config.Compress)
+	}
+	if err != nil {
+		fmt.Printf("Ошибка записи: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"game.com/m/internal/parser"
+)
+
+// Коды завершения как у diff(1): 0 - записи совпадают, 1 - отличаются, 2 - ошибка
+func main() {
+	config, err := parser.ParseArguments(os.Args, "diff")
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(2)
+	}
+
+	before, err := parser.ReadInput(config.InputFile)
+	if err != nil {
+		fmt.Printf("Ошибка чтения старого файла: %v\n", err)
+		os.Exit(2)
+	}
+
+	after, err := parser.ReadInput(config.SecondArg)
+	if err != nil {
+		fmt.Printf("Ошибка чтения нового файла: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Сравнение
+	result := parser.Diff(before, after, config.Diff)
+
+	write := result.WriteText
+	if config.OutputFormat == "json" {
+		write = result.WriteJSON
+	}
+	if err := parser.WriteReport(config.OutputFile, config.Compress, write); err != nil {
+		fmt.Printf("Ошибка записи: %v\n", err)
+		os.Exit(2)
+	}
+
+	if !result.Equal() {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"game.com/m/internal/parser"
+)
+
+func main() {
+	config, err := parser.ParseArguments(os.Args, "dump")
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Формат входного файла определяется автоматически
+	base, err := parser.ReadInput(config.InputFile)
+	if err != nil {
+		fmt.Printf("Ошибка чтения файла: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Читаемый вид, с --compile - формат evemu, с --output-format=json - JSON
+	if err := base.WriteOutputFormat(config.OutputFile, config.OutputFormat, config.Compress); err != nil {
+		fmt.Printf("Ошибка записи: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"game.com/m/internal/parser"
+)
+
+// Коды завершения как у grep(1): 0 - найдены вхождения, 1 - не найдены, 2 - ошибка
+func main() {
+	config, err := parser.ParseArguments(os.Args, "grep")
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(2)
+	}
+
+	files, err := parser.ExpandPaths(config.Paths, config.Recursive)
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(2)
+	}
+
+	found, failed := false, false
+	results := []parser.GrepResult{}
+	for _, file := range files {
+		recording, err := parser.ReadInput(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+			failed = true
+			continue
+		}
+
+		// Поиск
+		matches := recording.Grep(config.Pattern)
+		found = found || len(matches) > 0
+
+		name := file
+		if parser.IsStdio(file) {
+			name = "(stdin)"
+		}
+		switch {
+		case config.OutputFormat == "json":
+			result := parser.GrepResult{File: name, Count: len(matches)}
+			if !config.Count {
+				result.Matches = matches
+			}
+			if !config.List || len(matches) > 0 {
+				results = append(results, result)
+			}
+		case config.Count:
+			fmt.Printf("%s:%d\n", name, len(matches))
+		case config.List:
+			if len(matches) > 0 {
+				fmt.Println(name)
+			}
+		default:
+			for _, match := range matches {
+				fmt.Printf("%s:%d: %.6f %s\n", name, match.Line, match.Time, match.Describe())
+			}
+		}
+	}
+
+	if config.OutputFormat == "json" {
+		if err := parser.WriteGrepJSON(os.Stdout, results); err != nil {
+			fmt.Printf("Ошибка записи: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	switch {
+	case failed:
+		os.Exit(2)
+	case !found:
+		os.Exit(1)
+	}
+}
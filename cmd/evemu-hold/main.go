@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"game.com/m/internal/parser"
+)
+
+func main() {
+	config, err := parser.ParseArguments(os.Args, "hold")
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+
+	base, err := parser.ReadInput(config.InputFile)
+	if err != nil {
+		fmt.Printf("Ошибка чтения файла: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Изменение удержаний
+	adjusted, err := base.AdjustHolds(config.Hold)
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := adjusted.WriteOutputFormat(config.OutputFile, config.OutputFormat, config.Compress); err != nil {
+		fmt.Printf("Ошибка записи: %v\n", err)
+		os.Exit(1)
+	}
+	if !parser.IsStdio(config.OutputFile) {
+		fmt.Fprintf(os.Stderr, "Готово! Удержания изменены в %s\n", config.OutputFile)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"game.com/m/internal/parser"
+)
+
+func main() {
+	config, err := parser.ParseArguments(os.Args, "idle")
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+
+	base, err := parser.ReadInput(config.InputFile)
+	if err != nil {
+		fmt.Printf("Ошибка чтения файла: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Сокращение пауз
+	compressed, err := base.CompressIdle(config.Idle)
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := compressed.WriteOutputFormat(config.OutputFile, config.OutputFormat, config.Compress); err != nil {
+		fmt.Printf("Ошибка записи: %v\n", err)
+		os.Exit(1)
+	}
+	if !parser.IsStdio(config.OutputFile) {
+		fmt.Fprintf(os.Stderr, "Готово! Паузы сокращены в %s\n", config.OutputFile)
+	}
+}
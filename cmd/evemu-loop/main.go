@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"game.com/m/internal/parser"
+)
+
+// С --extract в выходной файл пишется одна итерация, а описание петли - в stderr
+func main() {
+	config, err := parser.ParseArguments(os.Args, "loop")
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+
+	base, err := parser.ReadInput(config.InputFile)
+	if err != nil {
+		fmt.Printf("Ошибка чтения файла: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Поиск петли
+	loop, err := base.FindLoop(config.Loop)
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+
+	write := loop.WriteText
+	if config.OutputFormat == "json" {
+		write = loop.WriteJSON
+	}
+
+	if config.Extract {
+		// С отчётом в JSON итерация тоже записывается в JSON
+		format := parser.FormatEvemu
+		if config.OutputFormat == parser.FormatJSON {
+			format = parser.FormatJSON
+		}
+		if err := base.ExtractLoop(loop).WriteOutputFormat(config.OutputFile, format, config.Compress); err != nil {
+			fmt.Printf("Ошибка записи: %v\n", err)
+			os.Exit(1)
+		}
+		err = write(os.Stderr)
+	} else {
+		err = parser.WriteReport(config.OutputFile, config.Compress, write)
+	}
+	if err != nil {
+		fmt.Printf("Ошибка записи: %v\n", err)
+		os.Exit(1)
+	}
+}
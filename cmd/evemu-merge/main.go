@@ -17,33 +17,29 @@ func main() {
 		fmt.Printf("Ошибка: %v\n", err)
 		os.Exit(1)
 	}
-	var base *parser.EvemuFile
 
-	if parser.IsStdio(config.InputFile) {
-		base, err = parser.ReadFromStdin()
-	} else {
-		base, err = parser.ParseEvemuFile(config.InputFile)
-	}
+	base, err := parser.ReadInput(config.InputFile)
 	if err != nil {
 		fmt.Printf("Ошибка чтения базового файла: %v\n", err)
 		os.Exit(1)
 	}
 
-	add, err := parser.ParseEvemuFile(config.SecondArg)
+	add, err := parser.ReadInput(config.SecondArg)
 	if err != nil {
 		fmt.Printf("Ошибка чтения добавочного файла: %v\n", err)
 		os.Exit(1)
 	}
-	// Мерж
-	merged := base.Merge(add)
-
-	if parser.IsStdio(config.OutputFile) {
-		err = merged.WriteToStdout()
-	} else {
-		err = merged.WriteToFile(config.OutputFile)
+	// Мерж с проверкой совместимости устройств
+	merged, compat, err := base.MergeChecked(add, config.Merge)
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+	for _, mismatch := range compat.Unresolved() {
+		fmt.Fprintf(os.Stderr, "Предупреждение: %s\n", mismatch.Message)
 	}
 
-	if err != nil {
+	if err := merged.WriteOutputFormat(config.OutputFile, config.OutputFormat, config.Compress); err != nil {
 		fmt.Printf("Ошибка записи: %v\n", err)
 		os.Exit(1)
 	}
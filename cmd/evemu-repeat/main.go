@@ -22,7 +22,7 @@ func main() {
 	if parser.IsStdio(config.InputFile) {
 		base, err = parser.ReadFromStdin()
 	} else {
-		base, err = parser.ParseEvemuFile(config.InputFile)
+		base, err = parser.ReadInput(config.InputFile)
 	}
 	if err != nil {
 		fmt.Printf("Ошибка чтения файла: %v\n", err)
@@ -30,19 +30,22 @@ func main() {
 	}
 
 	// Генерация повторений
-	repeated := base.GenerateRepeatedEvents(config.RepeatCount)
-
-	if parser.IsStdio(config.OutputFile) {
-		err = repeated.WriteToStdout()
-	} else {
-		err = repeated.WriteToFile(config.OutputFile)
-	}
-
-	if err != nil {
+	repeated := base.Repeat(parser.RepeatOptions{
+		Count:    config.RepeatCount,
+		Duration: config.Duration,
+		Truncate: config.Truncate,
+		Sanitize: config.Sanitize,
+	})
+
+	if err := repeated.WriteOutputFormat(config.OutputFile, config.OutputFormat, config.Compress); err != nil {
 		fmt.Printf("Ошибка записи: %v\n", err)
 		os.Exit(1)
 	}
 	if !parser.IsStdio(config.OutputFile) {
-		fmt.Fprintf(os.Stderr, "Готово! Сгенерировано %d повторов в %s\n", config.RepeatCount, config.OutputFile)
+		if config.Duration > 0 {
+			fmt.Fprintf(os.Stderr, "Готово! Сгенерировано %.3f с повторов в %s\n", config.Duration, config.OutputFile)
+		} else {
+			fmt.Fprintf(os.Stderr, "Готово! Сгенерировано %d повторов в %s\n", config.RepeatCount, config.OutputFile)
+		}
 	}
 }
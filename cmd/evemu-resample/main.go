@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"game.com/m/internal/parser"
+)
+
+func main() {
+	config, err := parser.ParseArguments(os.Args, "resample")
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+
+	base, err := parser.ReadInput(config.InputFile)
+	if err != nil {
+		fmt.Printf("Ошибка чтения файла: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Передискретизация осей
+	resampled, err := base.Resample(config.Resample)
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := resampled.WriteOutputFormat(config.OutputFile, config.OutputFormat, config.Compress); err != nil {
+		fmt.Printf("Ошибка записи: %v\n", err)
+		os.Exit(1)
+	}
+	if !parser.IsStdio(config.OutputFile) {
+		fmt.Fprintf(os.Stderr, "Готово! Оси передискретизированы в %s\n", config.OutputFile)
+	}
+}
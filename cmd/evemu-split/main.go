@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"game.com/m/internal/parser"
+)
+
+func main() {
+	config, err := parser.ParseArguments(os.Args, "split")
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+
+	base, err := parser.ReadInput(config.InputFile)
+	if err != nil {
+		fmt.Printf("Ошибка чтения файла: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Разбиение на фрагменты
+	segments, err := base.Split(config.Split)
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+
+	prefix := config.Prefix
+	if prefix == "" {
+		prefix = "segment"
+		if !parser.IsStdio(config.InputFile) {
+			name := filepath.Base(config.InputFile)
+			prefix = strings.TrimSuffix(name, filepath.Ext(name))
+		}
+	}
+
+	if err := os.MkdirAll(config.OutputFile, 0755); err != nil {
+		fmt.Printf("Ошибка создания каталога: %v\n", err)
+		os.Exit(1)
+	}
+
+	extension := ".txt"
+	switch config.OutputFormat {
+	case parser.FormatJSON:
+		extension = ".json"
+	case parser.FormatJSONL, parser.FormatJSONLFrames:
+		extension = ".jsonl"
+	case parser.FormatCSV:
+		extension = ".csv"
+	case parser.FormatLibinput:
+		extension = ".yml"
+	case parser.FormatCompact:
+		extension = ".evb"
+	}
+	if _, ok := parser.RawFormatLayout(config.OutputFormat); ok {
+		extension = ".bin"
+	}
+	if config.Compress {
+		extension += parser.GzipSuffix
+	}
+
+	names := make([]string, len(segments))
+	for i, segment := range segments {
+		names[i] = fmt.Sprintf("%s-%03d%s", prefix, i+1, extension)
+		if err := segment.File.WriteOutputFormat(filepath.Join(config.OutputFile, names[i]), config.OutputFormat, config.Compress); err != nil {
+			fmt.Printf("Ошибка записи: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Индекс сжимается вместе с фрагментами
+	index := filepath.Join(config.OutputFile, prefix+"-index"+parser.SplitIndexSuffix)
+	if config.Compress {
+		index += parser.GzipSuffix
+	}
+	err = parser.WriteReport(index, config.Compress, func(w io.Writer) error {
+		return parser.WriteSplitIndex(w, segments, names)
+	})
+	if err != nil {
+		fmt.Printf("Ошибка записи индекса: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Готово! Записано %d фрагментов в %s\n", len(segments), config.OutputFile)
+}
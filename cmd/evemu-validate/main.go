@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"game.com/m/internal/parser"
+)
+
+// Коды завершения: 0 - ошибок нет, 1 - в записи остались ошибки, 2 - сбой утилиты.
+// С --fix исправленная запись пишется в выходной файл, а отчёт - в stderr.
+func main() {
+	config, err := parser.ParseArguments(os.Args, "validate")
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(2)
+	}
+
+	base, err := parser.ReadInput(config.InputFile)
+	if err != nil {
+		fmt.Printf("Ошибка чтения файла: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Проверка
+	report := base.Validate(config.Validate)
+	remaining := report
+	if config.Fix {
+		var fixed *parser.EvemuFile
+		fixed, report = base.Fix(config.Validate)
+		remaining = fixed.Validate(config.Validate)
+
+		// С отчётом в JSON исправленная запись тоже записывается в JSON
+		format := parser.FormatEvemu
+		if config.OutputFormat == parser.FormatJSON {
+			format = parser.FormatJSON
+		}
+		if err := fixed.WriteOutputFormat(config.OutputFile, format, config.Compress); err != nil {
+			fmt.Printf("Ошибка записи: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	write := report.WriteText
+	if config.OutputFormat == "json" {
+		write = report.WriteJSON
+	}
+	if config.Fix {
+		err = write(os.Stderr)
+	} else {
+		err = parser.WriteReport(config.OutputFile, config.Compress, write)
+	}
+	if err != nil {
+		fmt.Printf("Ошибка записи: %v\n", err)
+		os.Exit(2)
+	}
+
+	if remaining.HasErrors() {
+		os.Exit(1)
+	}
+}
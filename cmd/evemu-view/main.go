@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"game.com/m/internal/parser"
+)
+
+func main() {
+	config, err := parser.ParseArguments(os.Args, "view")
+	if err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+
+	base, err := parser.ReadInput(config.InputFile)
+	if err != nil {
+		fmt.Printf("Ошибка чтения файла: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Вне терминала выводим всю запись страницами подряд
+	timeline := base.Timeline()
+	if !isTerminal(os.Stdout) {
+		if err := timeline.WriteViewPages(os.Stdout, config.View); err != nil {
+			fmt.Printf("Ошибка записи: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Если запись пришла через stdin, команды читаются с терминала
+	var commands io.Reader = os.Stdin
+	if parser.IsStdio(config.InputFile) {
+		tty, err := os.Open("/dev/tty")
+		if err != nil {
+			fmt.Printf("Ошибка открытия терминала: %v\n", err)
+			os.Exit(1)
+		}
+		defer tty.Close()
+		commands = tty
+	}
+
+	if err := timeline.RunViewPager(commands, os.Stdout, config.View); err != nil {
+		fmt.Printf("Ошибка: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// isTerminal проверяет, подключён ли файл к терминалу
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
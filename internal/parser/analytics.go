@@ -0,0 +1,315 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AnalyticsMaxBins - наибольшее число столбцов гистограммы; при более узких
+// столбцах ширина увеличивается
+const AnalyticsMaxBins = 10000
+
+// AnalyticsPercentiles - перцентили, вычисляемые для каждого распределения
+var AnalyticsPercentiles = []float64{50, 90, 95, 99}
+
+// Метрики распределений
+const (
+	MetricHold     = "hold"     // длительность удержания кнопки
+	MetricInterval = "interval" // время между событиями пары
+)
+
+// EventPair - пара событий, время между которыми измеряется.
+// Ограничение within у второго шага отбрасывает более длинные интервалы.
+type EventPair struct {
+	Name string
+	From PatternStep
+	To   PatternStep
+}
+
+// AnalyticsOptions задаёт параметры сбора распределений
+type AnalyticsOptions struct {
+	Pairs    []EventPair
+	BinWidth float64 // ширина столбца гистограммы, секунды (0 - подбирается автоматически)
+}
+
+// Percentile - значение перцентиля P
+type Percentile struct {
+	P     float64 `json:"p"`
+	Value float64 `json:"value"`
+}
+
+// HistogramBin - столбец гистограммы [Start, End)
+type HistogramBin struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Count int     `json:"count"`
+}
+
+// Distribution - распределение значений в секундах
+type Distribution struct {
+	Metric      string         `json:"metric"`
+	Name        string         `json:"name"`
+	Count       int            `json:"count"`
+	Min         float64        `json:"min"`
+	Max         float64        `json:"max"`
+	Mean        float64        `json:"mean"`
+	StdDev      float64        `json:"stddev"`
+	Percentiles []Percentile   `json:"percentiles"`
+	BinWidth    float64        `json:"bin_width"`
+	Histogram   []HistogramBin `json:"histogram"`
+}
+
+// Analytics содержит распределения по одной или нескольким записям
+type Analytics struct {
+	Files     int            `json:"files"`
+	Holds     []Distribution `json:"holds"`
+	Intervals []Distribution `json:"intervals"`
+}
+
+// ParseEventPairs разбирает пары вида "press LB -> press A; press A -> press A within 100ms"
+func ParseEventPairs(text string) ([]EventPair, error) {
+	var pairs []EventPair
+	for _, item := range strings.Split(text, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(item, "->")
+		if !ok {
+			return nil, fmt.Errorf("ожидается <событие> -> <событие>: %s", item)
+		}
+		fromStep, err := parseStep(strings.Fields(from))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", item, err)
+		}
+		toStep, err := parseStep(strings.Fields(to))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", item, err)
+		}
+		if fromStep.Within > 0 || fromStep.For > 0 || toStep.For > 0 {
+			return nil, fmt.Errorf("%s: допускается только within у второго события", item)
+		}
+		pairs = append(pairs, EventPair{
+			Name: strings.Join(strings.Fields(item), " "),
+			From: fromStep,
+			To:   toStep,
+		})
+	}
+	return pairs, nil
+}
+
+// Analyze собирает распределения длительности удержания каждой кнопки и
+// интервалов для заданных пар событий по всем записям. Интервал пары
+// отсчитывается от последнего первого события перед вторым; нажатия без
+// отпускания в распределения удержания не входят.
+func Analyze(files []*EvemuFile, opts AnalyticsOptions) *Analytics {
+	holds := make(map[int][]float64)
+	intervals := make([][]float64, len(opts.Pairs))
+
+	for _, f := range files {
+		pressedAt := make(map[int]float64)
+		lastFrom := make([]float64, len(opts.Pairs))
+		for i := range lastFrom {
+			lastFrom[i] = math.NaN()
+		}
+
+		for _, event := range f.Events {
+			if event.TypeNum() == EvKey {
+				code := event.CodeNum()
+				switch event.IntValue() {
+				case 1:
+					if _, held := pressedAt[code]; !held {
+						pressedAt[code] = event.Timestamp
+					}
+				case 0:
+					if start, held := pressedAt[code]; held {
+						holds[code] = append(holds[code], event.Timestamp-start)
+						delete(pressedAt, code)
+					}
+				}
+			}
+
+			for i, pair := range opts.Pairs {
+				if pair.To.matches(event) && !math.IsNaN(lastFrom[i]) {
+					interval := event.Timestamp - lastFrom[i]
+					if pair.To.Within == 0 || interval <= pair.To.Within {
+						intervals[i] = append(intervals[i], interval)
+					}
+					lastFrom[i] = math.NaN()
+				}
+				if pair.From.matches(event) {
+					lastFrom[i] = event.Timestamp
+				}
+			}
+		}
+	}
+
+	result := &Analytics{Files: len(files), Holds: []Distribution{}, Intervals: []Distribution{}}
+	var codes []int
+	for code := range holds {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		result.Holds = append(result.Holds, NewDistribution(MetricHold, CodeLabel(EvKey, code), holds[code], opts.BinWidth))
+	}
+	for i, pair := range opts.Pairs {
+		result.Intervals = append(result.Intervals, NewDistribution(MetricInterval, pair.Name, intervals[i], opts.BinWidth))
+	}
+	return result
+}
+
+// NewDistribution вычисляет сводку, перцентили и гистограмму выборки. Если
+// при ширине binWidth столбцов больше AnalyticsMaxBins, ширина увеличивается
+// в целое число раз; использованная ширина записывается в BinWidth.
+func NewDistribution(metric, name string, samples []float64, binWidth float64) Distribution {
+	d := Distribution{Metric: metric, Name: name, Count: len(samples), Percentiles: []Percentile{}, Histogram: []HistogramBin{}}
+	if len(samples) == 0 {
+		return d
+	}
+
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+	d.Min, d.Max = sorted[0], sorted[len(sorted)-1]
+
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+	d.Mean = sum / float64(len(sorted))
+	variance := 0.0
+	for _, v := range sorted {
+		variance += (v - d.Mean) * (v - d.Mean)
+	}
+	d.StdDev = math.Sqrt(variance / float64(len(sorted)))
+
+	for _, p := range AnalyticsPercentiles {
+		d.Percentiles = append(d.Percentiles, Percentile{P: p, Value: percentile(sorted, p)})
+	}
+
+	if binWidth <= 0 {
+		binWidth = 0.001
+		if d.Max > d.Min {
+			binWidth = math.Max(rulerStep(d.Max-d.Min), 0.001)
+		}
+	}
+	// Границы столбцов кратны ширине; малая поправка защищает от ошибок округления
+	first := math.Floor(d.Min/binWidth + 1e-9)
+	last := math.Floor(d.Max/binWidth + 1e-9)
+	for bins := last - first + 1; bins > AnalyticsMaxBins; bins = last - first + 1 {
+		binWidth *= math.Ceil(bins / AnalyticsMaxBins)
+		first = math.Floor(d.Min/binWidth + 1e-9)
+		last = math.Floor(d.Max/binWidth + 1e-9)
+	}
+	d.BinWidth = binWidth
+	for i := first; i <= last; i++ {
+		d.Histogram = append(d.Histogram, HistogramBin{Start: i * binWidth, End: (i + 1) * binWidth})
+	}
+	for _, v := range sorted {
+		bin := int(math.Floor(v/binWidth+1e-9) - first)
+		d.Histogram[bin].Count++
+	}
+	return d
+}
+
+// percentile вычисляет перцентиль отсортированной выборки с линейной интерполяцией
+func percentile(sorted []float64, p float64) float64 {
+	position := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(position-float64(lower))
+}
+
+// WriteText записывает распределения таблицами; время в миллисекундах
+func (a *Analytics) WriteText(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	fmt.Fprintf(writer, "Записей: %d\n", a.Files)
+	writeDistributions(writer, "Удержание кнопок, мс", a.Holds)
+	writeDistributions(writer, "Интервалы между событиями, мс", a.Intervals)
+	return writer.Flush()
+}
+
+// writeDistributions выводит таблицу сводок и гистограммы распределений
+func writeDistributions(w io.Writer, title string, distributions []Distribution) {
+	if len(distributions) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s:\n", title)
+	fmt.Fprintf(w, "  %-28s %6s %8s %8s %8s", "", "кол-во", "мин", "сред", "откл")
+	for _, p := range AnalyticsPercentiles {
+		fmt.Fprintf(w, " %8s", "p"+strconv.FormatFloat(p, 'f', -1, 64))
+	}
+	fmt.Fprintf(w, " %8s\n", "макс")
+
+	for _, d := range distributions {
+		fmt.Fprintf(w, "  %-28s %6d %8.1f %8.1f %8.1f", d.Name, d.Count, d.Min*1000, d.Mean*1000, d.StdDev*1000)
+		for _, p := range d.Percentiles {
+			fmt.Fprintf(w, " %8.1f", p.Value*1000)
+		}
+		fmt.Fprintf(w, " %8.1f\n", d.Max*1000)
+	}
+
+	for _, d := range distributions {
+		if len(d.Histogram) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n  %s:\n", d.Name)
+		peak := 0
+		for _, bin := range d.Histogram {
+			peak = max(peak, bin.Count)
+		}
+		for _, bin := range d.Histogram {
+			bar := strings.Repeat("█", int(math.Round(float64(bin.Count)/float64(peak)*40)))
+			fmt.Fprintf(w, "    %8.1f-%-8.1f %5d %s\n", bin.Start*1000, bin.End*1000, bin.Count, bar)
+		}
+	}
+}
+
+// WriteCSV записывает распределения в длинном формате:
+// metric,name,kind,key,value. Строки kind=stat содержат сводку
+// (count, min, mean, stddev, p50..., max), строки kind=bin - гистограмму,
+// где key - начало столбца. Время в секундах.
+func (a *Analytics) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"metric", "name", "kind", "key", "value"})
+
+	seconds := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', 6, 64)
+	}
+	for _, d := range append(append([]Distribution(nil), a.Holds...), a.Intervals...) {
+		stat := func(key, value string) {
+			writer.Write([]string{d.Metric, d.Name, "stat", key, value})
+		}
+		stat("count", strconv.Itoa(d.Count))
+		if d.Count == 0 {
+			continue
+		}
+		stat("min", seconds(d.Min))
+		stat("mean", seconds(d.Mean))
+		stat("stddev", seconds(d.StdDev))
+		for _, p := range d.Percentiles {
+			stat("p"+strconv.FormatFloat(p.P, 'f', -1, 64), seconds(p.Value))
+		}
+		stat("max", seconds(d.Max))
+		for _, bin := range d.Histogram {
+			writer.Write([]string{d.Metric, d.Name, "bin", seconds(bin.Start), strconv.Itoa(bin.Count)})
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSON записывает распределения в формате JSON
+func (a *Analytics) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(a)
+}
@@ -0,0 +1,193 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestParseEventPairs тестирует разбор пар событий
+func TestParseEventPairs(t *testing.T) {
+	pairs, err := ParseEventPairs("press LB -> press A;  press A  ->  press A within 300ms ;")
+	if err != nil {
+		t.Fatalf("ParseEventPairs failed: %v", err)
+	}
+	if len(pairs) != 2 {
+		t.Fatalf("Expected 2 pairs, got %+v", pairs)
+	}
+	if pairs[0].From.Code != 0x136 || pairs[0].To.Code != 0x130 || pairs[0].Name != "press LB -> press A" {
+		t.Errorf("Unexpected first pair: %+v", pairs[0])
+	}
+	if pairs[1].To.Within != 0.3 || pairs[1].Name != "press A -> press A within 300ms" {
+		t.Errorf("Unexpected second pair: %+v", pairs[1])
+	}
+
+	for _, text := range []string{"press A", "press A -> hold B for 1s", "press A within 1s -> press B", "press NOPE -> press A"} {
+		if _, err := ParseEventPairs(text); err == nil {
+			t.Errorf("Expected error for %q", text)
+		}
+	}
+}
+
+// TestNewDistribution тестирует сводку, перцентили и гистограмму
+func TestNewDistribution(t *testing.T) {
+	d := NewDistribution(MetricHold, "BTN_SOUTH", []float64{0.04, 0.01, 0.03, 0.02, 0.05}, 0.02)
+	if d.Count != 5 || d.Min != 0.01 || d.Max != 0.05 || math.Abs(d.Mean-0.03) > 1e-9 {
+		t.Errorf("Unexpected summary: %+v", d)
+	}
+	if math.Abs(d.StdDev-math.Sqrt(0.0002)) > 1e-9 {
+		t.Errorf("Unexpected stddev: %f", d.StdDev)
+	}
+	if math.Abs(d.Percentiles[0].Value-0.03) > 1e-9 || math.Abs(d.Percentiles[1].Value-0.046) > 1e-9 {
+		t.Errorf("Unexpected percentiles: %+v", d.Percentiles)
+	}
+
+	counts := []int{}
+	for _, bin := range d.Histogram {
+		counts = append(counts, bin.Count)
+	}
+	// Столбцы [0, 20), [20, 40), [40, 60) мс
+	if len(counts) != 3 || counts[0] != 1 || counts[1] != 2 || counts[2] != 2 || math.Abs(d.Histogram[2].Start-0.04) > 1e-9 {
+		t.Errorf("Unexpected histogram: %+v", d.Histogram)
+	}
+
+	// Слишком узкие столбцы расширяются до AnalyticsMaxBins
+	for _, width := range []float64{1e-6, 1e-9} {
+		wide := NewDistribution(MetricHold, "BTN_SOUTH", []float64{0.01, 2.5, 7.3}, width)
+		if len(wide.Histogram) > AnalyticsMaxBins || wide.BinWidth < 7.29/AnalyticsMaxBins {
+			t.Errorf("width %g: %d bins of %g", width, len(wide.Histogram), wide.BinWidth)
+		}
+		if ratio := wide.BinWidth / width; math.Abs(ratio-math.Round(ratio)) > 1e-6*ratio {
+			t.Errorf("width %g: widened to %g, not a multiple", width, wide.BinWidth)
+		}
+	}
+	if d.BinWidth != 0.02 {
+		t.Errorf("Expected requested bin width, got %g", d.BinWidth)
+	}
+
+	empty := NewDistribution(MetricInterval, "none", nil, 0)
+	if empty.Count != 0 || len(empty.Histogram) != 0 || len(empty.Percentiles) != 0 {
+		t.Errorf("Unexpected empty distribution: %+v", empty)
+	}
+}
+
+// TestAnalyze тестирует сбор удержаний и интервалов по нескольким записям
+func TestAnalyze(t *testing.T) {
+	pairs, err := ParseEventPairs("press LB -> press A; press LB -> press A within 150ms")
+	if err != nil {
+		t.Fatalf("ParseEventPairs failed: %v", err)
+	}
+	f := newGrepTestFile(t)
+	a := Analyze([]*EvemuFile{f, f}, AnalyticsOptions{Pairs: pairs, BinWidth: 0.05})
+
+	if a.Files != 2 || len(a.Holds) != 2 || len(a.Intervals) != 2 {
+		t.Fatalf("Unexpected analytics: %+v", a)
+	}
+	// BTN_SOUTH (0x130) идёт раньше BTN_TL (0x136)
+	south, tl := a.Holds[0], a.Holds[1]
+	if south.Name != "BTN_SOUTH" || south.Count != 6 || math.Abs(south.Min-0.05) > 1e-9 || math.Abs(south.Max-0.1) > 1e-9 {
+		t.Errorf("Unexpected BTN_SOUTH holds: %+v", south)
+	}
+	if tl.Name != "BTN_TL" || tl.Count != 6 || math.Abs(tl.Max-0.6) > 1e-9 {
+		t.Errorf("Unexpected BTN_TL holds: %+v", tl)
+	}
+
+	// Интервалы LB -> A: 0.1, 0.1, 0.5 в каждой записи
+	all, limited := a.Intervals[0], a.Intervals[1]
+	if all.Count != 6 || math.Abs(all.Max-0.5) > 1e-9 || all.Metric != MetricInterval {
+		t.Errorf("Unexpected intervals: %+v", all)
+	}
+	if limited.Count != 4 || math.Abs(limited.Max-0.1) > 1e-9 {
+		t.Errorf("Unexpected limited intervals: %+v", limited)
+	}
+}
+
+// TestAnalyzeDirectory тестирует сбор по каталогу записей с заметками,
+// README, заголовками CSV, архивом и картинкой: читаются только записи
+func TestAnalyzeDirectory(t *testing.T) {
+	dir := writeBundleTestDir(t)
+	bundle, err := os.ReadFile(packTestBundle(t, dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	extra := map[string][]byte{
+		"set.zip":   bundle,
+		"cover.png": {0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0, 0, 0, 0x0d},
+	}
+	for name, data := range extra {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := ExpandPaths([]string{dir}, true)
+	if err != nil {
+		t.Fatalf("ExpandPaths failed: %v", err)
+	}
+	var recordings []*EvemuFile
+	for _, file := range files {
+		recording, err := ReadInput(file)
+		if err != nil {
+			t.Fatalf("ReadInput(%s) failed: %v", file, err)
+		}
+		recordings = append(recordings, recording)
+	}
+	// combo, jump и table в каталоге и те же три записи в архиве
+	if a := Analyze(recordings, AnalyticsOptions{}); a.Files != 6 {
+		t.Errorf("Expected 6 recordings, got %d: %v", a.Files, files)
+	}
+}
+
+// TestAnalyticsWrite тестирует вывод в тексте, CSV и JSON
+func TestAnalyticsWrite(t *testing.T) {
+	pairs, _ := ParseEventPairs("press LB -> press A")
+	a := Analyze([]*EvemuFile{newGrepTestFile(t)}, AnalyticsOptions{Pairs: pairs})
+
+	var text bytes.Buffer
+	if err := a.WriteText(&text); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	for _, expected := range []string{"Записей: 1", "Удержание кнопок, мс", "BTN_SOUTH", "press LB -> press A", "p99", "█"} {
+		if !strings.Contains(text.String(), expected) {
+			t.Errorf("Text output missing %q:\n%s", expected, text.String())
+		}
+	}
+
+	var table bytes.Buffer
+	if err := a.WriteCSV(&table); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	records, err := csv.NewReader(&table).ReadAll()
+	if err != nil {
+		t.Fatalf("Invalid CSV: %v", err)
+	}
+	if strings.Join(records[0], ",") != "metric,name,kind,key,value" {
+		t.Errorf("Unexpected CSV header: %v", records[0])
+	}
+	found := false
+	for _, record := range records {
+		if strings.Join(record, ",") == "interval,press LB -> press A,stat,count,3" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("CSV missing interval count:\n%v", records)
+	}
+
+	var data bytes.Buffer
+	if err := a.WriteJSON(&data); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	var decoded Analytics
+	if err := json.Unmarshal(data.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if decoded.Files != 1 || len(decoded.Holds) != 2 || decoded.Intervals[0].Count != 3 {
+		t.Errorf("Unexpected decoded analytics: %+v", decoded)
+	}
+}
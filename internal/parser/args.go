@@ -3,6 +3,8 @@ package parser
 import (
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Args struct {
@@ -10,36 +12,175 @@ type Args struct {
 	SecondArg   string
 	OutputFile  string
 	RepeatCount int
+	Duration    float64
+	Truncate    bool
+	Options     map[string]string
+	Hold        HoldOptions
+	Resample    ResampleOptions
+	Sanitize    *SanitizeOptions
+	Idle        IdleOptions
+	Split       SplitOptions
+	Prefix      string
+	Diff        DiffOptions
+	Validate    ValidateOptions
+	Fix         bool
+	SVG         SVGOptions
+	View        ViewOptions
+	Compile     bool
+	Loop        LoopOptions
+	Extract     bool
+	Pattern     *Pattern
+	Paths       []string
+	Recursive   bool
+	Count       bool
+	List        bool
+	Analytics   AnalyticsOptions
+	Merge       MergeOptions
+	Sidecar     bool
+	Compress    bool
+	Bundle      BundleOptions
+
+	OutputFormat string
 }
 
+// ParseArguments разбирает аргументы утилиты. Флаг --compress (сжать вывод
+// gzip) принимают все утилиты, кроме evemu-view и evemu-grep, которые
+// пишут только в терминал, и evemu-bundle, архивы которого уже сжаты.
 func ParseArguments(args []string, utilityType string) (Args, error) {
+	compress := false
+	if utilityType != "view" && utilityType != "grep" && utilityType != "bundle" {
+		args, compress = cutFlag(args, "--compress")
+	}
+	result, err := parseUtilityArguments(args, utilityType)
+	result.Compress = compress && err == nil
+	return result, err
+}
+
+func parseUtilityArguments(args []string, utilityType string) (Args, error) {
 	switch utilityType {
 	case "merge":
 		return parseMergeArguments(args)
 	case "repeat":
 		return parseRepeatArguments(args)
+	case "hold":
+		return parseHoldArguments(args)
+	case "resample":
+		return parseResampleArguments(args)
+	case "sanitize":
+		return parseSanitizeArguments(args)
+	case "idle":
+		return parseIdleArguments(args)
+	case "split":
+		return parseSplitArguments(args)
+	case "stats":
+		return parseStatsArguments(args)
+	case "diff":
+		return parseDiffArguments(args)
+	case "validate":
+		return parseValidateArguments(args)
+	case "svg":
+		return parseSVGArguments(args)
+	case "view":
+		return parseViewArguments(args)
+	case "dump":
+		return parseDumpArguments(args)
+	case "loop":
+		return parseLoopArguments(args)
+	case "grep":
+		return parseGrepArguments(args)
+	case "analytics":
+		return parseAnalyticsArguments(args)
+	case "convert":
+		return parseConvertArguments(args)
+	case "bundle":
+		return parseBundleArguments(args)
 	default:
 		return Args{InputFile: "-", SecondArg: args[1], OutputFile: "-", RepeatCount: 0}, fmt.Errorf("неизвестный тип утилиты: %s", utilityType)
 	}
 }
 
 func parseMergeArguments(args []string) (Args, error) {
-	if len(args) < 2 || len(args) > 4 {
-		return Args{}, fmt.Errorf("использование: merge_events [базовый файл] <добавочный файл> [итоговый файл]")
+	usage := fmt.Errorf("использование: merge_events [--header=base|add] [--on-mismatch=fail|warn] [--rescale] [--output-format=<формат записи>] [базовый файл] <добавочный файл> [итоговый файл]")
+	args, options := splitOptions(args)
+	if err := checkOptions(options, "header", "on-mismatch", "rescale", "output-format"); err != nil {
+		return Args{}, err
 	}
 
-	if len(args) == 2 {
-		return Args{InputFile: "-", SecondArg: args[1], OutputFile: "-", RepeatCount: 0}, nil
-	} else if len(args) == 3 {
-		return Args{InputFile: args[1], SecondArg: args[2], OutputFile: "-", RepeatCount: 0}, nil
-	} else {
-		return Args{InputFile: args[1], SecondArg: args[2], OutputFile: args[3], RepeatCount: 0}, nil
+	var result Args
+	switch len(args) {
+	case 2:
+		result = Args{InputFile: "-", SecondArg: args[1], OutputFile: "-", RepeatCount: 0}
+	case 3:
+		result = Args{InputFile: args[1], SecondArg: args[2], OutputFile: "-", RepeatCount: 0}
+	case 4:
+		result = Args{InputFile: args[1], SecondArg: args[2], OutputFile: args[3], RepeatCount: 0}
+	default:
+		return Args{}, usage
+	}
+	result.Options = options
+
+	switch value := options["header"]; value {
+	case "", HeaderBase:
+		result.Merge.Header = HeaderBase
+	case HeaderAdd:
+		result.Merge.Header = HeaderAdd
+	default:
+		return Args{}, fmt.Errorf("неизвестный заголовок: %s (ожидается base или add)", value)
+	}
+	switch value := options["on-mismatch"]; value {
+	case "", "fail":
+	case "warn":
+		result.Merge.Warn = true
+	default:
+		return Args{}, fmt.Errorf("неизвестная реакция на несовместимость: %s (ожидается fail или warn)", value)
+	}
+	_, result.Merge.Rescale = options["rescale"]
+
+	var err error
+	if result.OutputFormat, err = parseRecordingFormat(options["output-format"]); err != nil {
+		return Args{}, err
 	}
+	return result, nil
 }
 
 func parseRepeatArguments(args []string) (Args, error) {
+	args, options := splitOptions(args)
+	if err := checkOptions(options, "duration", "truncate", "sanitize", "output-format"); err != nil {
+		return Args{}, err
+	}
+
+	var sanitize *SanitizeOptions
+	if value, ok := options["sanitize"]; ok {
+		rest, err := parseRestMode(value)
+		if err != nil {
+			return Args{}, err
+		}
+		sanitize = &SanitizeOptions{Rest: rest}
+	}
+
+	var result Args
+	var err error
+	if value, ok := options["duration"]; ok {
+		result, err = parseRepeatDurationArguments(args, options, value)
+	} else if _, ok := options["truncate"]; ok {
+		return Args{}, fmt.Errorf("--truncate требует --duration")
+	} else {
+		result, err = parseRepeatCountArguments(args)
+	}
+	if err != nil {
+		return Args{}, err
+	}
+
+	result.Sanitize = sanitize
+	if result.OutputFormat, err = parseRecordingFormat(options["output-format"]); err != nil {
+		return Args{}, err
+	}
+	return result, nil
+}
+
+func parseRepeatCountArguments(args []string) (Args, error) {
 	if len(args) < 2 || len(args) > 4 {
-		return Args{}, fmt.Errorf("использование: repeat_events [входной файл] <количество повторов> [выходной файл]")
+		return Args{}, fmt.Errorf("использование: repeat_events [--output-format=<формат записи>] [входной файл] <количество повторов> [выходной файл]")
 	}
 
 	if len(args) == 2 {
@@ -51,6 +192,755 @@ func parseRepeatArguments(args []string) (Args, error) {
 	}
 }
 
+func parseRepeatDurationArguments(args []string, options map[string]string, value string) (Args, error) {
+	usage := fmt.Errorf("использование: repeat_events --duration=<длительность> [--truncate] [--output-format=<формат записи>] [входной файл] [выходной файл]")
+	result, err := parseInputOutput(args, options, usage)
+	if err != nil {
+		return Args{}, err
+	}
+
+	if result.Duration, err = parseDuration(value); err != nil {
+		return Args{}, err
+	}
+	_, result.Truncate = options["truncate"]
+	return result, nil
+}
+
+func parseHoldArguments(args []string) (Args, error) {
+	usage := fmt.Errorf("использование: evemu-hold --buttons=<кнопки> (--add=<время>|--set=<время>|--scale=<проценты>) [--shift] [--output-format=<формат записи>] [входной файл] [выходной файл]")
+	args, options := splitOptions(args)
+	if err := checkOptions(options, "buttons", "add", "set", "scale", "shift", "output-format"); err != nil {
+		return Args{}, err
+	}
+
+	result, err := parseInputOutput(args, options, usage)
+	if err != nil {
+		return Args{}, err
+	}
+
+	codes, ok := ParseKeyList(options["buttons"])
+	if !ok {
+		return Args{}, fmt.Errorf("некорректный список кнопок: %s", options["buttons"])
+	}
+	result.Hold.Codes = codes
+	_, result.Hold.Shift = options["shift"]
+
+	modes := 0
+	if value, ok := options["add"]; ok {
+		modes++
+		result.Hold.Mode = HoldAdd
+		if result.Hold.Amount, err = parseSignedDuration(value); err != nil {
+			return Args{}, err
+		}
+	}
+	if value, ok := options["set"]; ok {
+		modes++
+		result.Hold.Mode = HoldSet
+		if result.Hold.Amount, err = parseDuration(value); err != nil {
+			return Args{}, err
+		}
+	}
+	if value, ok := options["scale"]; ok {
+		modes++
+		result.Hold.Mode = HoldScale
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil || percent <= 0 {
+			return Args{}, fmt.Errorf("некорректный процент: %s", value)
+		}
+		result.Hold.Amount = percent
+	}
+	if modes != 1 {
+		return Args{}, usage
+	}
+
+	if result.OutputFormat, err = parseRecordingFormat(options["output-format"]); err != nil {
+		return Args{}, err
+	}
+	return result, nil
+}
+
+func parseResampleArguments(args []string) (Args, error) {
+	usage := fmt.Errorf("использование: evemu-resample [--rate=<Гц>] [--interp=linear|cubic] [--smooth=none|average|lowpass] [--window=<отсчёты>] [--cutoff=<Гц>] [--hold-gap=<время>] [--axes=<оси>] [--output-format=<формат записи>] [входной файл] [выходной файл]")
+	args, options := splitOptions(args)
+	if err := checkOptions(options, "rate", "interp", "smooth", "window", "cutoff", "hold-gap", "axes", "output-format"); err != nil {
+		return Args{}, err
+	}
+
+	result, err := parseInputOutput(args, options, usage)
+	if err != nil {
+		return Args{}, err
+	}
+
+	opts := ResampleOptions{Rate: 125, Window: 5, Cutoff: 10}
+	if value, ok := options["rate"]; ok {
+		if opts.Rate, err = parsePositiveFloat(value); err != nil {
+			return Args{}, err
+		}
+	}
+
+	switch options["interp"] {
+	case "", "linear":
+		opts.Interpolation = InterpolateLinear
+	case "cubic":
+		opts.Interpolation = InterpolateCubic
+	default:
+		return Args{}, fmt.Errorf("неизвестный способ интерполяции: %s", options["interp"])
+	}
+
+	switch options["smooth"] {
+	case "", "none":
+		opts.Smoothing = SmoothNone
+	case "average":
+		opts.Smoothing = SmoothMovingAverage
+	case "lowpass":
+		opts.Smoothing = SmoothLowPass
+	default:
+		return Args{}, fmt.Errorf("неизвестный способ сглаживания: %s", options["smooth"])
+	}
+
+	if value, ok := options["window"]; ok {
+		window, err := strconv.Atoi(value)
+		if err != nil || window < 1 || window%2 == 0 {
+			return Args{}, fmt.Errorf("некорректное окно сглаживания: %s (ожидается нечётное число отсчётов)", value)
+		}
+		opts.Window = window
+	}
+	if value, ok := options["cutoff"]; ok {
+		if opts.Cutoff, err = parsePositiveFloat(value); err != nil {
+			return Args{}, err
+		}
+	}
+	if value, ok := options["hold-gap"]; ok {
+		if opts.HoldGap, err = parseDuration(value); err != nil {
+			return Args{}, err
+		}
+	}
+	if value, ok := options["axes"]; ok {
+		axes, ok := ParseCodeList(EvAbs, value)
+		if !ok {
+			return Args{}, fmt.Errorf("некорректный список осей: %s", value)
+		}
+		opts.Axes = axes
+	}
+
+	result.Resample = opts
+	if result.OutputFormat, err = parseRecordingFormat(options["output-format"]); err != nil {
+		return Args{}, err
+	}
+	return result, nil
+}
+
+func parseSanitizeArguments(args []string) (Args, error) {
+	usage := fmt.Errorf("использование: evemu-sanitize [--rest=center|initial] [--output-format=<формат записи>] [входной файл] [выходной файл]")
+	args, options := splitOptions(args)
+	if err := checkOptions(options, "rest", "output-format"); err != nil {
+		return Args{}, err
+	}
+
+	result, err := parseInputOutput(args, options, usage)
+	if err != nil {
+		return Args{}, err
+	}
+
+	rest, err := parseRestMode(options["rest"])
+	if err != nil {
+		return Args{}, err
+	}
+	result.Sanitize = &SanitizeOptions{Rest: rest}
+	if result.OutputFormat, err = parseRecordingFormat(options["output-format"]); err != nil {
+		return Args{}, err
+	}
+	return result, nil
+}
+
+func parseIdleArguments(args []string) (Args, error) {
+	usage := fmt.Errorf("использование: evemu-idle [--threshold=<время>] [--max-gap=<время>] [--between=<начало>,<конец>] [--output-format=<формат записи>] [входной файл] [выходной файл]")
+	args, options := splitOptions(args)
+	if err := checkOptions(options, "threshold", "max-gap", "between", "output-format"); err != nil {
+		return Args{}, err
+	}
+
+	result, err := parseInputOutput(args, options, usage)
+	if err != nil {
+		return Args{}, err
+	}
+
+	opts := IdleOptions{Threshold: 2, MaxGap: 0.5}
+	if value, ok := options["threshold"]; ok {
+		if opts.Threshold, err = parseDuration(value); err != nil {
+			return Args{}, err
+		}
+	}
+	if value, ok := options["max-gap"]; ok {
+		if opts.MaxGap, err = parseNonNegativeDuration(value); err != nil {
+			return Args{}, err
+		}
+	}
+	if value, ok := options["between"]; ok {
+		start, end, found := strings.Cut(value, ",")
+		if !found || (start == "" && end == "") {
+			return Args{}, fmt.Errorf("ожидается пара маркеров <начало>,<конец>: %s", value)
+		}
+		opts.StartMarker, opts.EndMarker = start, end
+	}
+	if opts.MaxGap > opts.Threshold {
+		return Args{}, fmt.Errorf("--max-gap не может быть больше --threshold")
+	}
+
+	result.Idle = opts
+	if result.OutputFormat, err = parseRecordingFormat(options["output-format"]); err != nil {
+		return Args{}, err
+	}
+	return result, nil
+}
+
+func parseSplitArguments(args []string) (Args, error) {
+	usage := fmt.Errorf("использование: evemu-split [--idle=<время> | --delimiter=<кнопка> [--every=<N>]] [--prefix=<имя>] [--sanitize[=center|initial]] [--output-format=<формат записи>] [входной файл] [каталог]")
+	args, options := splitOptions(args)
+	if err := checkOptions(options, "idle", "delimiter", "every", "prefix", "sanitize", "output-format"); err != nil {
+		return Args{}, err
+	}
+
+	result, err := parseInputOutput(args, options, usage)
+	if err != nil {
+		return Args{}, err
+	}
+	if len(args) < 3 {
+		result.OutputFile = "."
+	}
+
+	_, hasIdle := options["idle"]
+	delimiter, hasDelimiter := options["delimiter"]
+	if hasIdle && hasDelimiter {
+		return Args{}, usage
+	}
+
+	opts := SplitOptions{IdleGap: 2}
+	if hasIdle {
+		if opts.IdleGap, err = parseDuration(options["idle"]); err != nil {
+			return Args{}, err
+		}
+	}
+	if hasDelimiter {
+		code, ok := LookupKey(delimiter)
+		if !ok {
+			return Args{}, fmt.Errorf("неизвестная кнопка: %s", delimiter)
+		}
+		opts.Delimiter, opts.Every = code, 1
+		if value, ok := options["every"]; ok {
+			every, err := strconv.Atoi(value)
+			if err != nil || every < 1 {
+				return Args{}, fmt.Errorf("некорректное количество нажатий: %s", value)
+			}
+			opts.Every = every
+		}
+	} else if _, ok := options["every"]; ok {
+		return Args{}, usage
+	}
+	if value, ok := options["sanitize"]; ok {
+		rest, err := parseRestMode(value)
+		if err != nil {
+			return Args{}, err
+		}
+		opts.Sanitize = &SanitizeOptions{Rest: rest}
+	}
+
+	result.Split = opts
+	result.Prefix = options["prefix"]
+	if result.OutputFormat, err = parseRecordingFormat(options["output-format"]); err != nil {
+		return Args{}, err
+	}
+	return result, nil
+}
+
+func parseStatsArguments(args []string) (Args, error) {
+	usage := fmt.Errorf("использование: evemu-stats [--output-format=text|json] [входной файл] [файл отчёта]")
+	args, options := splitOptions(args)
+	if err := checkOptions(options, "output-format"); err != nil {
+		return Args{}, err
+	}
+
+	result, err := parseInputOutput(args, options, usage)
+	if err != nil {
+		return Args{}, err
+	}
+	if result.OutputFormat, err = parseReportFormat(options["output-format"]); err != nil {
+		return Args{}, err
+	}
+	return result, nil
+}
+
+func parseDiffArguments(args []string) (Args, error) {
+	usage := fmt.Errorf("использование: evemu-diff [--tolerance=<время>] [--output-format=text|json] <старый файл> <новый файл> [файл отчёта]")
+	args, options := splitOptions(args)
+	if err := checkOptions(options, "tolerance", "output-format"); err != nil {
+		return Args{}, err
+	}
+	if len(args) < 3 || len(args) > 4 {
+		return Args{}, usage
+	}
+
+	result := Args{InputFile: args[1], SecondArg: args[2], OutputFile: "-", Options: options}
+	if len(args) == 4 {
+		result.OutputFile = args[3]
+	}
+
+	var err error
+	result.Diff.Tolerance = 0.001
+	if value, ok := options["tolerance"]; ok {
+		if result.Diff.Tolerance, err = parseNonNegativeDuration(value); err != nil {
+			return Args{}, err
+		}
+	}
+	if result.OutputFormat, err = parseReportFormat(options["output-format"]); err != nil {
+		return Args{}, err
+	}
+	return result, nil
+}
+
+func parseValidateArguments(args []string) (Args, error) {
+	usage := fmt.Errorf("использование: evemu-validate [--severity=<правило:уровень,...>] [--output-format=text|json] [--fix] [входной файл] [файл отчёта|исправленный файл]")
+	args, options := splitOptions(args)
+	if err := checkOptions(options, "severity", "output-format", "fix"); err != nil {
+		return Args{}, err
+	}
+
+	result, err := parseInputOutput(args, options, usage)
+	if err != nil {
+		return Args{}, err
+	}
+	if result.Validate.Severities, err = ParseSeverities(options["severity"]); err != nil {
+		return Args{}, err
+	}
+	if result.OutputFormat, err = parseReportFormat(options["output-format"]); err != nil {
+		return Args{}, err
+	}
+	_, result.Fix = options["fix"]
+	return result, nil
+}
+
+func parseSVGArguments(args []string) (Args, error) {
+	usage := fmt.Errorf("использование: evemu-svg [--width=<пиксели>] [входной файл] [файл SVG]")
+	args, options := splitOptions(args)
+	if err := checkOptions(options, "width"); err != nil {
+		return Args{}, err
+	}
+
+	result, err := parseInputOutput(args, options, usage)
+	if err != nil {
+		return Args{}, err
+	}
+	if value, ok := options["width"]; ok {
+		width, err := strconv.Atoi(value)
+		if err != nil || width <= 0 {
+			return Args{}, fmt.Errorf("некорректная ширина: %s", value)
+		}
+		result.SVG.Width = width
+	}
+	return result, nil
+}
+
+func parseViewArguments(args []string) (Args, error) {
+	usage := fmt.Errorf("использование: evemu-view [--resolution=<время на колонку>] [--width=<колонки>] [входной файл]")
+	args, options := splitOptions(args)
+	if err := checkOptions(options, "resolution", "width"); err != nil {
+		return Args{}, err
+	}
+	if len(args) > 2 {
+		return Args{}, usage
+	}
+
+	result := Args{InputFile: "-", OutputFile: "-", Options: options}
+	if len(args) == 2 {
+		result.InputFile = args[1]
+	}
+
+	var err error
+	if value, ok := options["resolution"]; ok {
+		if result.View.Resolution, err = parseDuration(value); err != nil {
+			return Args{}, err
+		}
+	}
+	if value, ok := options["width"]; ok {
+		width, err := strconv.Atoi(value)
+		if err != nil || width <= 0 {
+			return Args{}, fmt.Errorf("некорректная ширина: %s", value)
+		}
+		result.View.Width = width
+	}
+	return result, nil
+}
+
+func parseDumpArguments(args []string) (Args, error) {
+	usage := fmt.Errorf("использование: evemu-dump [--compile] [--output-format=<формат записи>] [входной файл] [выходной файл]")
+	args, options := splitOptions(args)
+	if err := checkOptions(options, "compile", "output-format"); err != nil {
+		return Args{}, err
+	}
+
+	result, err := parseInputOutput(args, options, usage)
+	if err != nil {
+		return Args{}, err
+	}
+	_, result.Compile = options["compile"]
+
+	// По умолчанию - читаемый вид, с --compile - evemu, --output-format важнее обоих
+	result.OutputFormat = FormatDump
+	if result.Compile {
+		result.OutputFormat = FormatEvemu
+	}
+	if value, ok := options["output-format"]; ok {
+		if result.OutputFormat, err = parseRecordingFormat(value); err != nil {
+			return Args{}, err
+		}
+	}
+	return result, nil
+}
+
+func parseLoopArguments(args []string) (Args, error) {
+	usage := fmt.Errorf("использование: evemu-loop [--jitter=<время>] [--min-score=<доля>] [--max-period=<кадров>] [--value-tolerance=<значение>] [--extract] [--output-format=text|json] [входной файл] [файл отчёта|файл итерации]")
+	args, options := splitOptions(args)
+	if err := checkOptions(options, "jitter", "min-score", "max-period", "value-tolerance", "extract", "output-format"); err != nil {
+		return Args{}, err
+	}
+
+	result, err := parseInputOutput(args, options, usage)
+	if err != nil {
+		return Args{}, err
+	}
+	if value, ok := options["jitter"]; ok {
+		if result.Loop.Jitter, err = parseDuration(value); err != nil {
+			return Args{}, err
+		}
+	}
+	if value, ok := options["min-score"]; ok {
+		if result.Loop.MinScore, err = parsePositiveFloat(value); err != nil || result.Loop.MinScore > 1 {
+			return Args{}, fmt.Errorf("доля совпадений должна быть в пределах (0, 1]: %s", value)
+		}
+	}
+	if value, ok := options["max-period"]; ok {
+		if result.Loop.MaxPeriod, err = strconv.Atoi(value); err != nil || result.Loop.MaxPeriod < 1 {
+			return Args{}, fmt.Errorf("наибольший период должен быть положительным числом кадров: %s", value)
+		}
+	}
+	if value, ok := options["value-tolerance"]; ok {
+		if result.Loop.ValueTolerance, err = strconv.Atoi(value); err != nil || result.Loop.ValueTolerance < 0 {
+			return Args{}, fmt.Errorf("допуск значений должен быть неотрицательным числом: %s", value)
+		}
+	}
+	if result.OutputFormat, err = parseReportFormat(options["output-format"]); err != nil {
+		return Args{}, err
+	}
+	_, result.Extract = options["extract"]
+	return result, nil
+}
+
+func parseGrepArguments(args []string) (Args, error) {
+	usage := fmt.Errorf("использование: evemu-grep [--recursive] [--count|--files-with-matches] [--output-format=text|json] <шаблон> [файлы и каталоги...]")
+	args, options := splitOptions(args)
+	if err := checkOptions(options, "recursive", "count", "files-with-matches", "output-format"); err != nil {
+		return Args{}, err
+	}
+	if len(args) < 2 {
+		return Args{}, usage
+	}
+
+	result := Args{InputFile: "-", OutputFile: "-", Options: options, Paths: args[2:]}
+	if len(result.Paths) == 0 {
+		result.Paths = []string{"-"}
+	}
+
+	var err error
+	if result.Pattern, err = ParsePattern(args[1]); err != nil {
+		return Args{}, err
+	}
+	_, result.Recursive = options["recursive"]
+	_, result.Count = options["count"]
+	_, result.List = options["files-with-matches"]
+	if result.Count && result.List {
+		return Args{}, usage
+	}
+	if result.OutputFormat, err = parseReportFormat(options["output-format"]); err != nil {
+		return Args{}, err
+	}
+	return result, nil
+}
+
+func parseAnalyticsArguments(args []string) (Args, error) {
+	usage := fmt.Errorf("использование: evemu-analytics [--pairs=\"<событие> -> <событие>; ...\"] [--bin=<время>] [--recursive] [--output-format=text|csv|json] [--output=<файл отчёта>] [файлы и каталоги...]")
+	args, options := splitOptions(args)
+	if err := checkOptions(options, "pairs", "bin", "recursive", "output-format", "output"); err != nil {
+		return Args{}, err
+	}
+	if len(args) < 1 {
+		return Args{}, usage
+	}
+
+	result := Args{InputFile: "-", OutputFile: "-", Options: options, Paths: args[1:]}
+	if len(result.Paths) == 0 {
+		result.Paths = []string{"-"}
+	}
+	if value, ok := options["output"]; ok {
+		if value == "" {
+			return Args{}, usage
+		}
+		result.OutputFile = value
+	}
+
+	var err error
+	if result.Analytics.Pairs, err = ParseEventPairs(options["pairs"]); err != nil {
+		return Args{}, err
+	}
+	if value, ok := options["bin"]; ok {
+		if result.Analytics.BinWidth, err = parseDuration(value); err != nil {
+			return Args{}, err
+		}
+	}
+	if result.OutputFormat, err = parseTableFormat(options["output-format"]); err != nil {
+		return Args{}, err
+	}
+	_, result.Recursive = options["recursive"]
+	return result, nil
+}
+
+func parseConvertArguments(args []string) (Args, error) {
+	usage := fmt.Errorf("использование: evemu-convert [--output-format=<формат записи>|dump] [--sidecar] [входной файл] [выходной файл]")
+	args, options := splitOptions(args)
+	if err := checkOptions(options, "output-format", "sidecar"); err != nil {
+		return Args{}, err
+	}
+
+	result, err := parseInputOutput(args, options, usage)
+	if err != nil {
+		return Args{}, err
+	}
+	if value := options["output-format"]; value == FormatDump {
+		result.OutputFormat = FormatDump
+	} else if result.OutputFormat, err = parseRecordingFormat(value); err != nil {
+		return Args{}, err
+	}
+
+	// Заголовок в отдельном файле рядом с CSV: нужен путь выходного файла
+	_, result.Sidecar = options["sidecar"]
+	if result.Sidecar && (result.OutputFormat != FormatCSV || IsStdio(result.OutputFile)) {
+		return Args{}, fmt.Errorf("--sidecar допускается только с --output-format=csv и выходным файлом")
+	}
+	return result, nil
+}
+
+func parseBundleArguments(args []string) (Args, error) {
+	usage := fmt.Errorf("использование: evemu-bundle pack [--description=<текст>] <каталог|файлы...> <архив> | unpack <архив> [каталог] | list [--output-format=text|json] <архив> | verify <архив>")
+	args, options := splitOptions(args)
+	if len(args) < 3 {
+		return Args{}, usage
+	}
+
+	result := Args{InputFile: args[2], OutputFile: "-", Options: options}
+	result.Bundle.Command = args[1]
+	var err error
+	switch result.Bundle.Command {
+	case BundlePack:
+		if err := checkOptions(options, "description"); err != nil {
+			return Args{}, err
+		}
+		if len(args) < 4 {
+			return Args{}, usage
+		}
+		result.InputFile = ""
+		result.Paths = args[2 : len(args)-1]
+		result.OutputFile = args[len(args)-1]
+		result.Bundle.Description = options["description"]
+		// Сжатый gzip архив не открывается как zip
+		if HasGzipSuffix(result.OutputFile) {
+			return Args{}, fmt.Errorf("архив записей не сжимается gzip, уберите %s из имени: %s", GzipSuffix, result.OutputFile)
+		}
+	case BundleUnpack:
+		if err := checkOptions(options); err != nil {
+			return Args{}, err
+		}
+		switch len(args) {
+		case 3:
+			result.OutputFile = bundleUnpackDir(args[2])
+		case 4:
+			result.OutputFile = args[3]
+		default:
+			return Args{}, usage
+		}
+	case BundleList:
+		if err := checkOptions(options, "output-format"); err != nil {
+			return Args{}, err
+		}
+		if len(args) != 3 {
+			return Args{}, usage
+		}
+		if result.OutputFormat, err = parseReportFormat(options["output-format"]); err != nil {
+			return Args{}, err
+		}
+	case BundleVerify:
+		if err := checkOptions(options); err != nil {
+			return Args{}, err
+		}
+		if len(args) != 3 {
+			return Args{}, usage
+		}
+	default:
+		return Args{}, usage
+	}
+	// Архив читается с произвольным доступом, поэтому stdin не подходит
+	if IsStdio(result.InputFile) || result.Bundle.Command == BundleUnpack && IsStdio(result.OutputFile) {
+		return Args{}, fmt.Errorf("evemu-bundle читает архив и распаковывает записи только в файлы")
+	}
+	return result, nil
+}
+
+// parseReportFormat разбирает формат отчёта: text (по умолчанию) или json
+func parseReportFormat(value string) (string, error) {
+	switch value {
+	case "", "text":
+		return "text", nil
+	case "json":
+		return "json", nil
+	default:
+		return "", fmt.Errorf("неизвестный формат отчёта: %s", value)
+	}
+}
+
+// parseRecordingFormat разбирает формат выходной записи: evemu (по умолчанию),
+// json, jsonl, jsonl-frames, csv, libinput, compact или raw[-<раскладка>]
+func parseRecordingFormat(value string) (string, error) {
+	if _, ok := RawFormatLayout(value); ok {
+		return value, nil
+	}
+	switch value {
+	case "":
+		return FormatEvemu, nil
+	case FormatEvemu, FormatJSON, FormatJSONL, FormatJSONLFrames, FormatCSV, FormatLibinput, FormatCompact:
+		return value, nil
+	default:
+		return "", fmt.Errorf("неизвестный формат записи: %s", value)
+	}
+}
+
+// parseTableFormat разбирает формат табличного отчёта: text, csv или json
+func parseTableFormat(value string) (string, error) {
+	if value == "csv" {
+		return "csv", nil
+	}
+	return parseReportFormat(value)
+}
+
+// parseNonNegativeDuration разбирает длительность, допускающую ноль
+func parseNonNegativeDuration(value string) (float64, error) {
+	if value == "0" {
+		return 0, nil
+	}
+	return parseDuration(value)
+}
+
+// parseRestMode разбирает способ выбора положения покоя осей
+func parseRestMode(value string) (RestMode, error) {
+	switch value {
+	case "", "center":
+		return RestCenter, nil
+	case "initial":
+		return RestInitial, nil
+	default:
+		return RestCenter, fmt.Errorf("неизвестное положение покоя осей: %s", value)
+	}
+}
+
+// parsePositiveFloat разбирает положительное число
+func parsePositiveFloat(value string) (float64, error) {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("ожидается положительное число: %s", value)
+	}
+	return n, nil
+}
+
+// parseInputOutput разбирает позиционные аргументы [входной файл] [выходной файл]
+func parseInputOutput(args []string, options map[string]string, usage error) (Args, error) {
+	if len(args) > 3 {
+		return Args{}, usage
+	}
+
+	result := Args{InputFile: "-", OutputFile: "-", Options: options}
+	if len(args) > 1 {
+		result.InputFile = args[1]
+	}
+	if len(args) > 2 {
+		result.OutputFile = args[2]
+	}
+	return result, nil
+}
+
+// parseSignedDuration разбирает длительность, которая может быть отрицательной
+func parseSignedDuration(value string) (float64, error) {
+	if strings.HasPrefix(value, "-") {
+		d, err := parseDuration(strings.TrimPrefix(value, "-"))
+		return -d, err
+	}
+	return parseDuration(value)
+}
+
+// parseDuration разбирает длительность в формате Go (2h, 90s) или в секундах
+func parseDuration(value string) (float64, error) {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return seconds, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("некорректная длительность: %s", value)
+	}
+	return d.Seconds(), nil
+}
+
+// splitOptions отделяет опции вида --ключ=значение и --флаг от позиционных аргументов
+func splitOptions(args []string) ([]string, map[string]string) {
+	var positional []string
+	options := make(map[string]string)
+	for i, arg := range args {
+		if i == 0 || !strings.HasPrefix(arg, "--") {
+			positional = append(positional, arg)
+			continue
+		}
+		key, value, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		options[key] = value
+	}
+	return positional, options
+}
+
+// cutFlag удаляет флаг из аргументов и сообщает, был ли он передан
+func cutFlag(args []string, flag string) ([]string, bool) {
+	var rest []string
+	found := false
+	for i, arg := range args {
+		if i > 0 && arg == flag {
+			found = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, found
+}
+
+// checkOptions проверяет, что переданы только поддерживаемые опции
+func checkOptions(options map[string]string, allowed ...string) error {
+	for key := range options {
+		known := false
+		for _, name := range allowed {
+			if key == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("неизвестная опция: --%s", key)
+		}
+	}
+	return nil
+}
+
 func parseRepeatCount(arg string) int {
 	count, err := strconv.Atoi(arg)
 	if err != nil {
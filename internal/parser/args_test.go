@@ -71,6 +71,29 @@ func TestParseArgumentsMerge(t *testing.T) {
 	}
 }
 
+// TestParseArgumentsMergeOptions тестирует опции совместимости для merge
+func TestParseArgumentsMergeOptions(t *testing.T) {
+	config, err := ParseArguments([]string{"merge", "base.txt", "add.txt"}, "merge")
+	if err != nil || config.Merge.Header != HeaderBase || config.Merge.Warn || config.Merge.Rescale {
+		t.Errorf("Unexpected config: %+v, %v", config, err)
+	}
+
+	config, err = ParseArguments([]string{"merge", "--header=add", "--on-mismatch=warn", "--rescale", "base.txt", "add.txt", "out.txt"}, "merge")
+	if err != nil || config.Merge.Header != HeaderAdd || !config.Merge.Warn || !config.Merge.Rescale || config.OutputFile != "out.txt" {
+		t.Errorf("Unexpected config: %+v, %v", config, err)
+	}
+
+	for _, args := range [][]string{
+		{"merge", "--header=other", "add.txt"},
+		{"merge", "--on-mismatch=ignore", "add.txt"},
+		{"merge", "--unknown", "add.txt"},
+	} {
+		if _, err := ParseArguments(args, "merge"); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+}
+
 // TestParseArgumentsRepeat тестирует парсинг аргументов для repeat
 func TestParseArgumentsRepeat(t *testing.T) {
 	tests := []struct {
@@ -179,3 +202,511 @@ func TestInvalidApplications(t *testing.T) {
 		})
 	}
 }
+
+// TestParseArgumentsRepeatDuration тестирует парсинг режима повторения по длительности
+func TestParseArgumentsRepeatDuration(t *testing.T) {
+	tests := []struct {
+		name             string
+		args             []string
+		expectedInput    string
+		expectedOutput   string
+		expectedDuration float64
+		expectedTruncate bool
+		expectedError    bool
+	}{
+		{
+			name:             "Duration only",
+			args:             []string{"repeat", "--duration=2h"},
+			expectedInput:    "-",
+			expectedOutput:   "-",
+			expectedDuration: 7200,
+		},
+		{
+			name:             "Duration in seconds with files",
+			args:             []string{"repeat", "--duration=90", "--truncate", "in.txt", "out.txt"},
+			expectedInput:    "in.txt",
+			expectedOutput:   "out.txt",
+			expectedDuration: 90,
+			expectedTruncate: true,
+		},
+		{
+			name:          "Invalid duration",
+			args:          []string{"repeat", "--duration=abc", "in.txt"},
+			expectedError: true,
+		},
+		{
+			name:          "Unknown option",
+			args:          []string{"repeat", "--speed=2", "in.txt", "5"},
+			expectedError: true,
+		},
+		{
+			name:          "Too many arguments",
+			args:          []string{"repeat", "--duration=1m", "a", "b", "c"},
+			expectedError: true,
+		},
+		{
+			name:          "Truncate without duration",
+			args:          []string{"repeat", "--truncate", "in.txt", "3"},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ParseArguments(tt.args, "repeat")
+
+			if tt.expectedError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+
+			if config.InputFile != tt.expectedInput || config.OutputFile != tt.expectedOutput ||
+				config.Duration != tt.expectedDuration || config.Truncate != tt.expectedTruncate {
+				t.Errorf("ParseArguments() = %+v", config)
+			}
+		})
+	}
+}
+
+// TestParseArgumentsHold тестирует парсинг аргументов для hold
+func TestParseArgumentsHold(t *testing.T) {
+	config, err := ParseArguments([]string{"hold", "--buttons=A,LB", "--add=-50ms", "--shift", "in.txt"}, "hold")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.InputFile != "in.txt" || config.OutputFile != "-" {
+		t.Errorf("Unexpected files: %s %s", config.InputFile, config.OutputFile)
+	}
+	if len(config.Hold.Codes) != 2 || config.Hold.Mode != HoldAdd || config.Hold.Amount != -0.05 || !config.Hold.Shift {
+		t.Errorf("Unexpected hold options: %+v", config.Hold)
+	}
+
+	config, err = ParseArguments([]string{"hold", "--buttons=A", "--scale=150%"}, "hold")
+	if err != nil || config.Hold.Mode != HoldScale || config.Hold.Amount != 150 {
+		t.Errorf("Unexpected scale parsing: %+v, %v", config.Hold, err)
+	}
+
+	invalid := [][]string{
+		{"hold", "--add=10ms"},
+		{"hold", "--buttons=A"},
+		{"hold", "--buttons=A", "--add=10ms", "--set=20ms"},
+		{"hold", "--buttons=NOPE", "--add=10ms"},
+		{"hold", "--buttons=A", "--scale=-5"},
+	}
+	for _, args := range invalid {
+		if _, err := ParseArguments(args, "hold"); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+}
+
+// TestParseArgumentsResample тестирует парсинг аргументов для resample
+func TestParseArgumentsResample(t *testing.T) {
+	config, err := ParseArguments([]string{"resample", "in.txt", "out.txt"}, "resample")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Resample.Rate != 125 || config.Resample.Interpolation != InterpolateLinear || config.Resample.Smoothing != SmoothNone {
+		t.Errorf("Unexpected defaults: %+v", config.Resample)
+	}
+
+	config, err = ParseArguments([]string{"resample", "--rate=250", "--interp=cubic", "--smooth=lowpass", "--cutoff=8", "--axes=ABS_X,ABS_Y"}, "resample")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	opts := config.Resample
+	if opts.Rate != 250 || opts.Interpolation != InterpolateCubic || opts.Smoothing != SmoothLowPass || opts.Cutoff != 8 || len(opts.Axes) != 2 {
+		t.Errorf("Unexpected options: %+v", opts)
+	}
+
+	invalid := [][]string{
+		{"resample", "--rate=0"},
+		{"resample", "--interp=quadratic"},
+		{"resample", "--smooth=gauss"},
+		{"resample", "--window=0"},
+		{"resample", "--window=4"},
+		{"resample", "--axes=ABS_NOPE"},
+	}
+	for _, args := range invalid {
+		if _, err := ParseArguments(args, "resample"); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+}
+
+// TestParseArgumentsSanitize тестирует парсинг аргументов для sanitize и repeat --sanitize
+func TestParseArgumentsSanitize(t *testing.T) {
+	config, err := ParseArguments([]string{"sanitize", "--rest=initial", "in.txt", "out.txt"}, "sanitize")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Sanitize == nil || config.Sanitize.Rest != RestInitial || config.OutputFile != "out.txt" {
+		t.Errorf("Unexpected sanitize config: %+v", config)
+	}
+
+	config, err = ParseArguments([]string{"repeat", "--sanitize", "in.txt", "3"}, "repeat")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Sanitize == nil || config.Sanitize.Rest != RestCenter || config.RepeatCount != 3 {
+		t.Errorf("Unexpected repeat config: %+v", config)
+	}
+
+	config, err = ParseArguments([]string{"repeat", "in.txt", "3"}, "repeat")
+	if err != nil || config.Sanitize != nil {
+		t.Errorf("Sanitize should be disabled by default: %+v, %v", config, err)
+	}
+
+	if _, err := ParseArguments([]string{"sanitize", "--rest=zero"}, "sanitize"); err == nil {
+		t.Error("Expected error for unknown rest mode")
+	}
+}
+
+// TestParseArgumentsIdle тестирует парсинг аргументов для idle
+func TestParseArgumentsIdle(t *testing.T) {
+	config, err := ParseArguments([]string{"idle", "--threshold=5s", "--max-gap=0", "--between=menu,fight", "in.txt"}, "idle")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	opts := config.Idle
+	if opts.Threshold != 5 || opts.MaxGap != 0 || opts.StartMarker != "menu" || opts.EndMarker != "fight" {
+		t.Errorf("Unexpected idle options: %+v", opts)
+	}
+
+	config, err = ParseArguments([]string{"idle"}, "idle")
+	if err != nil || config.Idle.Threshold != 2 || config.Idle.MaxGap != 0.5 {
+		t.Errorf("Unexpected defaults: %+v, %v", config.Idle, err)
+	}
+
+	invalid := [][]string{
+		{"idle", "--threshold=1s", "--max-gap=2s"},
+		{"idle", "--between=menu"},
+		{"idle", "--threshold=abc"},
+	}
+	for _, args := range invalid {
+		if _, err := ParseArguments(args, "idle"); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+}
+
+// TestParseArgumentsSplit тестирует парсинг аргументов для split
+func TestParseArgumentsSplit(t *testing.T) {
+	config, err := ParseArguments([]string{"split", "session.txt"}, "split")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.OutputFile != "." || config.Split.IdleGap != 2 || config.Split.Every != 0 {
+		t.Errorf("Unexpected defaults: %+v", config)
+	}
+
+	config, err = ParseArguments([]string{"split", "--delimiter=A", "--every=3", "--prefix=clip", "session.txt", "clips"}, "split")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Split.Delimiter != 0x130 || config.Split.Every != 3 || config.Prefix != "clip" || config.OutputFile != "clips" {
+		t.Errorf("Unexpected delimiter config: %+v", config)
+	}
+
+	invalid := [][]string{
+		{"split", "--idle=1s", "--delimiter=A"},
+		{"split", "--every=2"},
+		{"split", "--delimiter=NOPE"},
+		{"split", "--delimiter=A", "--every=0"},
+	}
+	for _, args := range invalid {
+		if _, err := ParseArguments(args, "split"); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+}
+
+// TestParseArgumentsStats тестирует парсинг аргументов для stats
+func TestParseArgumentsStats(t *testing.T) {
+	config, err := ParseArguments([]string{"stats", "in.txt"}, "stats")
+	if err != nil || config.OutputFormat != "text" || config.InputFile != "in.txt" || config.OutputFile != "-" {
+		t.Errorf("Unexpected config: %+v, %v", config, err)
+	}
+
+	config, err = ParseArguments([]string{"stats", "--output-format=json"}, "stats")
+	if err != nil || config.OutputFormat != "json" || config.InputFile != "-" {
+		t.Errorf("Unexpected config: %+v, %v", config, err)
+	}
+
+	if _, err := ParseArguments([]string{"stats", "--output-format=xml"}, "stats"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+// TestParseArgumentsDiff тестирует парсинг аргументов для diff
+func TestParseArgumentsDiff(t *testing.T) {
+	config, err := ParseArguments([]string{"diff", "old.txt", "new.txt"}, "diff")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.InputFile != "old.txt" || config.SecondArg != "new.txt" || config.OutputFile != "-" || config.Diff.Tolerance != 0.001 {
+		t.Errorf("Unexpected config: %+v", config)
+	}
+
+	config, err = ParseArguments([]string{"diff", "--tolerance=5ms", "--output-format=json", "old.txt", "new.txt", "report.json"}, "diff")
+	if err != nil || config.Diff.Tolerance != 0.005 || config.OutputFormat != "json" || config.OutputFile != "report.json" {
+		t.Errorf("Unexpected config: %+v, %v", config, err)
+	}
+
+	if _, err := ParseArguments([]string{"diff", "old.txt"}, "diff"); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+// TestParseArgumentsValidate тестирует парсинг аргументов для validate
+func TestParseArgumentsValidate(t *testing.T) {
+	config, err := ParseArguments([]string{"validate", "in.txt"}, "validate")
+	if err != nil || config.InputFile != "in.txt" || config.OutputFile != "-" || config.Fix || len(config.Validate.Severities) != 0 {
+		t.Errorf("Unexpected config: %+v, %v", config, err)
+	}
+
+	config, err = ParseArguments([]string{"validate", "--fix", "--severity=abs-range:warning", "in.txt", "out.txt"}, "validate")
+	if err != nil || !config.Fix || config.OutputFile != "out.txt" || config.Validate.Severities[RuleAbsRange] != SeverityWarning {
+		t.Errorf("Unexpected config: %+v, %v", config, err)
+	}
+
+	if _, err := ParseArguments([]string{"validate", "--severity=abs-range:fatal"}, "validate"); err == nil {
+		t.Error("Expected error for unknown severity")
+	}
+}
+
+// TestParseArgumentsSVG тестирует парсинг аргументов для svg
+func TestParseArgumentsSVG(t *testing.T) {
+	config, err := ParseArguments([]string{"svg", "in.txt", "out.svg"}, "svg")
+	if err != nil || config.InputFile != "in.txt" || config.OutputFile != "out.svg" || config.SVG.Width != 0 {
+		t.Errorf("Unexpected config: %+v, %v", config, err)
+	}
+
+	config, err = ParseArguments([]string{"svg", "--width=800"}, "svg")
+	if err != nil || config.SVG.Width != 800 || config.InputFile != "-" {
+		t.Errorf("Unexpected config: %+v, %v", config, err)
+	}
+
+	if _, err := ParseArguments([]string{"svg", "--width=0"}, "svg"); err == nil {
+		t.Error("Expected error for zero width")
+	}
+}
+
+// TestParseArgumentsView тестирует парсинг аргументов для view
+func TestParseArgumentsView(t *testing.T) {
+	config, err := ParseArguments([]string{"view", "--resolution=10ms", "--width=80", "in.txt"}, "view")
+	if err != nil || config.InputFile != "in.txt" || config.View.Resolution != 0.01 || config.View.Width != 80 {
+		t.Errorf("Unexpected config: %+v, %v", config, err)
+	}
+
+	config, err = ParseArguments([]string{"view"}, "view")
+	if err != nil || config.InputFile != "-" || config.View.Resolution != 0 {
+		t.Errorf("Unexpected config: %+v, %v", config, err)
+	}
+
+	if _, err := ParseArguments([]string{"view", "in.txt", "out.txt"}, "view"); err == nil {
+		t.Error("Expected error for extra argument")
+	}
+}
+
+// TestParseArgumentsDump тестирует парсинг аргументов для dump
+func TestParseArgumentsDump(t *testing.T) {
+	config, err := ParseArguments([]string{"dump", "in.txt"}, "dump")
+	if err != nil || config.InputFile != "in.txt" || config.OutputFile != "-" || config.Compile {
+		t.Errorf("Unexpected config: %+v, %v", config, err)
+	}
+
+	config, err = ParseArguments([]string{"dump", "--compile", "in.dump", "out.txt"}, "dump")
+	if err != nil || !config.Compile || config.OutputFile != "out.txt" {
+		t.Errorf("Unexpected config: %+v, %v", config, err)
+	}
+}
+
+// TestParseArgumentsLoop тестирует парсинг аргументов для loop
+func TestParseArgumentsLoop(t *testing.T) {
+	config, err := ParseArguments([]string{"loop", "in.txt"}, "loop")
+	if err != nil || config.InputFile != "in.txt" || config.Extract || config.Loop.Jitter != 0 || config.OutputFormat != "text" {
+		t.Errorf("Unexpected config: %+v, %v", config, err)
+	}
+
+	config, err = ParseArguments([]string{"loop", "--jitter=20ms", "--min-score=0.9", "--extract", "in.txt", "out.txt"}, "loop")
+	if err != nil || !config.Extract || config.Loop.Jitter != 0.02 || config.Loop.MinScore != 0.9 || config.OutputFile != "out.txt" {
+		t.Errorf("Unexpected config: %+v, %v", config, err)
+	}
+
+	config, err = ParseArguments([]string{"loop", "--max-period=200", "--value-tolerance=300", "in.txt"}, "loop")
+	if err != nil || config.Loop.MaxPeriod != 200 || config.Loop.ValueTolerance != 300 {
+		t.Errorf("Unexpected config: %+v, %v", config, err)
+	}
+
+	for _, args := range [][]string{
+		{"loop", "--min-score=1.5"},
+		{"loop", "--max-period=0"},
+		{"loop", "--value-tolerance=-1"},
+	} {
+		if _, err := ParseArguments(args, "loop"); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+}
+
+// TestParseArgumentsGrep тестирует парсинг аргументов для grep
+func TestParseArgumentsGrep(t *testing.T) {
+	config, err := ParseArguments([]string{"grep", "press A"}, "grep")
+	if err != nil || len(config.Pattern.Steps) != 1 || len(config.Paths) != 1 || config.Paths[0] != "-" {
+		t.Errorf("Unexpected config: %+v, %v", config, err)
+	}
+
+	config, err = ParseArguments([]string{"grep", "--recursive", "--count", "hold LB then press A within 200ms", "clips", "extra.txt"}, "grep")
+	if err != nil || !config.Recursive || !config.Count || len(config.Paths) != 2 || len(config.Pattern.Steps) != 2 {
+		t.Errorf("Unexpected config: %+v, %v", config, err)
+	}
+
+	for _, args := range [][]string{
+		{"grep"},
+		{"grep", "press NOPE"},
+		{"grep", "--count", "--files-with-matches", "press A"},
+	} {
+		if _, err := ParseArguments(args, "grep"); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+}
+
+// TestParseArgumentsAnalytics тестирует парсинг аргументов для analytics
+func TestParseArgumentsAnalytics(t *testing.T) {
+	config, err := ParseArguments([]string{"analytics"}, "analytics")
+	if err != nil || len(config.Paths) != 1 || config.Paths[0] != "-" || config.OutputFormat != "text" {
+		t.Errorf("Unexpected config: %+v, %v", config, err)
+	}
+
+	config, err = ParseArguments([]string{"analytics", "--pairs=press LB -> press A; press A -> press A within 300ms",
+		"--bin=5ms", "--recursive", "--output-format=csv", "--output=report.csv", "clips", "extra.txt"}, "analytics")
+	if err != nil || !config.Recursive || len(config.Paths) != 2 || len(config.Analytics.Pairs) != 2 ||
+		config.Analytics.BinWidth != 0.005 || config.OutputFormat != "csv" || config.OutputFile != "report.csv" {
+		t.Errorf("Unexpected config: %+v, %v", config, err)
+	}
+
+	for _, args := range [][]string{
+		{"analytics", "--pairs=press LB"},
+		{"analytics", "--pairs=press NOPE -> press A"},
+		{"analytics", "--bin=0"},
+		{"analytics", "--output-format=xml"},
+	} {
+		if _, err := ParseArguments(args, "analytics"); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+}
+
+// TestParseArgumentsOutputFormat тестирует выбор формата выходной записи
+func TestParseArgumentsOutputFormat(t *testing.T) {
+	tests := []struct {
+		utility  string
+		args     []string
+		expected string
+	}{
+		{"repeat", []string{"repeat", "in.txt", "2"}, FormatEvemu},
+		{"repeat", []string{"repeat", "--output-format=json", "in.txt", "2", "out.json"}, FormatJSON},
+		{"merge", []string{"merge", "--output-format=json", "a.txt", "b.txt"}, FormatJSON},
+		{"hold", []string{"hold", "--buttons=A", "--set=50ms", "--output-format=json"}, FormatJSON},
+		{"resample", []string{"resample", "--output-format=json"}, FormatJSON},
+		{"sanitize", []string{"sanitize", "--output-format=json"}, FormatJSON},
+		{"idle", []string{"idle", "--output-format=json"}, FormatJSON},
+		{"split", []string{"split", "--output-format=json"}, FormatJSON},
+		{"dump", []string{"dump"}, FormatDump},
+		{"dump", []string{"dump", "--compile"}, FormatEvemu},
+		{"dump", []string{"dump", "--compile", "--output-format=json"}, FormatJSON},
+		{"grep", []string{"grep", "--output-format=json", "press A"}, "json"},
+		{"convert", []string{"convert", "in.json"}, FormatEvemu},
+		{"convert", []string{"convert", "--output-format=dump", "in.json", "out.txt"}, FormatDump},
+		{"convert", []string{"convert", "--output-format=jsonl", "in.txt"}, FormatJSONL},
+		{"idle", []string{"idle", "--output-format=jsonl-frames"}, FormatJSONLFrames},
+		{"dump", []string{"dump", "--output-format=jsonl"}, FormatJSONL},
+		{"hold", []string{"hold", "--buttons=A", "--add=10ms", "--output-format=csv"}, FormatCSV},
+		{"convert", []string{"convert", "--output-format=csv", "--sidecar", "in.txt", "out.csv"}, FormatCSV},
+		{"convert", []string{"convert", "--output-format=libinput", "in.txt", "out.yml"}, FormatLibinput},
+		{"split", []string{"split", "--output-format=libinput"}, FormatLibinput},
+		{"convert", []string{"convert", "--output-format=raw", "in.txt", "out.bin"}, FormatRaw},
+		{"repeat", []string{"repeat", "--output-format=raw-32be", "in.bin", "2", "out.bin"}, "raw-32be"},
+		{"convert", []string{"convert", "--output-format=compact", "in.txt", "out.evb"}, FormatCompact},
+	}
+	for _, tt := range tests {
+		config, err := ParseArguments(tt.args, tt.utility)
+		if err != nil || config.OutputFormat != tt.expected {
+			t.Errorf("%v: expected %s, got %q, %v", tt.args, tt.expected, config.OutputFormat, err)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"convert", "--sidecar", "in.txt", "out.txt"},
+		{"convert", "--output-format=csv", "--sidecar", "in.txt"},
+	} {
+		if _, err := ParseArguments(args, "convert"); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+
+	for _, utility := range []string{"repeat", "merge", "sanitize", "dump", "convert"} {
+		if _, err := ParseArguments([]string{utility, "--output-format=xml", "in.txt", "2"}, utility); err == nil {
+			t.Errorf("%s: expected error for unknown format", utility)
+		}
+	}
+}
+
+// TestParseArgumentsCompress тестирует флаг --compress
+func TestParseArgumentsCompress(t *testing.T) {
+	config, err := ParseArguments([]string{"merge", "--compress", "a.gz", "b.gz", "out"}, "merge")
+	if err != nil || !config.Compress || config.InputFile != "a.gz" || config.OutputFile != "out" {
+		t.Errorf("Unexpected merge config: %+v, %v", config, err)
+	}
+	config, err = ParseArguments([]string{"stats", "in.txt.gz"}, "stats")
+	if err != nil || config.Compress {
+		t.Errorf("Unexpected stats config: %+v, %v", config, err)
+	}
+	if _, err := ParseArguments([]string{"grep", "--compress", "press A"}, "grep"); err == nil {
+		t.Error("Expected --compress to be rejected by grep")
+	}
+	if _, err := ParseArguments([]string{"sanitize", "--compress=yes"}, "sanitize"); err == nil {
+		t.Error("Expected --compress=yes to be rejected")
+	}
+}
+
+// TestParseArgumentsBundle тестирует парсинг аргументов для bundle
+func TestParseArgumentsBundle(t *testing.T) {
+	config, err := ParseArguments([]string{"bundle", "pack", "--description=Combos", "clips", "extra.txt", "set.zip"}, "bundle")
+	if err != nil || config.Bundle.Command != BundlePack || config.Bundle.Description != "Combos" ||
+		len(config.Paths) != 2 || config.OutputFile != "set.zip" {
+		t.Errorf("Unexpected pack config: %+v, %v", config, err)
+	}
+	config, err = ParseArguments([]string{"bundle", "unpack", "dir/set.zip"}, "bundle")
+	if err != nil || config.InputFile != "dir/set.zip" || config.OutputFile != "dir/set" {
+		t.Errorf("Unexpected unpack config: %+v, %v", config, err)
+	}
+	config, err = ParseArguments([]string{"bundle", "list", "--output-format=json", "set.zip"}, "bundle")
+	if err != nil || config.Bundle.Command != BundleList || config.OutputFormat != "json" {
+		t.Errorf("Unexpected list config: %+v, %v", config, err)
+	}
+
+	for _, args := range [][]string{
+		{"bundle"},
+		{"bundle", "pack", "set.zip"},
+		{"bundle", "pack", "clips", "set.zip.gz"},
+		{"bundle", "zip", "set.zip"},
+		{"bundle", "verify", "-"},
+		{"bundle", "unpack", "set.zip", "-"},
+		{"bundle", "list", "--description=x", "set.zip"},
+		{"bundle", "verify", "--compress", "set.zip"},
+	} {
+		if _, err := ParseArguments(args, "bundle"); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+}
@@ -0,0 +1,661 @@
+package parser
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Идентификатор и версия манифеста архива записей
+const (
+	BundleFormatName    = "evemu-bundle"
+	BundleFormatVersion = 1
+)
+
+// Устройство архива: манифест, записи и заметки без пары
+const (
+	BundleManifestName = "manifest.json"
+	bundleClipsDir     = "clips/"
+	bundleNotesDir     = "notes/"
+)
+
+// BundleClipSeparator отделяет путь к архиву от имени записи: bundle.zip#combo
+const BundleClipSeparator = "#"
+
+// BundleNoteSuffix - суффикс заметки с описанием записи: combo.md для combo.txt
+const BundleNoteSuffix = ".md"
+
+// zipMagic - сигнатура локального заголовка zip
+var zipMagic = []byte("PK\x03\x04")
+
+// Команды evemu-bundle
+const (
+	BundlePack   = "pack"
+	BundleUnpack = "unpack"
+	BundleList   = "list"
+	BundleVerify = "verify"
+)
+
+// BundleOptions - параметры evemu-bundle
+type BundleOptions struct {
+	Command     string // BundlePack, BundleUnpack, BundleList или BundleVerify
+	Description string // описание набора при упаковке (дополняет README.md)
+}
+
+// BundleManifest - манифест архива: описание набора и его записи
+type BundleManifest struct {
+	Format      string       `json:"format"`
+	Version     int          `json:"version"`
+	Description string       `json:"description,omitempty"`
+	Clips       []BundleClip `json:"clips"`
+	Notes       []string     `json:"notes,omitempty"` // заметки без пары в notes/
+}
+
+// BundleClip - запись архива. Файл хранится в clips/ без изменений, в
+// своём формате; SHA256 - контрольная сумма файла.
+type BundleClip struct {
+	Name        string        `json:"name"`
+	File        string        `json:"file"`
+	Description string        `json:"description,omitempty"`
+	Device      *BundleDevice `json:"device,omitempty"`
+	Duration    float64       `json:"duration"`
+	Events      int           `json:"events"`
+	SHA256      string        `json:"sha256"`
+}
+
+// BundleDevice - устройство, для которого сделана запись
+type BundleDevice struct {
+	Name    string `json:"name"`
+	Bus     int    `json:"bus"`
+	Vendor  int    `json:"vendor"`
+	Product int    `json:"product"`
+	Version int    `json:"version"`
+}
+
+// BundleInput - запись для упаковки
+type BundleInput struct {
+	Name        string
+	File        string
+	Data        []byte
+	Description string
+}
+
+// BundleNote - заметка без пары, хранящаяся в архиве как есть
+type BundleNote struct {
+	File string
+	Data []byte
+}
+
+// BundleContents - содержимое будущего архива
+type BundleContents struct {
+	Description string
+	Clips       []BundleInput
+	Notes       []BundleNote
+}
+
+// BundleClipName возвращает имя записи по имени файла: без .gz и расширения
+func BundleClipName(file string) string {
+	name := filepath.Base(file)
+	if HasGzipSuffix(name) {
+		name = name[:len(name)-len(GzipSuffix)]
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// CollectBundle собирает записи из каталогов и файлов. Для записи
+// combo.txt описание берётся из combo.md рядом, описание набора - из
+// README.md (или readme.txt) каталога; остальные заметки .md сохраняются
+// как есть. Скрытые файлы и заголовки CSV (.header) пропускаются, заголовок
+// CSV без преамбулы переносится в преамбулу.
+func CollectBundle(paths []string) (*BundleContents, error) {
+	var files []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка открытия файла: %v", err)
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения каталога: %v", err)
+		}
+		for _, entry := range entries {
+			if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+				files = append(files, filepath.Join(p, entry.Name()))
+			}
+		}
+	}
+
+	contents := &BundleContents{}
+	notes := make(map[string]string) // путь заметки -> путь записи
+	seen := make(map[string]string)
+	for _, file := range files {
+		base := strings.ToLower(filepath.Base(file))
+		switch {
+		case strings.HasSuffix(base, CSVSidecarSuffix):
+			continue
+		case strings.HasPrefix(base, "readme"):
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("ошибка открытия файла: %v", err)
+			}
+			contents.Description = joinBundleDescription(contents.Description, string(data))
+			continue
+		case strings.HasSuffix(base, BundleNoteSuffix):
+			notes[file] = ""
+			continue
+		}
+
+		input, err := newBundleInput(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		if other, ok := seen[input.Name]; ok {
+			return nil, fmt.Errorf("записи %s и %s получают одно имя %s", other, file, input.Name)
+		}
+		seen[input.Name] = file
+
+		note := filepath.Join(filepath.Dir(file), input.Name+BundleNoteSuffix)
+		if data, err := os.ReadFile(note); err == nil {
+			input.Description = strings.TrimSpace(string(data))
+			notes[note] = file
+		}
+		contents.Clips = append(contents.Clips, input)
+	}
+
+	var unpaired []string
+	for note, clip := range notes {
+		if clip == "" {
+			unpaired = append(unpaired, note)
+		}
+	}
+	sort.Strings(unpaired)
+	for _, note := range unpaired {
+		data, err := os.ReadFile(note)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка открытия файла: %v", err)
+		}
+		contents.Notes = append(contents.Notes, BundleNote{File: filepath.Base(note), Data: data})
+	}
+
+	if len(contents.Clips) == 0 {
+		return nil, fmt.Errorf("не найдено ни одной записи")
+	}
+	return contents, nil
+}
+
+// newBundleInput читает запись для упаковки
+func newBundleInput(file string) (BundleInput, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return BundleInput{}, fmt.Errorf("ошибка открытия файла: %v", err)
+	}
+	input := BundleInput{Name: BundleClipName(file), File: filepath.Base(file), Data: data}
+
+	plain, err := Decompress(data)
+	if err != nil {
+		return BundleInput{}, err
+	}
+	if DetectFormat(plain) == FormatCSV {
+		if _, err := os.Stat(CSVSidecarPath(file)); err == nil {
+			recording, err := ReadInput(file)
+			if err != nil {
+				return BundleInput{}, err
+			}
+			var buf bytes.Buffer
+			if err := recording.WriteCSV(&buf, true); err != nil {
+				return BundleInput{}, err
+			}
+			input.Data, input.File = buf.Bytes(), input.Name+".csv"
+		}
+	}
+	return input, nil
+}
+
+// joinBundleDescription дописывает текст к описанию через пустую строку
+func joinBundleDescription(description, text string) string {
+	text = strings.TrimSpace(text)
+	if description == "" {
+		return text
+	}
+	return description + "\n\n" + text
+}
+
+// NewBundleClip строит запись манифеста: разбирает файл, чтобы узнать
+// устройство, длительность и число событий, и считает контрольную сумму
+func NewBundleClip(input BundleInput) (BundleClip, error) {
+	if err := checkBundleName(input.Name); err != nil {
+		return BundleClip{}, err
+	}
+	if err := checkBundleName(input.File); err != nil {
+		return BundleClip{}, err
+	}
+	recording, err := ParseData(input.Data)
+	if err != nil {
+		return BundleClip{}, fmt.Errorf("%s: %v", input.File, err)
+	}
+	if len(recording.Events) == 0 {
+		return BundleClip{}, fmt.Errorf("%s: в записи нет событий", input.File)
+	}
+
+	sum := sha256.Sum256(input.Data)
+	stats := recording.ComputeStats()
+	clip := BundleClip{
+		Name:        input.Name,
+		File:        input.File,
+		Description: input.Description,
+		Duration:    stats.Duration,
+		Events:      stats.Events,
+		SHA256:      hex.EncodeToString(sum[:]),
+	}
+	if d := ParseDescriptor(recording.Header); d.known() {
+		clip.Device = &BundleDevice{Name: d.Name, Bus: d.Bus, Vendor: d.Vendor, Product: d.Product, Version: d.Version}
+	}
+	return clip, nil
+}
+
+// checkBundleName проверяет имя записи или файла: без каталогов и
+// разделителя записи, чтобы распаковка не вышла за пределы каталога
+func checkBundleName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`+BundleClipSeparator) {
+		return fmt.Errorf("недопустимое имя в архиве: %q", name)
+	}
+	return nil
+}
+
+// WriteBundle записывает архив zip: манифест, записи в clips/ и заметки в
+// notes/. Возвращает записанный манифест.
+func WriteBundle(w io.Writer, contents *BundleContents) (*BundleManifest, error) {
+	manifest := &BundleManifest{
+		Format:      BundleFormatName,
+		Version:     BundleFormatVersion,
+		Description: contents.Description,
+		Clips:       []BundleClip{},
+	}
+	files := make(map[string]bool)
+	for _, input := range contents.Clips {
+		clip, err := NewBundleClip(input)
+		if err != nil {
+			return nil, err
+		}
+		if files[clip.File] {
+			return nil, fmt.Errorf("файл %s встречается дважды", clip.File)
+		}
+		files[clip.File] = true
+		manifest.Clips = append(manifest.Clips, clip)
+	}
+	for _, note := range contents.Notes {
+		if err := checkBundleName(note.File); err != nil {
+			return nil, err
+		}
+		manifest.Notes = append(manifest.Notes, note.File)
+	}
+
+	archive := zip.NewWriter(w)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeZipFile(archive, BundleManifestName, append(data, '\n')); err != nil {
+		return nil, err
+	}
+	for _, input := range contents.Clips {
+		if err := writeZipFile(archive, bundleClipsDir+input.File, input.Data); err != nil {
+			return nil, err
+		}
+	}
+	for _, note := range contents.Notes {
+		if err := writeZipFile(archive, bundleNotesDir+note.File, note.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// writeZipFile добавляет файл в архив со сжатием
+func writeZipFile(archive *zip.Writer, name string, data []byte) error {
+	writer, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(data)
+	return err
+}
+
+// Bundle - открытый архив записей
+type Bundle struct {
+	Manifest BundleManifest
+	archive  *zip.ReadCloser
+	files    map[string]*zip.File
+}
+
+// OpenBundle открывает архив и читает манифест
+func OpenBundle(file string) (*Bundle, error) {
+	archive, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия архива: %v", err)
+	}
+	b := &Bundle{archive: archive, files: make(map[string]*zip.File)}
+	for _, f := range archive.File {
+		b.files[f.Name] = f
+	}
+
+	data, err := b.readFile(BundleManifestName)
+	if err == nil {
+		err = json.Unmarshal(data, &b.Manifest)
+	}
+	if err != nil {
+		archive.Close()
+		return nil, fmt.Errorf("ошибка чтения манифеста: %v", err)
+	}
+	if b.Manifest.Format != BundleFormatName {
+		archive.Close()
+		return nil, fmt.Errorf("неизвестный формат архива: %q", b.Manifest.Format)
+	}
+	if b.Manifest.Version > BundleFormatVersion {
+		archive.Close()
+		return nil, fmt.Errorf("неподдерживаемая версия архива: %d", b.Manifest.Version)
+	}
+	return b, nil
+}
+
+// Close закрывает архив
+func (b *Bundle) Close() error {
+	return b.archive.Close()
+}
+
+// Clip находит запись манифеста по имени
+func (b *Bundle) Clip(name string) (*BundleClip, error) {
+	for i := range b.Manifest.Clips {
+		if b.Manifest.Clips[i].Name == name {
+			return &b.Manifest.Clips[i], nil
+		}
+	}
+	var names []string
+	for _, clip := range b.Manifest.Clips {
+		names = append(names, clip.Name)
+	}
+	return nil, fmt.Errorf("в архиве нет записи %q (есть: %s)", name, strings.Join(names, ", "))
+}
+
+// ReadClip читает файл записи и проверяет контрольную сумму
+func (b *Bundle) ReadClip(clip *BundleClip) ([]byte, error) {
+	if err := checkBundleName(clip.File); err != nil {
+		return nil, err
+	}
+	data, err := b.readFile(bundleClipsDir + clip.File)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != clip.SHA256 {
+		return nil, fmt.Errorf("%s: контрольная сумма не совпадает с манифестом", clip.Name)
+	}
+	return data, nil
+}
+
+// readFile читает файл архива; zip проверяет CRC32 при чтении
+func (b *Bundle) readFile(name string) ([]byte, error) {
+	f, ok := b.files[name]
+	if !ok {
+		return nil, fmt.Errorf("в архиве нет файла %s", name)
+	}
+	reader, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return data, nil
+}
+
+// Verify проверяет архив: для каждой записи - наличие файла, контрольную
+// сумму, разбор, число событий, длительность и устройство по манифесту;
+// для архива - уникальность имён и отсутствие файлов вне манифеста.
+// Возвращает список проблем.
+func (b *Bundle) Verify() []string {
+	var problems []string
+	listed := map[string]bool{BundleManifestName: true}
+	names := make(map[string]bool)
+	for i := range b.Manifest.Clips {
+		clip := &b.Manifest.Clips[i]
+		listed[bundleClipsDir+clip.File] = true
+		if names[clip.Name] {
+			problems = append(problems, fmt.Sprintf("%s: имя записи повторяется", clip.Name))
+		}
+		names[clip.Name] = true
+
+		data, err := b.ReadClip(clip)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", clip.Name, err))
+			continue
+		}
+		actual, err := NewBundleClip(BundleInput{Name: clip.Name, File: clip.File, Data: data, Description: clip.Description})
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", clip.Name, err))
+			continue
+		}
+		if actual.Events != clip.Events {
+			problems = append(problems, fmt.Sprintf("%s: событий %d, в манифесте %d", clip.Name, actual.Events, clip.Events))
+		}
+		if math.Abs(actual.Duration-clip.Duration) > 1e-6 {
+			problems = append(problems, fmt.Sprintf("%s: длительность %.6f с, в манифесте %.6f с", clip.Name, actual.Duration, clip.Duration))
+		}
+		if (actual.Device == nil) != (clip.Device == nil) || (actual.Device != nil && *actual.Device != *clip.Device) {
+			problems = append(problems, fmt.Sprintf("%s: устройство записи не совпадает с манифестом", clip.Name))
+		}
+	}
+	for _, note := range b.Manifest.Notes {
+		listed[bundleNotesDir+note] = true
+		if _, ok := b.files[bundleNotesDir+note]; !ok {
+			problems = append(problems, fmt.Sprintf("в архиве нет заметки %s", note))
+		}
+	}
+	for _, f := range b.archive.File {
+		if !listed[f.Name] && !strings.HasSuffix(f.Name, "/") {
+			problems = append(problems, fmt.Sprintf("%s: файл не указан в манифесте", f.Name))
+		}
+	}
+	return problems
+}
+
+// bundleFile - файл, извлекаемый из архива при распаковке
+type bundleFile struct {
+	name string
+	data []byte
+}
+
+// Unpack распаковывает записи, описания (<имя>.md), заметки и описание
+// набора (README.md) в каталог. Существующие файлы не перезаписываются:
+// архив читается и имена проверяются до записи первого файла, а при ошибке
+// записи уже созданные файлы удаляются.
+func (b *Bundle) Unpack(dir string) error {
+	var files []bundleFile
+	if b.Manifest.Description != "" {
+		files = append(files, bundleFile{"README.md", []byte(b.Manifest.Description + "\n")})
+	}
+	for i := range b.Manifest.Clips {
+		clip := &b.Manifest.Clips[i]
+		data, err := b.ReadClip(clip)
+		if err != nil {
+			return err
+		}
+		files = append(files, bundleFile{clip.File, data})
+		if clip.Description != "" {
+			files = append(files, bundleFile{clip.Name + BundleNoteSuffix, []byte(clip.Description + "\n")})
+		}
+	}
+	for _, note := range b.Manifest.Notes {
+		data, err := b.readFile(bundleNotesDir + note)
+		if err != nil {
+			return err
+		}
+		files = append(files, bundleFile{note, data})
+	}
+
+	seen := make(map[string]bool)
+	for _, f := range files {
+		if err := checkBundleName(f.name); err != nil {
+			return err
+		}
+		if seen[f.name] {
+			return fmt.Errorf("%s: файл встречается в архиве дважды", f.name)
+		}
+		seen[f.name] = true
+		if _, err := os.Lstat(filepath.Join(dir, f.name)); err == nil {
+			return fmt.Errorf("%s: файл уже существует", filepath.Join(dir, f.name))
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("ошибка создания каталога: %v", err)
+	}
+	for i, f := range files {
+		if err := writeNewFile(filepath.Join(dir, f.name), f.data); err != nil {
+			for _, written := range files[:i] {
+				os.Remove(filepath.Join(dir, written.name))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// writeNewFile создаёт файл, которого ещё нет, и записывает в него данные
+func writeNewFile(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("ошибка создания файла: %v", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(path)
+		return fmt.Errorf("ошибка записи файла: %v", err)
+	}
+	// Ошибка сброса на диск проявляется только при закрытии
+	if err := file.Close(); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("ошибка записи файла: %v", err)
+	}
+	return nil
+}
+
+// WriteText выводит список записей: имя, длительность, число событий,
+// устройство и первая строка описания
+func (m *BundleManifest) WriteText(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	if m.Description != "" {
+		first, _, _ := strings.Cut(m.Description, "\n")
+		fmt.Fprintf(writer, "%s\n\n", first)
+	}
+	fmt.Fprintf(writer, "%-20s %12s %8s  %-40s %s\n", "Запись", "Длительность", "События", "Устройство", "Описание")
+	for _, clip := range m.Clips {
+		device := "-"
+		if clip.Device != nil {
+			device = fmt.Sprintf("%s (%04x:%04x)", clip.Device.Name, clip.Device.Vendor, clip.Device.Product)
+		}
+		description, _, _ := strings.Cut(clip.Description, "\n")
+		fmt.Fprintf(writer, "%-20s %10.3f с %8d  %-40s %s\n", clip.Name, clip.Duration, clip.Events, device, description)
+	}
+	return writer.Flush()
+}
+
+// WriteJSON выводит манифест в формате JSON
+func (m *BundleManifest) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(m)
+}
+
+// SplitBundlePath разделяет ссылку на запись в архиве "bundle.zip#combo".
+// Путь считается ссылкой, только если такого файла нет, а архив до
+// последнего "#" существует.
+func SplitBundlePath(file string) (string, string, bool) {
+	i := strings.LastIndex(file, BundleClipSeparator)
+	if i <= 0 || i == len(file)-len(BundleClipSeparator) {
+		return "", "", false
+	}
+	if _, err := os.Stat(file); err == nil {
+		return "", "", false
+	}
+	bundle := file[:i]
+	if info, err := os.Stat(bundle); err != nil || info.IsDir() {
+		return "", "", false
+	}
+	return bundle, file[i+len(BundleClipSeparator):], true
+}
+
+// ReadBundleClip читает запись из архива
+func ReadBundleClip(bundle, name string) (*EvemuFile, error) {
+	b, err := OpenBundle(bundle)
+	if err != nil {
+		return nil, err
+	}
+	defer b.Close()
+
+	clip, err := b.Clip(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := b.ReadClip(clip)
+	if err != nil {
+		return nil, err
+	}
+	return ParseData(data)
+}
+
+// bundleClipPaths возвращает ссылки на все записи архива или nil, если
+// файл - не архив записей
+func bundleClipPaths(file string) []string {
+	header := make([]byte, len(zipMagic))
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	_, err = io.ReadFull(f, header)
+	f.Close()
+	if err != nil || !IsBundle(header) {
+		return nil
+	}
+
+	b, err := OpenBundle(file)
+	if err != nil {
+		return nil
+	}
+	defer b.Close()
+	var paths []string
+	for _, clip := range b.Manifest.Clips {
+		paths = append(paths, file+BundleClipSeparator+clip.Name)
+	}
+	return paths
+}
+
+// IsBundle проверяет сигнатуру zip
+func IsBundle(data []byte) bool {
+	return bytes.HasPrefix(data, zipMagic)
+}
+
+// bundleUnpackDir возвращает каталог распаковки по умолчанию: путь архива
+// без расширения
+func bundleUnpackDir(file string) string {
+	return strings.TrimSuffix(file, filepath.Ext(file))
+}
@@ -0,0 +1,254 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeBundleTestDir создаёт каталог с записями, заметками и README
+func writeBundleTestDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	f, err := ParseEvemu(strings.NewReader(csvTestText))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	var compact bytes.Buffer
+	if err := f.WriteCompact(&compact); err != nil {
+		t.Fatalf("WriteCompact failed: %v", err)
+	}
+	files := map[string]string{
+		"combo.txt":        csvTestText,
+		"combo.md":         "Hadouken\n",
+		"jump.evb":         compact.String(),
+		"README.md":        "Fighting macros\n",
+		"todo.md":          "record more\n",
+		".hidden":          "not a recording",
+		"table.csv":        "time,type,code,value\n0.000000,EV_KEY,BTN_SOUTH,1\n0.000000,EV_SYN,SYN_REPORT,0\n",
+		"table.csv.header": strings.Join(testDeviceHeader, ""),
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// packTestBundle упаковывает каталог в архив и возвращает путь к нему
+func packTestBundle(t *testing.T, dir string) string {
+	t.Helper()
+	contents, err := CollectBundle([]string{dir})
+	if err != nil {
+		t.Fatalf("CollectBundle failed: %v", err)
+	}
+	var data bytes.Buffer
+	if _, err := WriteBundle(&data, contents); err != nil {
+		t.Fatalf("WriteBundle failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "set.zip")
+	if err := os.WriteFile(path, data.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestBundleClipName тестирует имя записи по имени файла
+func TestBundleClipName(t *testing.T) {
+	for file, want := range map[string]string{
+		"dir/combo.txt":    "combo",
+		"combo.evemu.gz":   "combo",
+		"combo":            "combo",
+		"dir/jump.json.GZ": "jump",
+	} {
+		if got := BundleClipName(file); got != want {
+			t.Errorf("BundleClipName(%q) = %q, want %q", file, got, want)
+		}
+	}
+}
+
+// TestBundleRoundTrip тестирует упаковку, манифест, чтение записей и распаковку
+func TestBundleRoundTrip(t *testing.T) {
+	path := packTestBundle(t, writeBundleTestDir(t))
+	b, err := OpenBundle(path)
+	if err != nil {
+		t.Fatalf("OpenBundle failed: %v", err)
+	}
+	defer b.Close()
+
+	m := b.Manifest
+	if m.Format != BundleFormatName || m.Version != BundleFormatVersion || m.Description != "Fighting macros" {
+		t.Errorf("Unexpected manifest: %+v", m)
+	}
+	if len(m.Clips) != 3 || len(m.Notes) != 1 || m.Notes[0] != "todo.md" {
+		t.Fatalf("Unexpected clips or notes: %+v", m)
+	}
+	combo, err := b.Clip("combo")
+	if err != nil {
+		t.Fatalf("Clip failed: %v", err)
+	}
+	if combo.File != "combo.txt" || combo.Description != "Hadouken" || combo.Events != 5 ||
+		combo.Duration != 0.016123 || combo.Device == nil || combo.Device.Vendor != 0x45e || len(combo.SHA256) != 64 {
+		t.Errorf("Unexpected clip: %+v", combo)
+	}
+	// Заголовок CSV переносится в преамбулу, чтобы запись не зависела от файла рядом
+	if table, _ := b.Clip("table"); table == nil || table.Device == nil {
+		t.Errorf("Expected sidecar header in table clip: %+v", table)
+	}
+	if _, err := b.Clip("missing"); err == nil || !strings.Contains(err.Error(), "combo") {
+		t.Errorf("Expected error listing clips, got %v", err)
+	}
+	if problems := b.Verify(); len(problems) != 0 {
+		t.Errorf("Unexpected problems: %v", problems)
+	}
+
+	dir := filepath.Join(t.TempDir(), "out")
+	if err := b.Unpack(dir); err != nil {
+		t.Fatalf("Unpack failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "combo.txt"))
+	if err != nil || string(data) != csvTestText {
+		t.Errorf("Unexpected unpacked clip: %q, %v", data, err)
+	}
+	for _, name := range []string{"combo.md", "jump.evb", "table.csv", "todo.md", "README.md"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Missing unpacked file: %v", err)
+		}
+	}
+	if err := b.Unpack(dir); err == nil {
+		t.Error("Expected error when unpacking over existing files")
+	}
+
+	// Совпадение имени обнаруживается до записи первого файла
+	partial := t.TempDir()
+	if err := os.WriteFile(filepath.Join(partial, "todo.md"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Unpack(partial); err == nil || !strings.Contains(err.Error(), "todo.md") {
+		t.Errorf("Expected collision error, got %v", err)
+	}
+	if entries, _ := os.ReadDir(partial); len(entries) != 1 {
+		t.Errorf("Unpack wrote files before failing: %v", entries)
+	}
+}
+
+// TestReadInputBundleClip тестирует чтение записи по ссылке bundle.zip#имя
+func TestReadInputBundleClip(t *testing.T) {
+	path := packTestBundle(t, writeBundleTestDir(t))
+
+	f, err := ReadInput(path + "#jump")
+	if err != nil {
+		t.Fatalf("ReadInput failed: %v", err)
+	}
+	if len(f.Events) != 5 || len(f.Comments) != 1 {
+		t.Errorf("Unexpected clip: %d events, %d comments", len(f.Events), len(f.Comments))
+	}
+	if _, err := ReadInput(path + "#nope"); err == nil {
+		t.Error("Expected error for unknown clip")
+	}
+	if _, err := ReadInput(path); err == nil || !strings.Contains(err.Error(), path+"#combo") {
+		t.Errorf("Expected error listing clips, got %v", err)
+	}
+
+	paths, err := ExpandPaths([]string{path, path + "#combo"}, false)
+	if err != nil {
+		t.Fatalf("ExpandPaths failed: %v", err)
+	}
+	want := []string{path + "#combo", path + "#jump", path + "#table", path + "#combo"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("Unexpected paths: %v", paths)
+	}
+}
+
+// TestSplitBundlePath тестирует разбор ссылки на запись
+func TestSplitBundlePath(t *testing.T) {
+	dir := t.TempDir()
+	bundle := filepath.Join(dir, "set.zip")
+	literal := filepath.Join(dir, "take#2.txt")
+	for _, path := range []string{bundle, literal} {
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if b, clip, ok := SplitBundlePath(bundle + "#combo"); !ok || b != bundle || clip != "combo" {
+		t.Errorf("Unexpected split: %q %q %v", b, clip, ok)
+	}
+	for _, path := range []string{literal, bundle, bundle + "#", filepath.Join(dir, "none.zip#combo")} {
+		if _, _, ok := SplitBundlePath(path); ok {
+			t.Errorf("Expected %q not to be a clip reference", path)
+		}
+	}
+}
+
+// TestBundleVerify тестирует обнаружение повреждённых и лишних файлов
+func TestBundleVerify(t *testing.T) {
+	source := packTestBundle(t, writeBundleTestDir(t))
+	archive, err := zip.OpenReader(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	// Переписываем архив, подменяя запись и добавляя файл вне манифеста
+	var data bytes.Buffer
+	w := zip.NewWriter(&data)
+	for _, f := range archive.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(r)
+		r.Close()
+		if f.Name == bundleClipsDir+"combo.txt" {
+			content = bytes.Replace(content, []byte("0.016123"), []byte("0.016124"), 1)
+		}
+		if err := writeZipFile(w, f.Name, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeZipFile(w, bundleClipsDir+"stray.txt", []byte(csvTestText))
+	w.Close()
+	path := filepath.Join(t.TempDir(), "bad.zip")
+	os.WriteFile(path, data.Bytes(), 0644)
+
+	b, err := OpenBundle(path)
+	if err != nil {
+		t.Fatalf("OpenBundle failed: %v", err)
+	}
+	defer b.Close()
+	problems := strings.Join(b.Verify(), "\n")
+	if !strings.Contains(problems, "combo: контрольная сумма") || !strings.Contains(problems, "stray.txt") {
+		t.Errorf("Unexpected problems: %s", problems)
+	}
+	if _, err := ReadInput(path + "#combo"); err == nil {
+		t.Error("Expected checksum error when reading tampered clip")
+	}
+}
+
+// TestCollectBundleErrors тестирует ошибки сбора записей
+func TestCollectBundleErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := CollectBundle([]string{dir}); err == nil {
+		t.Error("Expected error for empty directory")
+	}
+	os.WriteFile(filepath.Join(dir, "combo.txt"), []byte(csvTestText), 0644)
+	os.WriteFile(filepath.Join(dir, "combo.json"), []byte(csvTestText), 0644)
+	if _, err := CollectBundle([]string{dir}); err == nil {
+		t.Error("Expected error for duplicate clip names")
+	}
+
+	contents := &BundleContents{Clips: []BundleInput{{Name: "../evil", File: "evil.txt", Data: []byte(csvTestText)}}}
+	if _, err := WriteBundle(io.Discard, contents); err == nil {
+		t.Error("Expected error for unsafe clip name")
+	}
+	contents = &BundleContents{Clips: []BundleInput{{Name: "empty", File: "empty.txt", Data: []byte(strings.Join(testDeviceHeader, ""))}}}
+	if _, err := WriteBundle(io.Discard, contents); err == nil {
+		t.Error("Expected error for clip without events")
+	}
+}
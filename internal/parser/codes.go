@@ -0,0 +1,253 @@
+package parser
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// typeNames содержит символьные имена типов событий
+var typeNames = map[int]string{
+	0x00: "EV_SYN",
+	0x01: "EV_KEY",
+	0x02: "EV_REL",
+	0x03: "EV_ABS",
+	0x04: "EV_MSC",
+	0x05: "EV_SW",
+	0x11: "EV_LED",
+	0x12: "EV_SND",
+	0x14: "EV_REP",
+	0x15: "EV_FF",
+	0x16: "EV_PWR",
+	0x17: "EV_FF_STATUS",
+}
+
+// codeNames содержит символьные имена кодов событий по типам
+var codeNames = map[int]map[int]string{
+	EvSyn: {
+		0x00: "SYN_REPORT",
+		0x01: "SYN_CONFIG",
+		0x02: "SYN_MT_REPORT",
+		0x03: "SYN_DROPPED",
+	},
+	EvKey: {
+		0x100: "BTN_0",
+		0x101: "BTN_1",
+		0x102: "BTN_2",
+		0x103: "BTN_3",
+		0x104: "BTN_4",
+		0x105: "BTN_5",
+		0x106: "BTN_6",
+		0x107: "BTN_7",
+		0x108: "BTN_8",
+		0x109: "BTN_9",
+		0x110: "BTN_LEFT",
+		0x111: "BTN_RIGHT",
+		0x112: "BTN_MIDDLE",
+		0x113: "BTN_SIDE",
+		0x114: "BTN_EXTRA",
+		0x120: "BTN_TRIGGER",
+		0x121: "BTN_THUMB",
+		0x122: "BTN_THUMB2",
+		0x123: "BTN_TOP",
+		0x124: "BTN_TOP2",
+		0x125: "BTN_PINKIE",
+		0x126: "BTN_BASE",
+		0x127: "BTN_BASE2",
+		0x128: "BTN_BASE3",
+		0x129: "BTN_BASE4",
+		0x12a: "BTN_BASE5",
+		0x12b: "BTN_BASE6",
+		0x12f: "BTN_DEAD",
+		0x130: "BTN_SOUTH",
+		0x131: "BTN_EAST",
+		0x132: "BTN_C",
+		0x133: "BTN_NORTH",
+		0x134: "BTN_WEST",
+		0x135: "BTN_Z",
+		0x136: "BTN_TL",
+		0x137: "BTN_TR",
+		0x138: "BTN_TL2",
+		0x139: "BTN_TR2",
+		0x13a: "BTN_SELECT",
+		0x13b: "BTN_START",
+		0x13c: "BTN_MODE",
+		0x13d: "BTN_THUMBL",
+		0x13e: "BTN_THUMBR",
+		0x220: "BTN_DPAD_UP",
+		0x221: "BTN_DPAD_DOWN",
+		0x222: "BTN_DPAD_LEFT",
+		0x223: "BTN_DPAD_RIGHT",
+		0x2c0: "BTN_TRIGGER_HAPPY1",
+		0x2c1: "BTN_TRIGGER_HAPPY2",
+		0x2c2: "BTN_TRIGGER_HAPPY3",
+		0x2c3: "BTN_TRIGGER_HAPPY4",
+	},
+	EvRel: {
+		0x00: "REL_X",
+		0x01: "REL_Y",
+		0x02: "REL_Z",
+		0x03: "REL_RX",
+		0x04: "REL_RY",
+		0x05: "REL_RZ",
+		0x06: "REL_HWHEEL",
+		0x07: "REL_DIAL",
+		0x08: "REL_WHEEL",
+		0x09: "REL_MISC",
+	},
+	EvAbs: {
+		0x00: "ABS_X",
+		0x01: "ABS_Y",
+		0x02: "ABS_Z",
+		0x03: "ABS_RX",
+		0x04: "ABS_RY",
+		0x05: "ABS_RZ",
+		0x06: "ABS_THROTTLE",
+		0x07: "ABS_RUDDER",
+		0x08: "ABS_WHEEL",
+		0x09: "ABS_GAS",
+		0x0a: "ABS_BRAKE",
+		0x10: "ABS_HAT0X",
+		0x11: "ABS_HAT0Y",
+		0x12: "ABS_HAT1X",
+		0x13: "ABS_HAT1Y",
+		0x14: "ABS_HAT2X",
+		0x15: "ABS_HAT2Y",
+		0x16: "ABS_HAT3X",
+		0x17: "ABS_HAT3Y",
+		0x18: "ABS_PRESSURE",
+		0x19: "ABS_DISTANCE",
+		0x1a: "ABS_TILT_X",
+		0x1b: "ABS_TILT_Y",
+		0x1c: "ABS_TOOL_WIDTH",
+		0x20: "ABS_VOLUME",
+		0x28: "ABS_MISC",
+	},
+	EvMsc: {
+		0x00: "MSC_SERIAL",
+		0x01: "MSC_PULSELED",
+		0x02: "MSC_GESTURE",
+		0x03: "MSC_RAW",
+		0x04: "MSC_SCAN",
+		0x05: "MSC_TIMESTAMP",
+	},
+}
+
+// keyAliases содержит привычные названия кнопок геймпада
+var keyAliases = map[string]int{
+	"A":           0x130,
+	"B":           0x131,
+	"X":           0x133,
+	"Y":           0x134,
+	"BTN_A":       0x130,
+	"BTN_B":       0x131,
+	"BTN_X":       0x133,
+	"BTN_Y":       0x134,
+	"BTN_GAMEPAD": 0x130,
+	"LB":          0x136,
+	"RB":          0x137,
+	"L2":          0x138,
+	"R2":          0x139,
+	"BACK":        0x13a,
+	"SELECT":      0x13a,
+	"START":       0x13b,
+	"GUIDE":       0x13c,
+	"MODE":        0x13c,
+	"LS":          0x13d,
+	"RS":          0x13e,
+}
+
+// TypeName возвращает символьное имя типа события или пустую строку
+func TypeName(typ int) string {
+	return typeNames[typ]
+}
+
+// CodeName возвращает символьное имя кода события или пустую строку
+func CodeName(typ, code int) string {
+	return codeNames[typ][code]
+}
+
+// TypeLabel возвращает имя типа или его шестнадцатеричное значение
+func TypeLabel(typ int) string {
+	if name := TypeName(typ); name != "" {
+		return name
+	}
+	return "0x" + strconv.FormatInt(int64(typ), 16)
+}
+
+// CodeLabel возвращает имя кода или его шестнадцатеричное значение
+func CodeLabel(typ, code int) string {
+	if name := CodeName(typ, code); name != "" {
+		return name
+	}
+	return "0x" + strconv.FormatInt(int64(code), 16)
+}
+
+// LookupType находит тип события по имени (EV_KEY) или числу
+func LookupType(name string) (int, bool) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	for typ, typName := range typeNames {
+		if typName == name {
+			return typ, true
+		}
+	}
+	return parseCodeNumber(name)
+}
+
+// LookupCode находит код события заданного типа по имени, псевдониму
+// (A, LB, START для кнопок) или числу. Числа без префикса 0x считаются
+// шестнадцатеричными, как в файлах evemu.
+func LookupCode(typ int, name string) (int, bool) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if typ == EvKey {
+		if code, ok := keyAliases[name]; ok {
+			return code, true
+		}
+	}
+	for code, codeName := range codeNames[typ] {
+		if codeName == name {
+			return code, true
+		}
+	}
+	return parseCodeNumber(name)
+}
+
+// LookupKey находит код кнопки по имени, псевдониму или числу
+func LookupKey(name string) (int, bool) {
+	return LookupCode(EvKey, name)
+}
+
+// ParseKeyList разбирает список кнопок, разделённых запятыми
+func ParseKeyList(list string) ([]int, bool) {
+	return ParseCodeList(EvKey, list)
+}
+
+// ParseCodeList разбирает список кодов заданного типа, разделённых запятыми
+func ParseCodeList(typ int, list string) ([]int, bool) {
+	var codes []int
+	for _, name := range strings.Split(list, ",") {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		code, ok := LookupCode(typ, name)
+		if !ok {
+			return nil, false
+		}
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	return codes, len(codes) > 0
+}
+
+// parseCodeNumber разбирает шестнадцатеричное число с необязательным префиксом 0x
+func parseCodeNumber(value string) (int, bool) {
+	value = strings.TrimPrefix(strings.ToLower(value), "0x")
+	if value == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(value, 16, 32)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return int(n), true
+}
@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"testing"
+)
+
+// TestCodeNames тестирует получение символьных имён
+func TestCodeNames(t *testing.T) {
+	if TypeName(EvAbs) != "EV_ABS" {
+		t.Errorf("TypeName(EV_ABS) = %s", TypeName(EvAbs))
+	}
+	if CodeName(EvAbs, 0x11) != "ABS_HAT0Y" {
+		t.Errorf("CodeName(ABS_HAT0Y) = %s", CodeName(EvAbs, 0x11))
+	}
+	if CodeLabel(EvKey, 0x2ff) != "0x2ff" {
+		t.Errorf("CodeLabel() for unknown code = %s", CodeLabel(EvKey, 0x2ff))
+	}
+	if TypeLabel(0x1f) != "0x1f" {
+		t.Errorf("TypeLabel() for unknown type = %s", TypeLabel(0x1f))
+	}
+}
+
+// TestLookupCode тестирует поиск кодов по имени, псевдониму и числу
+func TestLookupCode(t *testing.T) {
+	tests := []struct {
+		typ      int
+		name     string
+		expected int
+		valid    bool
+	}{
+		{EvKey, "BTN_SOUTH", 0x130, true},
+		{EvKey, "a", 0x130, true},
+		{EvKey, "LB", 0x136, true},
+		{EvKey, "0x13b", 0x13b, true},
+		{EvKey, "0131", 0x131, true},
+		{EvAbs, "ABS_RX", 0x03, true},
+		{EvAbs, "LB", 0, false},
+		{EvKey, "BTN_UNKNOWN", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, ok := LookupCode(tt.typ, tt.name)
+			if ok != tt.valid || (ok && code != tt.expected) {
+				t.Errorf("LookupCode(%d, %s) = (%#x, %v), expected (%#x, %v)", tt.typ, tt.name, code, ok, tt.expected, tt.valid)
+			}
+		})
+	}
+
+	if typ, ok := LookupType("ev_key"); !ok || typ != EvKey {
+		t.Errorf("LookupType(ev_key) = (%d, %v)", typ, ok)
+	}
+}
+
+// TestParseKeyList тестирует разбор списка кнопок
+func TestParseKeyList(t *testing.T) {
+	codes, ok := ParseKeyList("RB, A")
+	if !ok || len(codes) != 2 || codes[0] != 0x130 || codes[1] != 0x137 {
+		t.Errorf("ParseKeyList() = (%v, %v)", codes, ok)
+	}
+
+	if _, ok := ParseKeyList("A,NOPE"); ok {
+		t.Error("ParseKeyList should fail for unknown button")
+	}
+	if _, ok := ParseKeyList(""); ok {
+		t.Error("ParseKeyList should fail for empty list")
+	}
+}
@@ -0,0 +1,389 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Компактный двоичный формат записи:
+//
+//	"EVMB" 01                         сигнатура и версия
+//	uvarint длина, заголовок, crc32   строки заголовка evemu без изменений
+//	блоки событий:
+//	  uvarint число событий (>0), uvarint длина, события, crc32
+//	uvarint 0                         конец событий
+//	uvarint длина, комментарии, crc32
+//
+// Событие: varint разность времени с предыдущим событием в микросекундах,
+// uvarint номер пары (тип, код) в словаре и varint значение. Номер, равный
+// размеру словаря, добавляет в словарь пару, записанную следом двумя
+// uvarint. Комментарии: uvarint число, затем для каждого varint время в
+// микросекундах, uvarint длина и текст. varint кодируется зигзагом, crc32 -
+// IEEE, little-endian.
+const (
+	CompactMagic   = "EVMB"
+	CompactVersion = 1
+)
+
+// Ограничения блоков: число событий при записи и размер при чтении
+const (
+	compactBlockEvents = 4096
+	compactMaxSection  = 64 * 1024 * 1024
+)
+
+// compactValueCache - значения, строки которых переиспользуются при чтении
+// (кнопки, SYN, крестовина): [-compactValueCache, compactValueCache)
+const compactValueCache = 256
+
+// compactKey - пара (тип, код) в словаре
+type compactKey struct {
+	typ, code int
+}
+
+// CompactWriter записывает события в компактном формате блоками; комментарии
+// накапливаются и записываются при Close
+type CompactWriter struct {
+	writer   *bufio.Writer
+	dict     map[compactKey]int
+	block    []byte
+	events   int
+	previous int64
+	comments []Comment
+}
+
+// NewCompactWriter создаёт поток и записывает сигнатуру и заголовок
+func NewCompactWriter(w io.Writer, header []string) (*CompactWriter, error) {
+	c := &CompactWriter{writer: bufio.NewWriter(w), dict: make(map[compactKey]int)}
+	c.writer.WriteString(CompactMagic)
+	c.writer.WriteByte(CompactVersion)
+	return c, c.writeSection([]byte(strings.Join(header, "")))
+}
+
+// WriteEvent добавляет событие в текущий блок. Время округляется до
+// микросекунд, как в evemu.
+func (c *CompactWriter) WriteEvent(event Event) error {
+	micro := int64(math.Round(event.Timestamp * 1e6))
+	c.block = binary.AppendVarint(c.block, micro-c.previous)
+	c.previous = micro
+
+	key := compactKey{event.TypeNum(), event.CodeNum()}
+	if key.typ < 0 || key.code < 0 {
+		return fmt.Errorf("строка %d: некорректное событие: %s %s", event.Line, event.Type, event.Code)
+	}
+	index, ok := c.dict[key]
+	if !ok {
+		index = len(c.dict)
+		c.dict[key] = index
+	}
+	c.block = binary.AppendUvarint(c.block, uint64(index))
+	if !ok {
+		c.block = binary.AppendUvarint(c.block, uint64(key.typ))
+		c.block = binary.AppendUvarint(c.block, uint64(key.code))
+	}
+	c.block = binary.AppendVarint(c.block, int64(event.IntValue()))
+
+	c.events++
+	if c.events == compactBlockEvents {
+		return c.flushBlock()
+	}
+	return nil
+}
+
+// WriteComment запоминает комментарий
+func (c *CompactWriter) WriteComment(comment Comment) {
+	c.comments = append(c.comments, comment)
+}
+
+// Close записывает последний блок, конец событий и комментарии. Приёмник
+// не закрывается.
+func (c *CompactWriter) Close() error {
+	if err := c.flushBlock(); err != nil {
+		return err
+	}
+	c.writer.Write(binary.AppendUvarint(nil, 0))
+
+	payload := binary.AppendUvarint(nil, uint64(len(c.comments)))
+	for _, comment := range c.comments {
+		payload = binary.AppendVarint(payload, int64(math.Round(comment.Timestamp*1e6)))
+		payload = binary.AppendUvarint(payload, uint64(len(comment.Text)))
+		payload = append(payload, comment.Text...)
+	}
+	if err := c.writeSection(payload); err != nil {
+		return err
+	}
+	return c.writer.Flush()
+}
+
+// flushBlock записывает накопленный блок событий
+func (c *CompactWriter) flushBlock() error {
+	if c.events == 0 {
+		return nil
+	}
+	c.writer.Write(binary.AppendUvarint(nil, uint64(c.events)))
+	err := c.writeSection(c.block)
+	c.block, c.events = c.block[:0], 0
+	return err
+}
+
+// writeSection записывает длину, данные и их crc32
+func (c *CompactWriter) writeSection(payload []byte) error {
+	c.writer.Write(binary.AppendUvarint(nil, uint64(len(payload))))
+	c.writer.Write(payload)
+	_, err := c.writer.Write(binary.LittleEndian.AppendUint32(nil, crc32.ChecksumIEEE(payload)))
+	return err
+}
+
+// compactEntry - пара словаря с готовыми полями события
+type compactEntry struct {
+	typ, code string
+}
+
+// CompactReader читает запись в компактном формате по событию. Comments
+// заполняется, когда Next вернул io.EOF.
+type CompactReader struct {
+	Header   []string
+	Comments []Comment
+
+	reader    *bufio.Reader
+	dict      []compactEntry
+	values    [2 * compactValueCache]string
+	block     []byte
+	blockNum  int
+	remaining int
+	previous  int64
+	count     int
+	done      bool
+}
+
+// NewCompactReader проверяет сигнатуру и читает заголовок
+func NewCompactReader(r io.Reader) (*CompactReader, error) {
+	c := &CompactReader{reader: bufio.NewReader(r)}
+	magic := make([]byte, len(CompactMagic)+1)
+	if _, err := io.ReadFull(c.reader, magic); err != nil || string(magic[:len(CompactMagic)]) != CompactMagic {
+		return nil, fmt.Errorf("нет сигнатуры компактного формата %s", CompactMagic)
+	}
+	if version := magic[len(CompactMagic)]; version > CompactVersion {
+		return nil, fmt.Errorf("неподдерживаемая версия компактного формата: %d", version)
+	}
+
+	header, err := c.readSection("заголовок")
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.SplitAfter(string(header), "\n") {
+		if line != "" {
+			c.Header = append(c.Header, line)
+		}
+	}
+	return c, nil
+}
+
+// Next возвращает следующее событие; после последнего - io.EOF. В Event.Line
+// записывается номер события.
+func (c *CompactReader) Next() (Event, error) {
+	if c.done {
+		return Event{}, io.EOF
+	}
+	if c.remaining == 0 {
+		if err := c.nextBlock(); err != nil {
+			return Event{}, err
+		}
+		if c.done {
+			return Event{}, io.EOF
+		}
+	}
+
+	delta, err := c.varint()
+	if err != nil {
+		return Event{}, err
+	}
+	index, err := c.uvarint()
+	if err != nil {
+		return Event{}, err
+	}
+	switch {
+	case index == uint64(len(c.dict)):
+		typ, err := c.uvarint()
+		if err != nil {
+			return Event{}, err
+		}
+		code, err := c.uvarint()
+		if err != nil {
+			return Event{}, err
+		}
+		c.dict = append(c.dict, compactEntry{fmt.Sprintf("%04x", typ), fmt.Sprintf("%04x", code)})
+	case index > uint64(len(c.dict)):
+		return Event{}, fmt.Errorf("блок %d: номер %d вне словаря", c.blockNum, index)
+	}
+	value, err := c.varint()
+	if err != nil {
+		return Event{}, err
+	}
+
+	c.previous += delta
+	c.remaining--
+	c.count++
+	entry := c.dict[index]
+	return Event{
+		Timestamp: float64(c.previous) / 1e6,
+		Type:      entry.typ,
+		Code:      entry.code,
+		Value:     c.formatValue(value),
+		Line:      c.count,
+	}, nil
+}
+
+// nextBlock читает следующий блок или, после последнего, комментарии
+func (c *CompactReader) nextBlock() error {
+	if len(c.block) > 0 {
+		return fmt.Errorf("блок %d: лишние данные после событий", c.blockNum)
+	}
+	count, err := binary.ReadUvarint(c.reader)
+	if err != nil {
+		return fmt.Errorf("блок %d: данные обрываются", c.blockNum+1)
+	}
+	if count > 0 {
+		c.blockNum++
+		if c.block, err = c.readSection(fmt.Sprintf("блок %d", c.blockNum)); err != nil {
+			return err
+		}
+		c.remaining = int(count)
+		return nil
+	}
+
+	payload, err := c.readSection("комментарии")
+	if err != nil {
+		return err
+	}
+	c.block = payload
+	n, err := c.uvarint()
+	for i := uint64(0); err == nil && i < n; i++ {
+		var micro int64
+		var length uint64
+		if micro, err = c.varint(); err != nil {
+			break
+		}
+		if length, err = c.uvarint(); err != nil {
+			break
+		}
+		if length > uint64(len(c.block)) {
+			err = fmt.Errorf("комментарии: данные обрываются")
+			break
+		}
+		c.Comments = append(c.Comments, Comment{Timestamp: float64(micro) / 1e6, Text: string(c.block[:length])})
+		c.block = c.block[length:]
+	}
+	c.done = true
+	return err
+}
+
+// readSection читает длину, данные и проверяет crc32
+func (c *CompactReader) readSection(name string) ([]byte, error) {
+	length, err := binary.ReadUvarint(c.reader)
+	if err != nil || length > compactMaxSection {
+		return nil, fmt.Errorf("%s: некорректная длина", name)
+	}
+	payload := make([]byte, length+4)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return nil, fmt.Errorf("%s: данные обрываются", name)
+	}
+	payload, sum := payload[:length], binary.LittleEndian.Uint32(payload[length:])
+	if crc32.ChecksumIEEE(payload) != sum {
+		return nil, fmt.Errorf("%s: контрольная сумма не совпадает", name)
+	}
+	return payload, nil
+}
+
+// uvarint читает беззнаковое число из текущего блока
+func (c *CompactReader) uvarint() (uint64, error) {
+	value, n := binary.Uvarint(c.block)
+	if n <= 0 {
+		return 0, fmt.Errorf("блок %d: некорректное число", c.blockNum)
+	}
+	c.block = c.block[n:]
+	return value, nil
+}
+
+// varint читает знаковое число из текущего блока
+func (c *CompactReader) varint() (int64, error) {
+	value, n := binary.Varint(c.block)
+	if n <= 0 {
+		return 0, fmt.Errorf("блок %d: некорректное число", c.blockNum)
+	}
+	c.block = c.block[n:]
+	return value, nil
+}
+
+// formatValue форматирует значение, переиспользуя строки частых значений
+func (c *CompactReader) formatValue(value int64) string {
+	if value < -compactValueCache || value >= compactValueCache {
+		return formatCompactValue(value)
+	}
+	cached := &c.values[value+compactValueCache]
+	if *cached == "" {
+		*cached = formatCompactValue(value)
+	}
+	return *cached
+}
+
+// formatCompactValue форматирует значение как NewEvent (%04d) без fmt
+func formatCompactValue(value int64) string {
+	text := strconv.FormatInt(value, 10)
+	if len(text) >= 4 {
+		return text
+	}
+	if value < 0 {
+		return "-" + strings.Repeat("0", 4-len(text)) + text[1:]
+	}
+	return strings.Repeat("0", 4-len(text)) + text
+}
+
+// WriteCompact записывает запись в компактном формате. События
+// записываются числами, поэтому при чтении поля получают вид NewEvent.
+func (f *EvemuFile) WriteCompact(w io.Writer) error {
+	c, err := NewCompactWriter(w, f.Header)
+	if err != nil {
+		return err
+	}
+	for _, event := range f.Events {
+		if err := c.WriteEvent(event); err != nil {
+			return err
+		}
+	}
+	for _, comment := range f.Comments {
+		c.WriteComment(comment)
+	}
+	return c.Close()
+}
+
+// ParseCompact разбирает запись в компактном формате
+func ParseCompact(r io.Reader) (*EvemuFile, error) {
+	c, err := NewCompactReader(r)
+	if err != nil {
+		return nil, err
+	}
+	result := &EvemuFile{Header: c.Header}
+	for {
+		event, err := c.Next()
+		if err == io.EOF {
+			result.Comments = c.Comments
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Events = append(result.Events, event)
+	}
+}
+
+// isCompact проверяет сигнатуру компактного формата
+func isCompact(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(CompactMagic))
+}
@@ -0,0 +1,217 @@
+package parser
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// compactBenchEvents - число событий в бенчмарках; для сравнения на
+// больших корпусах: go test -bench Compact -args -compact.events=10000000
+var compactBenchEvents = flag.Int("compact.events", 100000, "число событий в бенчмарках компактного формата")
+
+// TestCompactRoundTrip тестирует запись и чтение компактного формата
+func TestCompactRoundTrip(t *testing.T) {
+	f, err := ParseEvemu(strings.NewReader(csvTestText))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	var data bytes.Buffer
+	if err := f.WriteFormat(&data, FormatCompact); err != nil {
+		t.Fatalf("WriteFormat failed: %v", err)
+	}
+	if !bytes.HasPrefix(data.Bytes(), []byte("EVMB\x01")) {
+		t.Errorf("Missing signature: %q", data.Bytes()[:5])
+	}
+	if format := DetectFormat(data.Bytes()); format != FormatCompact {
+		t.Errorf("Detected as %s", format)
+	}
+
+	parsed, err := ParseData(data.Bytes())
+	if err != nil {
+		t.Fatalf("ParseData failed: %v", err)
+	}
+	var restored bytes.Buffer
+	parsed.Write(&restored)
+	if restored.String() != csvTestText {
+		t.Errorf("Round-trip mismatch:\n%s", restored.String())
+	}
+	if parsed.Events[3].Line != 4 {
+		t.Errorf("Expected event number in Line, got %d", parsed.Events[3].Line)
+	}
+}
+
+// TestCompactReader тестирует потоковое чтение нескольких блоков
+func TestCompactReader(t *testing.T) {
+	f := &EvemuFile{Header: testDeviceHeader}
+	for i := 0; i < compactBlockEvents*2+10; i++ {
+		// Время идёт назад на каждом сотом событии, значения - с обеих сторон нуля
+		timestamp := float64(i)*0.001 - float64(i%100/99)*0.5
+		f.Events = append(f.Events, NewEvent(timestamp, EvAbs, i%3, (i%7-3)*10000))
+	}
+	f.Comments = []Comment{{Timestamp: 0.5, Text: "# marker: middle"}, {Timestamp: 9, Text: "# конец"}}
+
+	var data bytes.Buffer
+	if err := f.WriteCompact(&data); err != nil {
+		t.Fatalf("WriteCompact failed: %v", err)
+	}
+	if perEvent := float64(data.Len()-len(strings.Join(testDeviceHeader, ""))) / float64(len(f.Events)); perEvent > 6 {
+		t.Errorf("Expected compact events, got %.1f bytes per event", perEvent)
+	}
+
+	reader, err := NewCompactReader(&data)
+	if err != nil {
+		t.Fatalf("NewCompactReader failed: %v", err)
+	}
+	if len(reader.Header) != len(testDeviceHeader) || reader.Header[1] != testDeviceHeader[1] {
+		t.Errorf("Unexpected header: %q", reader.Header)
+	}
+	for i, expected := range f.Events {
+		event, err := reader.Next()
+		if err != nil {
+			t.Fatalf("Next failed at %d: %v", i, err)
+		}
+		if d := event.Timestamp - expected.Timestamp; d > 1e-9 || d < -1e-9 {
+			t.Fatalf("Event %d time mismatch: %v vs %v", i, event.Timestamp, expected.Timestamp)
+		}
+		if event.Type != expected.Type || event.Code != expected.Code || event.Value != expected.Value {
+			t.Fatalf("Event %d mismatch: %+v vs %+v", i, event, expected)
+		}
+	}
+	if _, err := reader.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+	if len(reader.Comments) != 2 || reader.Comments[1].Text != "# конец" || reader.Comments[0].Timestamp != 0.5 {
+		t.Errorf("Unexpected comments: %+v", reader.Comments)
+	}
+}
+
+// TestCompactCorruption тестирует обнаружение повреждённых данных
+func TestCompactCorruption(t *testing.T) {
+	f, err := ParseEvemu(strings.NewReader(csvTestText))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	var data bytes.Buffer
+	f.WriteCompact(&data)
+	good := data.Bytes()
+
+	flipped := append([]byte(nil), good...)
+	flipped[len(good)-30] ^= 0x40
+	for name, bad := range map[string][]byte{
+		"checksum":  flipped,
+		"truncated": good[:len(good)-3],
+		"version":   append([]byte("EVMB\x09"), good[5:]...),
+		"signature": append([]byte("EVMX"), good[4:]...),
+	} {
+		if _, err := ParseCompact(bytes.NewReader(bad)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+// TestReadRecordingStreamsCompact тестирует, что компактный формат читается
+// из потока по блокам: повреждённый первый блок бесконечного потока
+// обнаруживается сразу
+func TestReadRecordingStreamsCompact(t *testing.T) {
+	var data bytes.Buffer
+	c, err := NewCompactWriter(&data, testDeviceHeader)
+	if err != nil {
+		t.Fatalf("NewCompactWriter failed: %v", err)
+	}
+	c.writer.Flush()
+	block := data.Len()
+	for i := 0; i < 10; i++ {
+		c.WriteEvent(NewEvent(float64(i)*0.01, EvKey, 0x130, i%2))
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	result, err := ReadRecording(bytes.NewReader(data.Bytes()))
+	if err != nil || len(result.Events) != 10 || strings.Join(result.Header, "") != strings.Join(testDeviceHeader, "") {
+		t.Fatalf("Unexpected result: %+v, %v", result, err)
+	}
+
+	// Число событий и длина блока занимают по байту, за ними - события
+	broken := append([]byte(nil), data.Bytes()...)
+	broken[block+2] ^= 0x40
+	done := make(chan error, 1)
+	go func() {
+		_, err := ReadRecording(io.MultiReader(bytes.NewReader(broken), &endlessReader{line: []byte{0x01}}))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "блок 1") {
+			t.Errorf("Expected error in block 1, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ReadRecording did not stop at the broken block: input is not streamed")
+	}
+}
+
+// compactBenchData - запись для бенчмарков в тексте evemu и компактном формате
+var compactBenchData struct {
+	size    int // запрошенное число событий
+	events  int
+	text    []byte
+	compact []byte
+}
+
+// loadCompactBenchData строит запись геймпада: кадры по 4 мс со стиком и
+// кнопкой, как в многочасовой сессии
+func loadCompactBenchData(b *testing.B) {
+	if compactBenchData.size == *compactBenchEvents {
+		return
+	}
+	f := &EvemuFile{Header: testDeviceHeader}
+	for i := 0; len(f.Events) < *compactBenchEvents; i++ {
+		timestamp := float64(i) * 0.004
+		f.Events = append(f.Events,
+			NewEvent(timestamp, EvAbs, 0x00, (i*37)%65536-32768),
+			NewEvent(timestamp, EvAbs, 0x01, (i*91)%65536-32768))
+		if i%50 == 0 {
+			f.Events = append(f.Events, NewEvent(timestamp, EvKey, 0x130, i/50%2))
+		}
+		f.Events = append(f.Events, NewEvent(timestamp, EvSyn, SynReport, 0))
+	}
+
+	var text, compact bytes.Buffer
+	f.Write(&text)
+	f.WriteCompact(&compact)
+	compactBenchData.size, compactBenchData.events = *compactBenchEvents, len(f.Events)
+	compactBenchData.text, compactBenchData.compact = text.Bytes(), compact.Bytes()
+	b.Logf("%d событий: текст %d байт, компактный %d байт (в %.1f раза меньше)",
+		len(f.Events), text.Len(), compact.Len(), float64(text.Len())/float64(compact.Len()))
+}
+
+// BenchmarkParseEvemuText измеряет разбор текста evemu
+func BenchmarkParseEvemuText(b *testing.B) {
+	loadCompactBenchData(b)
+	b.SetBytes(int64(len(compactBenchData.text)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseEvemu(bytes.NewReader(compactBenchData.text)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(len(compactBenchData.text))/float64(compactBenchData.events), "bytes/event")
+}
+
+// BenchmarkParseCompact измеряет разбор компактного формата
+func BenchmarkParseCompact(b *testing.B) {
+	loadCompactBenchData(b)
+	b.SetBytes(int64(len(compactBenchData.compact)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseCompact(bytes.NewReader(compactBenchData.compact)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(len(compactBenchData.compact))/float64(compactBenchData.events), "bytes/event")
+}
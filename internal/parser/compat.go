@@ -0,0 +1,188 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Виды расхождений описаний устройств
+const (
+	MismatchName  = "name"  // различаются имена устройств (N:)
+	MismatchID    = "id"    // различаются шина, производитель, модель или версия (I:)
+	MismatchCode  = "code"  // код события не объявлен в маске B: итогового заголовка
+	MismatchAxis  = "axis"  // ось не описана строкой A: итогового заголовка
+	MismatchRange = "range" // различаются диапазоны оси
+)
+
+// Заголовок, который получает объединённая запись
+const (
+	HeaderBase = "base" // заголовок базового файла (по умолчанию)
+	HeaderAdd  = "add"  // заголовок добавочного файла
+)
+
+// MergeOptions задаёт проверку совместимости устройств при объединении
+type MergeOptions struct {
+	Header  string // HeaderBase или HeaderAdd ("" - HeaderBase)
+	Warn    bool   // при несовместимости только предупреждать
+	Rescale bool   // пересчитывать значения осей в диапазоны итогового заголовка
+}
+
+// Mismatch - расхождение описания второго устройства с итоговым
+type Mismatch struct {
+	Kind     string
+	Message  string
+	Rescaled bool // расхождение диапазонов устранено пересчётом значений
+}
+
+// Compatibility - результат сравнения описаний устройств
+type Compatibility struct {
+	Mismatches []Mismatch
+}
+
+// Compatible сообщает, что все расхождения устранены или их нет
+func (c *Compatibility) Compatible() bool {
+	return len(c.Unresolved()) == 0
+}
+
+// Unresolved возвращает расхождения, не устранённые пересчётом
+func (c *Compatibility) Unresolved() []Mismatch {
+	var result []Mismatch
+	for _, m := range c.Mismatches {
+		if !m.Rescaled {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// CompareDescriptors сравнивает описание устройства other с итоговым target.
+// Коды, объявленные только в target, расхождением не считаются: итоговое
+// устройство может их генерировать. Если одно из описаний пустое (заголовка
+// нет), сравнивать нечего.
+func CompareDescriptors(target, other *Descriptor) *Compatibility {
+	result := &Compatibility{}
+	if !target.known() || !other.known() {
+		return result
+	}
+	add := func(kind, format string, args ...any) {
+		result.Mismatches = append(result.Mismatches, Mismatch{Kind: kind, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if target.Name != other.Name {
+		add(MismatchName, "имя устройства: %q и %q", target.Name, other.Name)
+	}
+	if target.Bus != other.Bus || target.Vendor != other.Vendor || target.Product != other.Product || target.Version != other.Version {
+		add(MismatchID, "идентификатор устройства: %04x:%04x:%04x:%04x и %04x:%04x:%04x:%04x",
+			target.Bus, target.Vendor, target.Product, target.Version,
+			other.Bus, other.Vendor, other.Product, other.Version)
+	}
+
+	var types []int
+	for typ := range other.Bits {
+		if typ != EvSyn {
+			types = append(types, typ)
+		}
+	}
+	sort.Ints(types)
+	for _, typ := range types {
+		var missing []string
+		for _, code := range other.Codes(typ) {
+			if !target.HasCode(typ, code) {
+				missing = append(missing, CodeLabel(typ, code))
+			}
+		}
+		if len(missing) > 0 {
+			add(MismatchCode, "коды %s не объявлены: %s", TypeLabel(typ), strings.Join(missing, ", "))
+		}
+	}
+
+	var axes []int
+	for code := range other.Abs {
+		axes = append(axes, code)
+	}
+	sort.Ints(axes)
+	for _, code := range axes {
+		from := other.Abs[code]
+		to, ok := target.Abs[code]
+		switch {
+		case !ok:
+			add(MismatchAxis, "ось %s не описана", CodeLabel(EvAbs, code))
+		case from.Min != to.Min || from.Max != to.Max:
+			add(MismatchRange, "диапазон %s: [%d, %d] и [%d, %d]",
+				CodeLabel(EvAbs, code), to.Min, to.Max, from.Min, from.Max)
+		}
+	}
+	return result
+}
+
+// known сообщает, что описание устройства извлечено из заголовка
+func (d *Descriptor) known() bool {
+	return d.Name != "" || len(d.Bits) > 0
+}
+
+// RescaleAxes линейно пересчитывает значения осей из диапазонов from в
+// диапазоны to. Оси, не описанные в обоих заголовках или с вырожденным
+// диапазоном, не меняются.
+func (f *EvemuFile) RescaleAxes(from, to *Descriptor) *EvemuFile {
+	result := &EvemuFile{Header: f.Header, Comments: f.Comments, Events: make([]Event, len(f.Events))}
+	for i, event := range f.Events {
+		result.Events[i] = event
+		code := event.CodeNum()
+		if event.TypeNum() != EvAbs {
+			continue
+		}
+		source, ok := from.AbsRange(code)
+		target, found := to.AbsRange(code)
+		if !ok || !found || source.Min >= source.Max || source == target {
+			continue
+		}
+		scale := float64(target.Max-target.Min) / float64(source.Max-source.Min)
+		value := float64(target.Min) + float64(event.IntValue()-source.Min)*scale
+		rescaled := NewEvent(event.Timestamp, EvAbs, code, target.Clamp(int(math.Round(value))))
+		rescaled.Line = event.Line
+		result.Events[i] = rescaled
+	}
+	return result
+}
+
+// MergeChecked объединяет файлы, как Merge, предварительно сравнивая описания
+// устройств. Объединённая запись получает заголовок, выбранный opts.Header;
+// описание другого файла сравнивается с ним. При Rescale значения осей
+// другого файла пересчитываются в диапазоны итогового заголовка. Если
+// остались расхождения и Warn не задан, возвращается ошибка.
+func (f *EvemuFile) MergeChecked(other *EvemuFile, opts MergeOptions) (*EvemuFile, *Compatibility, error) {
+	target, source := f, other
+	if opts.Header == HeaderAdd {
+		target, source = other, f
+	}
+	targetDescriptor := ParseDescriptor(target.Header)
+	sourceDescriptor := ParseDescriptor(source.Header)
+
+	compat := CompareDescriptors(targetDescriptor, sourceDescriptor)
+	if opts.Rescale {
+		for i, m := range compat.Mismatches {
+			compat.Mismatches[i].Rescaled = m.Kind == MismatchRange
+		}
+		rescaled := source.RescaleAxes(sourceDescriptor, targetDescriptor)
+		if opts.Header == HeaderAdd {
+			f = rescaled
+		} else {
+			other = rescaled
+		}
+	}
+
+	if unresolved := compat.Unresolved(); len(unresolved) > 0 && !opts.Warn {
+		var messages []string
+		for _, m := range unresolved {
+			messages = append(messages, m.Message)
+		}
+		return nil, compat, fmt.Errorf("устройства несовместимы: %s", strings.Join(messages, "; "))
+	}
+
+	merged := f.Merge(other)
+	result := *merged
+	result.Header = target.Header
+	return &result, compat, nil
+}
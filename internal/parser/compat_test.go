@@ -0,0 +1,125 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+// newCompatTestHeader возвращает заголовок геймпада с диапазонами стиков 0-255
+// и дополнительной кнопкой BTN_0, при name == "" - заголовок testDeviceHeader
+func newCompatTestHeader(name string) []string {
+	d := ParseDescriptor(testDeviceHeader)
+	if name == "" {
+		return d.HeaderLines()
+	}
+	d.Name = name
+	d.Vendor, d.Product = 0x054c, 0x05c4
+	d.Abs[0x00] = AbsInfo{Min: 0, Max: 255}
+	d.Abs[0x01] = AbsInfo{Min: 0, Max: 255}
+	d.SetCode(EvKey, 0x100)
+	return d.HeaderLines()
+}
+
+// TestCompareDescriptors тестирует поиск расхождений описаний устройств
+func TestCompareDescriptors(t *testing.T) {
+	xbox := ParseDescriptor(newCompatTestHeader(""))
+	sony := ParseDescriptor(newCompatTestHeader("Sony Wireless Controller"))
+
+	if c := CompareDescriptors(xbox, ParseDescriptor(testDeviceHeader)); len(c.Mismatches) != 0 {
+		t.Errorf("Expected identical descriptors, got %+v", c.Mismatches)
+	}
+	if c := CompareDescriptors(xbox, ParseDescriptor(nil)); len(c.Mismatches) != 0 || !c.Compatible() {
+		t.Errorf("Expected no check without header, got %+v", c.Mismatches)
+	}
+
+	c := CompareDescriptors(xbox, sony)
+	var kinds []string
+	for _, m := range c.Mismatches {
+		kinds = append(kinds, m.Kind)
+	}
+	expected := "name,id,code,range,range"
+	if strings.Join(kinds, ",") != expected {
+		t.Errorf("Expected mismatches %s, got %+v", expected, c.Mismatches)
+	}
+	if !strings.Contains(c.Mismatches[2].Message, "BTN_0") || c.Compatible() {
+		t.Errorf("Unexpected mismatches: %+v", c.Mismatches)
+	}
+
+	// Лишняя кнопка итогового устройства расхождением не считается
+	c = CompareDescriptors(sony, xbox)
+	for _, m := range c.Mismatches {
+		if m.Kind == MismatchCode {
+			t.Errorf("Unexpected code mismatch: %+v", m)
+		}
+	}
+}
+
+// TestRescaleAxes тестирует пересчёт значений осей в другой диапазон
+func TestRescaleAxes(t *testing.T) {
+	from := ParseDescriptor(newCompatTestHeader("Sony Wireless Controller"))
+	to := ParseDescriptor(testDeviceHeader)
+	f := &EvemuFile{Events: []Event{
+		NewEvent(0.1, EvAbs, 0x00, 0),
+		NewEvent(0.1, EvAbs, 0x01, 255),
+		NewEvent(0.1, EvAbs, 0x02, 100), // диапазон совпадает
+		NewEvent(0.1, EvKey, 0x130, 1),
+		NewEvent(0.1, EvSyn, SynReport, 0),
+	}}
+	f.Events[0].Line = 7
+
+	result := f.RescaleAxes(from, to)
+	values := []int{-32768, 32767, 100, 1, 0}
+	for i, event := range result.Events {
+		if event.IntValue() != values[i] {
+			t.Errorf("Event %d: expected %d, got %d", i, values[i], event.IntValue())
+		}
+	}
+	if result.Events[0].Line != 7 || f.Events[0].IntValue() != 0 {
+		t.Error("Rescale must keep line numbers and not modify the source")
+	}
+}
+
+// TestMergeChecked тестирует объединение с проверкой совместимости
+func TestMergeChecked(t *testing.T) {
+	base := &EvemuFile{Header: newCompatTestHeader(""), Events: []Event{
+		NewEvent(1.0, EvAbs, 0x00, 32767),
+		NewEvent(1.0, EvSyn, SynReport, 0),
+	}}
+	add := &EvemuFile{Header: newCompatTestHeader("Sony Wireless Controller"), Events: []Event{
+		NewEvent(0.0, EvAbs, 0x00, 0),
+		NewEvent(0.0, EvSyn, SynReport, 0),
+	}}
+
+	if _, _, err := base.MergeChecked(add, MergeOptions{}); err == nil || !strings.Contains(err.Error(), "несовместимы") {
+		t.Errorf("Expected incompatibility error, got %v", err)
+	}
+
+	merged, compat, err := base.MergeChecked(add, MergeOptions{Warn: true, Rescale: true})
+	if err != nil {
+		t.Fatalf("MergeChecked failed: %v", err)
+	}
+	if len(compat.Unresolved()) != 3 || len(compat.Mismatches) != 5 {
+		t.Errorf("Expected 3 unresolved of 5 mismatches, got %+v", compat.Mismatches)
+	}
+	if merged.Header[1] != base.Header[1] || len(merged.Events) != 4 || merged.Events[2].IntValue() != -32768 {
+		t.Errorf("Unexpected merged file: %+v", merged)
+	}
+
+	// Заголовок добавочного файла: пересчитываются оси базового
+	merged, _, err = base.MergeChecked(add, MergeOptions{Header: HeaderAdd, Warn: true, Rescale: true})
+	if err != nil {
+		t.Fatalf("MergeChecked failed: %v", err)
+	}
+	if merged.Header[1] != add.Header[1] || merged.Events[0].IntValue() != 255 || merged.Events[2].IntValue() != 0 {
+		t.Errorf("Unexpected merged file: %+v", merged)
+	}
+	if base.Events[0].IntValue() != 32767 {
+		t.Error("MergeChecked must not modify the base file")
+	}
+
+	// Одинаковые устройства объединяются без расхождений
+	same := &EvemuFile{Header: testDeviceHeader, Events: add.Events}
+	if _, compat, err := base.MergeChecked(same, MergeOptions{}); err != nil || len(compat.Mismatches) != 0 {
+		t.Errorf("Expected compatible merge, got %+v, %v", compat, err)
+	}
+}
@@ -0,0 +1,212 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// CSVPreamblePrefix - префикс строк CSV, хранящих заголовок evemu
+const CSVPreamblePrefix = "# evemu: "
+
+// CSVSidecarSuffix - суффикс файла с заголовком evemu рядом с CSV без преамбулы
+const CSVSidecarSuffix = ".header"
+
+// csvColumns - столбцы таблицы событий
+var csvColumns = []string{"time", "type", "type_name", "code", "code_name", "value", "frame", "comment"}
+
+// CSVSidecarPath возвращает путь к файлу заголовка для CSV
+func CSVSidecarPath(path string) string {
+	return path + CSVSidecarSuffix
+}
+
+// WriteCSV записывает события таблицей: время, тип и код числами и именами,
+// значение и номер кадра. Комментарии записываются строками со столбцом
+// comment. Если preamble, заголовок evemu записывается перед таблицей
+// строками "# evemu: <строка заголовка>".
+func (f *EvemuFile) WriteCSV(w io.Writer, preamble bool) error {
+	writer := csv.NewWriter(w)
+	if preamble {
+		for _, line := range f.Header {
+			writer.Write([]string{CSVPreamblePrefix + strings.TrimSuffix(line, "\n")})
+		}
+	}
+	writer.Write(csvColumns)
+
+	frame, comment := 0, 0
+	writeComment := func(c Comment) {
+		writer.Write([]string{formatCSVTime(c.Timestamp), "", "", "", "", "", "", c.Text})
+	}
+	for i, event := range f.Events {
+		typ, code := event.TypeNum(), event.CodeNum()
+		writer.Write([]string{
+			formatCSVTime(event.Timestamp),
+			strconv.Itoa(typ), TypeLabel(typ),
+			strconv.Itoa(code), CodeLabel(typ, code),
+			strconv.Itoa(event.IntValue()),
+			strconv.Itoa(frame),
+			"",
+		})
+		if event.IsSynReport() {
+			frame++
+		}
+
+		if i+1 < len(f.Events) {
+			next := f.Events[i+1].Timestamp
+			for comment < len(f.Comments) && f.Comments[comment].Timestamp < next {
+				writeComment(f.Comments[comment])
+				comment++
+			}
+		}
+	}
+	for ; comment < len(f.Comments); comment++ {
+		writeComment(f.Comments[comment])
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatCSVTime форматирует время с точностью evemu
+func formatCSVTime(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', 6, 64)
+}
+
+// ParseCSV разбирает таблицу, записанную WriteCSV и, возможно, отредактированную
+// в электронной таблице. Столбцы находятся по именам в строке заголовка
+// таблицы; известные имена типа и кода важнее чисел, столбец frame
+// игнорируется. Пустые ячейки, добавленные редактором в конец строк
+// преамбулы, пропускаются.
+func ParseCSV(r io.Reader) (*EvemuFile, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла: %v", err)
+	}
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	result := &EvemuFile{}
+	var columns map[string]int
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка разбора CSV: %v", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if isEmptyRecord(record) {
+			continue
+		}
+
+		if columns == nil {
+			if text, ok := strings.CutPrefix(record[0], CSVPreamblePrefix); ok {
+				result.Header = append(result.Header, text+"\n")
+				continue
+			}
+			if columns, err = csvColumnIndex(record); err != nil {
+				return nil, fmt.Errorf("строка %d: %v", line, err)
+			}
+			continue
+		}
+
+		cell := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		timestamp, err := strconv.ParseFloat(cell("time"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: некорректное время: %s", line, cell("time"))
+		}
+		if text := cell("comment"); text != "" {
+			result.Comments = append(result.Comments, Comment{Timestamp: timestamp, Text: text})
+			continue
+		}
+
+		event, err := parseCSVEvent(timestamp, cell)
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: %v", line, err)
+		}
+		event.Line = line
+		result.Events = append(result.Events, event)
+	}
+
+	if columns == nil {
+		return nil, fmt.Errorf("не найдена строка заголовка таблицы (%s)", strings.Join(csvColumns, ","))
+	}
+	return result, nil
+}
+
+// csvColumnIndex находит столбцы таблицы по строке заголовка
+func csvColumnIndex(record []string) (map[string]int, error) {
+	columns := make(map[string]int)
+	for i, name := range record {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	missing := []string{}
+	for _, required := range [][]string{{"time"}, {"type", "type_name"}, {"code", "code_name"}, {"value"}} {
+		found := false
+		for _, name := range required {
+			if _, ok := columns[name]; ok {
+				found = true
+			}
+		}
+		if !found {
+			missing = append(missing, strings.Join(required, "|"))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("нет столбцов: %s", strings.Join(missing, ", "))
+	}
+	return columns, nil
+}
+
+// parseCSVEvent разбирает событие из ячеек строки
+func parseCSVEvent(timestamp float64, cell func(string) string) (Event, error) {
+	typ, ok := dumpNumber(cell("type"), cell("type_name"), LookupType)
+	if !ok {
+		return Event{}, fmt.Errorf("неизвестный тип события: %s %s", cell("type"), cell("type_name"))
+	}
+	code, ok := dumpNumber(cell("code"), cell("code_name"), func(name string) (int, bool) {
+		return LookupCode(typ, name)
+	})
+	if !ok {
+		return Event{}, fmt.Errorf("неизвестный код события: %s %s", cell("code"), cell("code_name"))
+	}
+
+	// Редактор может записать целое значение как число с дробной частью
+	value, err := strconv.Atoi(cell("value"))
+	if err != nil {
+		number, err := strconv.ParseFloat(cell("value"), 64)
+		if err != nil {
+			return Event{}, fmt.Errorf("некорректное значение: %s", cell("value"))
+		}
+		value = int(math.Round(number))
+	}
+	return NewEvent(timestamp, typ, code, value), nil
+}
+
+// isEmptyRecord проверяет, что все ячейки строки пустые
+func isEmptyRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// isCSVLine проверяет, что строка начинает таблицу CSV: преамбула или
+// строка заголовка таблицы
+func isCSVLine(line string) bool {
+	line = strings.TrimPrefix(strings.TrimPrefix(line, "\xef\xbb\xbf"), `"`)
+	return strings.HasPrefix(line, CSVPreamblePrefix) || strings.HasPrefix(strings.ToLower(line), "time,")
+}
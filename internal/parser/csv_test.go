@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// csvTestText - запись с маркером для проверки CSV
+var csvTestText = strings.Join(testDeviceHeader, "") +
+	"E: 0.000000 0001 0130 0001\n" +
+	"E: 0.000000 0003 0000 1200\n" +
+	"E: 0.000000 0000 0000 0000\n" +
+	"# marker: combo\n" +
+	"E: 0.016123 0003 0011 -001\n" +
+	"E: 0.016123 0000 0000 0000\n"
+
+// TestCSVRoundTrip тестирует запись в CSV и восстановление воспроизводимого файла
+func TestCSVRoundTrip(t *testing.T) {
+	f, err := ParseEvemu(strings.NewReader(csvTestText))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	var data bytes.Buffer
+	if err := f.WriteCSV(&data, true); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	text := data.String()
+	for _, expected := range []string{
+		"# evemu: N: Microsoft X-Box 360 pad\n",
+		"time,type,type_name,code,code_name,value,frame,comment\n",
+		"0.000000,1,EV_KEY,304,BTN_SOUTH,1,0,\n",
+		"0.000000,,,,,,,# marker: combo\n",
+		"0.016123,3,EV_ABS,17,ABS_HAT0Y,-1,1,\n",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("CSV missing %q:\n%s", expected, text)
+		}
+	}
+
+	if format := DetectFormat(data.Bytes()); format != FormatCSV {
+		t.Errorf("Detected as %s", format)
+	}
+	parsed, err := ParseData(data.Bytes())
+	if err != nil {
+		t.Fatalf("ParseData failed: %v", err)
+	}
+	var restored bytes.Buffer
+	parsed.Write(&restored)
+	if restored.String() != csvTestText {
+		t.Errorf("Round-trip mismatch:\n%s", restored.String())
+	}
+}
+
+// TestParseCSVEdited тестирует разбор таблицы, сохранённой электронной таблицей
+func TestParseCSVEdited(t *testing.T) {
+	text := "\xef\xbb\xbf\"# evemu: N: Pad, wireless\",,,\r\n" +
+		"# evemu: I: 0003 045e 028e 0114,,,\r\n" +
+		",,,\r\n" +
+		"Value,Code_Name,Type_Name,Time,Comment\r\n" +
+		"1.0,A,EV_KEY,0.5,\r\n" +
+		"0,SYN_REPORT,EV_SYN,0.5,\r\n" +
+		",,,0.5,# marker: hit\r\n"
+	f, err := ParseData([]byte(text))
+	if err != nil {
+		t.Fatalf("ParseData failed: %v", err)
+	}
+	if len(f.Header) != 2 || f.Header[0] != "N: Pad, wireless\n" {
+		t.Errorf("Unexpected header: %q", f.Header)
+	}
+	if len(f.Events) != 2 || f.Events[0] != (Event{Timestamp: 0.5, Type: "0001", Code: "0130", Value: "0001", Line: 5}) {
+		t.Errorf("Unexpected events: %+v", f.Events)
+	}
+	if len(f.Comments) != 1 || f.Comments[0].Text != "# marker: hit" {
+		t.Errorf("Unexpected comments: %+v", f.Comments)
+	}
+
+	for _, bad := range []string{
+		"time,type,code\n",
+		"# evemu: N: pad\n",
+		"time,type,code,value\nx,1,304,1\n",
+		"time,type,code,value\n0.5,EV_NOPE,304,1\n",
+		"time,type,code,value\n0.5,1,304,on\n",
+	} {
+		if _, err := ParseCSV(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+// TestReadInputCSVSidecar тестирует чтение заголовка CSV из файла рядом
+func TestReadInputCSVSidecar(t *testing.T) {
+	f, _ := ParseEvemu(strings.NewReader(csvTestText))
+	path := filepath.Join(t.TempDir(), "session.csv")
+
+	var data bytes.Buffer
+	f.WriteCSV(&data, false)
+	if err := os.WriteFile(path, data.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ReadInput(path)
+	if err != nil || len(parsed.Header) != 0 {
+		t.Errorf("Expected no header without sidecar: %+v, %v", parsed, err)
+	}
+
+	if err := (&EvemuFile{Header: f.Header}).WriteToFile(CSVSidecarPath(path)); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err = ReadInput(path)
+	if err != nil {
+		t.Fatalf("ReadInput failed: %v", err)
+	}
+	var restored bytes.Buffer
+	parsed.Write(&restored)
+	if restored.String() != csvTestText {
+		t.Errorf("Round-trip with sidecar mismatch:\n%s", restored.String())
+	}
+}
@@ -0,0 +1,261 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AbsInfo описывает параметры абсолютной оси из строки "A:" заголовка
+type AbsInfo struct {
+	Min        int `json:"min"`
+	Max        int `json:"max"`
+	Fuzz       int `json:"fuzz"`
+	Flat       int `json:"flat"`
+	Resolution int `json:"resolution"`
+}
+
+// Clamp ограничивает значение диапазоном оси
+func (a AbsInfo) Clamp(value int) int {
+	if a.Min >= a.Max {
+		return value
+	}
+	if value < a.Min {
+		return a.Min
+	}
+	if value > a.Max {
+		return a.Max
+	}
+	return value
+}
+
+// Descriptor описывает устройство по строкам N:, I:, P:, B: и A: заголовка evemu
+type Descriptor struct {
+	Name    string
+	Bus     int
+	Vendor  int
+	Product int
+	Version int
+	Props   []byte
+	Bits    map[int][]byte // маски поддерживаемых кодов по типам событий
+	Abs     map[int]AbsInfo
+}
+
+// ParseDescriptor извлекает описание устройства из заголовка evemu
+func ParseDescriptor(header []string) *Descriptor {
+	d := &Descriptor{
+		Bits: make(map[int][]byte),
+		Abs:  make(map[int]AbsInfo),
+	}
+
+	for _, line := range header {
+		line = strings.TrimRight(line, "\r\n")
+		if len(line) < 2 || line[1] != ':' {
+			continue
+		}
+		value := strings.TrimSpace(line[2:])
+		fields := strings.Fields(value)
+
+		switch line[0] {
+		case 'N':
+			d.Name = value
+		case 'I':
+			if len(fields) == 4 {
+				d.Bus = parseHex(fields[0])
+				d.Vendor = parseHex(fields[1])
+				d.Product = parseHex(fields[2])
+				d.Version = parseHex(fields[3])
+			}
+		case 'P':
+			d.Props = append(d.Props, parseHexBytes(fields)...)
+		case 'B':
+			if len(fields) > 1 {
+				typ := parseHex(fields[0])
+				d.Bits[typ] = append(d.Bits[typ], parseHexBytes(fields[1:])...)
+			}
+		case 'A':
+			if len(fields) >= 5 {
+				info := AbsInfo{
+					Min:  parseInt(fields[1]),
+					Max:  parseInt(fields[2]),
+					Fuzz: parseInt(fields[3]),
+					Flat: parseInt(fields[4]),
+				}
+				if len(fields) > 5 {
+					info.Resolution = parseInt(fields[5])
+				}
+				d.Abs[parseHex(fields[0])] = info
+			}
+		}
+	}
+
+	return d
+}
+
+// HasType проверяет, объявлен ли тип событий в масках B:
+func (d *Descriptor) HasType(typ int) bool {
+	_, ok := d.Bits[typ]
+	return ok
+}
+
+// HasCode проверяет, объявлен ли код события в маске B: соответствующего типа
+func (d *Descriptor) HasCode(typ, code int) bool {
+	mask, ok := d.Bits[typ]
+	if !ok || code < 0 || code/8 >= len(mask) {
+		return false
+	}
+	return mask[code/8]&(1<<(code%8)) != 0
+}
+
+// Codes возвращает список кодов, объявленных для типа событий
+func (d *Descriptor) Codes(typ int) []int {
+	var codes []int
+	for i, b := range d.Bits[typ] {
+		for bit := 0; bit < 8; bit++ {
+			if b&(1<<bit) != 0 {
+				codes = append(codes, i*8+bit)
+			}
+		}
+	}
+	return codes
+}
+
+// AbsRange возвращает параметры оси, если они объявлены в заголовке
+func (d *Descriptor) AbsRange(code int) (AbsInfo, bool) {
+	info, ok := d.Abs[code]
+	return info, ok
+}
+
+// SetCode объявляет код события в маске B: соответствующего типа
+func (d *Descriptor) SetCode(typ, code int) {
+	mask := d.Bits[typ]
+	for len(mask) <= code/8 {
+		mask = append(mask, 0)
+	}
+	mask[code/8] |= 1 << (code % 8)
+	d.Bits[typ] = mask
+}
+
+// SetProp объявляет свойство устройства INPUT_PROP_* в строке P:
+func (d *Descriptor) SetProp(prop int) {
+	for len(d.Props) <= prop/8 {
+		d.Props = append(d.Props, 0)
+	}
+	d.Props[prop/8] |= 1 << (prop % 8)
+}
+
+// declareEvents объявляет коды, встретившиеся в событиях. Для осей без
+// параметров диапазон берётся из значений событий.
+func (d *Descriptor) declareEvents(events []Event) {
+	observed := make(map[int]AbsInfo)
+	for _, event := range events {
+		typ, code, value := event.TypeNum(), event.CodeNum(), event.IntValue()
+		if typ == EvSyn {
+			continue
+		}
+		d.SetCode(typ, code)
+		if typ == EvAbs {
+			info, ok := observed[code]
+			if !ok {
+				info = AbsInfo{Min: value, Max: value}
+			}
+			info.Min, info.Max = min(info.Min, value), max(info.Max, value)
+			observed[code] = info
+		}
+	}
+	for _, code := range d.Codes(EvAbs) {
+		if _, ok := d.Abs[code]; !ok {
+			d.Abs[code] = observed[code]
+		}
+	}
+}
+
+// HeaderLines формирует заголовок evemu по описанию устройства: строки
+// N:, I:, P:, B: и A: и баннер, после которого начинаются события.
+// Маска типов EV_SYN (B: 00) строится по объявленным типам.
+func (d *Descriptor) HeaderLines() []string {
+	lines := []string{
+		"# EVEMU 1.3\n",
+		fmt.Sprintf("N: %s\n", d.Name),
+		fmt.Sprintf("I: %04x %04x %04x %04x\n", d.Bus, d.Vendor, d.Product, d.Version),
+	}
+	lines = append(lines, maskLines("P:", "", d.Props)...)
+
+	types := &Descriptor{Bits: make(map[int][]byte)}
+	types.SetCode(EvSyn, EvSyn)
+	var typeList []int
+	for typ := range d.Bits {
+		if typ != EvSyn {
+			types.SetCode(EvSyn, typ)
+			typeList = append(typeList, typ)
+		}
+	}
+	sort.Ints(typeList)
+	lines = append(lines, maskLines("B:", "00 ", types.Bits[EvSyn])...)
+	for _, typ := range typeList {
+		lines = append(lines, maskLines("B:", fmt.Sprintf("%02x ", typ), d.Bits[typ])...)
+	}
+
+	var axes []int
+	for code := range d.Abs {
+		axes = append(axes, code)
+	}
+	sort.Ints(axes)
+	for _, code := range axes {
+		info := d.Abs[code]
+		lines = append(lines, fmt.Sprintf("A: %02x %d %d %d %d %d\n",
+			code, info.Min, info.Max, info.Fuzz, info.Flat, info.Resolution))
+	}
+
+	return append(lines,
+		"################################\n",
+		"#      Waiting for events      #\n",
+		"################################\n",
+	)
+}
+
+// maskLines разбивает маску на строки по 8 байт, дополняя последнюю нулями
+func maskLines(prefix, typ string, mask []byte) []string {
+	padded := append([]byte(nil), mask...)
+	for len(padded) == 0 || len(padded)%8 != 0 {
+		padded = append(padded, 0)
+	}
+
+	var lines []string
+	for i := 0; i < len(padded); i += 8 {
+		var values []string
+		for _, b := range padded[i : i+8] {
+			values = append(values, fmt.Sprintf("%02x", b))
+		}
+		lines = append(lines, fmt.Sprintf("%s %s%s\n", prefix, typ, strings.Join(values, " ")))
+	}
+	return lines
+}
+
+// parseHex разбирает шестнадцатеричное число (0 при ошибке)
+func parseHex(value string) int {
+	n, err := strconv.ParseInt(strings.TrimPrefix(value, "0x"), 16, 64)
+	if err != nil {
+		return 0
+	}
+	return int(n)
+}
+
+// parseInt разбирает десятичное число (0 при ошибке)
+func parseInt(value string) int {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// parseHexBytes разбирает последовательность шестнадцатеричных байтов
+func parseHexBytes(fields []string) []byte {
+	result := make([]byte, 0, len(fields))
+	for _, field := range fields {
+		result = append(result, byte(parseHex(field)))
+	}
+	return result
+}
@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"testing"
+)
+
+// testDeviceHeader - заголовок evemu с описанием геймпада
+var testDeviceHeader = []string{
+	"# EVEMU 1.3\n",
+	"N: Microsoft X-Box 360 pad\n",
+	"I: 0003 045e 028e 0114\n",
+	"P: 00 00 00 00 00 00 00 00\n",
+	"B: 00 0b 00 00 00 00 00 00 00\n",
+	"B: 01 00 00 00 00 00 00 00 00\n",
+	"B: 01 00 00 00 00 00 00 00 00\n",
+	"B: 01 00 00 00 00 00 00 00 00\n",
+	"B: 01 00 00 00 00 00 00 00 00\n",
+	"B: 01 00 00 00 00 00 00 ff 7f\n",
+	"B: 03 3f 00 03 00 00 00 00 00\n",
+	"A: 00 -32768 32767 16 128 0\n",
+	"A: 01 -32768 32767 16 128 0\n",
+	"A: 02 0 255 0 0 0\n",
+	"A: 03 -32768 32767 16 128 0\n",
+	"A: 04 -32768 32767 16 128 0\n",
+	"A: 05 0 255 0 0 0\n",
+	"A: 10 -1 1 0 0 0\n",
+	"A: 11 -1 1 0 0 0\n",
+	"################################\n",
+	"#      Waiting for events      #\n",
+	"################################\n",
+}
+
+// TestParseDescriptor тестирует разбор описания устройства из заголовка
+func TestParseDescriptor(t *testing.T) {
+	d := ParseDescriptor(testDeviceHeader)
+
+	if d.Name != "Microsoft X-Box 360 pad" {
+		t.Errorf("Unexpected name: %q", d.Name)
+	}
+	if d.Bus != 3 || d.Vendor != 0x45e || d.Product != 0x28e || d.Version != 0x114 {
+		t.Errorf("Unexpected id: %x %x %x %x", d.Bus, d.Vendor, d.Product, d.Version)
+	}
+
+	if !d.HasType(EvKey) || d.HasType(EvRel) {
+		t.Error("Unexpected supported types")
+	}
+	if !d.HasCode(EvKey, 0x130) || !d.HasCode(EvKey, 0x13e) || d.HasCode(EvKey, 0x13f) {
+		t.Error("Unexpected key bits")
+	}
+	if !d.HasCode(EvAbs, 0x11) || d.HasCode(EvAbs, 0x06) {
+		t.Error("Unexpected abs bits")
+	}
+	if codes := d.Codes(EvAbs); len(codes) != 8 {
+		t.Errorf("Expected 8 abs codes, got %v", codes)
+	}
+
+	info, ok := d.AbsRange(0x02)
+	if !ok || info.Min != 0 || info.Max != 255 {
+		t.Errorf("Unexpected ABS_Z range: %+v", info)
+	}
+	if _, ok := d.AbsRange(0x06); ok {
+		t.Error("ABS_THROTTLE should not be declared")
+	}
+}
+
+// TestAbsInfoClamp тестирует ограничение значения диапазоном оси
+func TestAbsInfoClamp(t *testing.T) {
+	info := AbsInfo{Min: -10, Max: 10}
+	if info.Clamp(20) != 10 || info.Clamp(-20) != -10 || info.Clamp(5) != 5 {
+		t.Error("Clamp() returned unexpected values")
+	}
+
+	// Диапазон не задан - значение не ограничивается
+	if (AbsInfo{}).Clamp(100) != 100 {
+		t.Error("Clamp() should not limit empty range")
+	}
+}
+
+// TestDescriptorHeaderLines тестирует формирование заголовка по описанию
+func TestDescriptorHeaderLines(t *testing.T) {
+	d := ParseDescriptor(testDeviceHeader)
+	lines := d.HeaderLines()
+
+	// Заголовок тестового геймпада воспроизводится построчно
+	if len(lines) != len(testDeviceHeader) {
+		t.Fatalf("Expected %d lines, got %q", len(testDeviceHeader), lines)
+	}
+	for i, line := range lines {
+		if line != testDeviceHeader[i] {
+			t.Errorf("Line %d: expected %q, got %q", i, testDeviceHeader[i], line)
+		}
+	}
+
+	d = ParseDescriptor(nil)
+	d.SetCode(EvKey, 0x130)
+	d.SetProp(1)
+	parsed := ParseDescriptor(d.HeaderLines())
+	if !parsed.HasCode(EvKey, 0x130) || !parsed.HasCode(EvSyn, EvKey) || len(parsed.Props) != 8 || parsed.Props[0] != 0x02 {
+		t.Errorf("Unexpected round trip: %+v", parsed)
+	}
+}
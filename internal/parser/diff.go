@@ -0,0 +1,408 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// DiffOptions задаёт параметры сравнения записей
+type DiffOptions struct {
+	Tolerance float64 // расхождения интервалов меньше допуска не считаются отличием, секунды
+}
+
+// Виды отличий между записями
+const (
+	DiffInserted = "inserted" // кадр есть только в новой записи
+	DiffRemoved  = "removed"  // кадр есть только в старой записи
+	DiffChanged  = "changed"  // кадр на том же месте, но с другими событиями
+	DiffTiming   = "timing"   // кадры совпадают, но интервал до предыдущего отличается
+)
+
+// FrameDiff описывает отличие одного кадра. Номера кадров начинаются с нуля,
+// отсутствующий кадр обозначается -1.
+type FrameDiff struct {
+	Kind     string   `json:"kind"`
+	OldFrame int      `json:"old_frame"`
+	NewFrame int      `json:"new_frame"`
+	OldTime  float64  `json:"old_time"`
+	NewTime  float64  `json:"new_time"`
+	Removed  []string `json:"removed,omitempty"`
+	Added    []string `json:"added,omitempty"`
+}
+
+// DriftSummary описывает расхождение времени совпавших кадров относительно начала записей
+type DriftSummary struct {
+	Max   float64 `json:"max"`
+	Mean  float64 `json:"mean"`
+	Final float64 `json:"final"`
+}
+
+// DiffResult содержит результат сравнения двух записей
+type DiffResult struct {
+	OldFrames int          `json:"old_frames"`
+	NewFrames int          `json:"new_frames"`
+	Matched   int          `json:"matched"`
+	Drift     DriftSummary `json:"drift"`
+	Changes   []FrameDiff  `json:"changes"`
+}
+
+// Equal сообщает, что записи не отличаются с учётом допуска
+func (r *DiffResult) Equal() bool {
+	return len(r.Changes) == 0
+}
+
+// diffFrame - кадр с событиями без SYN, подготовленный для сравнения
+type diffFrame struct {
+	index     int
+	time      float64
+	events    []string
+	signature string
+}
+
+// Diff сравнивает записи по кадрам. Кадры выравниваются по содержимому
+// (без учёта времени), затем для совпавших кадров сравниваются интервалы.
+func Diff(before, after *EvemuFile, opts DiffOptions) *DiffResult {
+	a, b := diffFrames(before), diffFrames(after)
+	result := &DiffResult{OldFrames: len(a), NewFrames: len(b), Changes: []FrameDiff{}}
+
+	var removed, inserted []diffFrame
+	flush := func() {
+		n := min(len(removed), len(inserted))
+		for i := 0; i < n; i++ {
+			result.Changes = append(result.Changes, changedFrame(removed[i], inserted[i]))
+		}
+		for _, frame := range removed[n:] {
+			result.Changes = append(result.Changes, FrameDiff{
+				Kind: DiffRemoved, OldFrame: frame.index, NewFrame: -1, OldTime: frame.time, Removed: frame.events,
+			})
+		}
+		for _, frame := range inserted[n:] {
+			result.Changes = append(result.Changes, FrameDiff{
+				Kind: DiffInserted, OldFrame: -1, NewFrame: frame.index, NewTime: frame.time, Added: frame.events,
+			})
+		}
+		removed, inserted = nil, nil
+	}
+
+	var prevOld, prevNew *diffFrame
+	driftSum := 0.0
+	for _, op := range alignFrames(a, b) {
+		switch {
+		case op.old >= 0 && op.new >= 0:
+			flush()
+			oldFrame, newFrame := &a[op.old], &b[op.new]
+			drift := (newFrame.time - b[0].time) - (oldFrame.time - a[0].time)
+			result.Matched++
+			driftSum += math.Abs(drift)
+			result.Drift.Max = math.Max(result.Drift.Max, math.Abs(drift))
+			result.Drift.Final = drift
+
+			if prevOld != nil {
+				oldInterval := oldFrame.time - prevOld.time
+				newInterval := newFrame.time - prevNew.time
+				if math.Abs(newInterval-oldInterval) > opts.Tolerance {
+					result.Changes = append(result.Changes, FrameDiff{
+						Kind: DiffTiming, OldFrame: oldFrame.index, NewFrame: newFrame.index,
+						OldTime: oldFrame.time, NewTime: newFrame.time,
+					})
+				}
+			}
+			prevOld, prevNew = oldFrame, newFrame
+		case op.old >= 0:
+			removed = append(removed, a[op.old])
+		default:
+			inserted = append(inserted, b[op.new])
+		}
+	}
+	flush()
+
+	if result.Matched > 0 {
+		result.Drift.Mean = driftSum / float64(result.Matched)
+	}
+	return result
+}
+
+// diffFrames готовит кадры записи к сравнению, пропуская кадры только из SYN
+func diffFrames(f *EvemuFile) []diffFrame {
+	var result []diffFrame
+	for i, frame := range f.Frames() {
+		var events []string
+		for _, event := range frame.Events {
+			if event.TypeNum() != EvSyn {
+				events = append(events, describeEvent(event))
+			}
+		}
+		if len(events) > 0 {
+			result = append(result, diffFrame{
+				index:     i,
+				time:      frame.Timestamp(),
+				events:    events,
+				signature: strings.Join(events, ";"),
+			})
+		}
+	}
+	return result
+}
+
+// describeEvent возвращает описание события с символьными именами
+func describeEvent(event Event) string {
+	typ, code := event.TypeNum(), event.CodeNum()
+	return fmt.Sprintf("%s %s %d", TypeLabel(typ), CodeLabel(typ, code), event.IntValue())
+}
+
+// changedFrame описывает замену кадра: какие события исчезли и какие появились
+func changedFrame(before, after diffFrame) FrameDiff {
+	diff := FrameDiff{Kind: DiffChanged, OldFrame: before.index, NewFrame: after.index, OldTime: before.time, NewTime: after.time}
+
+	remaining := make(map[string]int)
+	for _, event := range after.events {
+		remaining[event]++
+	}
+	for _, event := range before.events {
+		if remaining[event] > 0 {
+			remaining[event]--
+		} else {
+			diff.Removed = append(diff.Removed, event)
+		}
+	}
+
+	present := make(map[string]int)
+	for _, event := range before.events {
+		present[event]++
+	}
+	for _, event := range after.events {
+		if present[event] > 0 {
+			present[event]--
+		} else {
+			diff.Added = append(diff.Added, event)
+		}
+	}
+	return diff
+}
+
+// alignOp - шаг выравнивания: пара совпавших кадров или кадр только одной записи
+type alignOp struct {
+	old int
+	new int
+}
+
+// diffMaxCost - число правок, после которого поиск средней змейки
+// прекращается: участок делится в самой дальней точке, достигнутой прямым
+// проходом. Так на сильно различающихся записях время остаётся
+// O((n+m)·diffMaxCost), а выравнивание - близким к минимальному.
+const diffMaxCost = 256
+
+// aligner выравнивает кадры по номерам сигнатур
+type aligner struct {
+	a, b []int
+	ops  []alignOp
+}
+
+// alignFrames выравнивает кадры алгоритмом Майерса в линейной памяти
+// (поиск средней змейки с разделением участка пополам)
+func alignFrames(a, b []diffFrame) []alignOp {
+	ids := make(map[string]int)
+	intern := func(frames []diffFrame) []int {
+		result := make([]int, len(frames))
+		for i, frame := range frames {
+			id, ok := ids[frame.signature]
+			if !ok {
+				id = len(ids)
+				ids[frame.signature] = id
+			}
+			result[i] = id
+		}
+		return result
+	}
+
+	al := &aligner{a: intern(a), b: intern(b)}
+	al.align(0, len(a), 0, len(b))
+	return al.ops
+}
+
+// align выравнивает участок a[x0:x1] и b[y0:y1]
+func (al *aligner) align(x0, x1, y0, y1 int) {
+	for x0 < x1 && y0 < y1 && al.a[x0] == al.b[y0] {
+		al.ops = append(al.ops, alignOp{old: x0, new: y0})
+		x0++
+		y0++
+	}
+	suffix := 0
+	for x1-suffix > x0 && y1-suffix > y0 && al.a[x1-suffix-1] == al.b[y1-suffix-1] {
+		suffix++
+	}
+	x1, y1 = x1-suffix, y1-suffix
+
+	if x, y, ok := al.split(x0, x1, y0, y1); ok {
+		al.align(x0, x, y0, y)
+		al.align(x, x1, y, y1)
+	} else {
+		for x := x0; x < x1; x++ {
+			al.ops = append(al.ops, alignOp{old: x, new: -1})
+		}
+		for y := y0; y < y1; y++ {
+			al.ops = append(al.ops, alignOp{old: -1, new: y})
+		}
+	}
+
+	for i := 0; i < suffix; i++ {
+		al.ops = append(al.ops, alignOp{old: x1 + i, new: y1 + i})
+	}
+}
+
+// split ищет точку деления участка: конец средней змейки или, если правок
+// больше diffMaxCost, самую дальнюю точку прямого прохода. Возвращает false,
+// если участок пуст с одной стороны или общих кадров нет.
+func (al *aligner) split(x0, x1, y0, y1 int) (int, int, bool) {
+	n, m := x1-x0, y1-y0
+	if n == 0 || m == 0 {
+		return 0, 0, false
+	}
+	a, b := al.a[x0:x1], al.b[y0:y1]
+
+	maxD := (n + m + 1) / 2
+	limit := min(maxD, diffMaxCost)
+	offset := limit + 1
+	forward := make([]int, 2*offset+2)
+	backward := make([]int, 2*offset+2)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	delta := n - m
+	front := delta%2 != 0
+	// Диагонали, вышедшие за край участка, больше не просматриваются
+	k1start, k1end, k2start, k2end := 0, 0, 0, 0
+	for d := 0; d < limit; d++ {
+		for k1 := -d + k1start; k1 <= d-k1end; k1 += 2 {
+			i := offset + k1
+			var x int
+			if k1 == -d || (k1 != d && forward[i-1] < forward[i+1]) {
+				x = forward[i+1]
+			} else {
+				x = forward[i-1] + 1
+			}
+			y := x - k1
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[i] = x
+			switch {
+			case x > n:
+				k1end += 2
+			case y > m:
+				k1start += 2
+			case front:
+				if j := offset + delta - k1; j >= 0 && j < len(backward) && backward[j] != -1 && x >= n-backward[j] {
+					return al.checkSplit(x0, y0, x, y, n, m)
+				}
+			}
+		}
+
+		for k2 := -d + k2start; k2 <= d-k2end; k2 += 2 {
+			i := offset + k2
+			var x int
+			if k2 == -d || (k2 != d && backward[i-1] < backward[i+1]) {
+				x = backward[i+1]
+			} else {
+				x = backward[i-1] + 1
+			}
+			y := x - k2
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			backward[i] = x
+			switch {
+			case x > n:
+				k2end += 2
+			case y > m:
+				k2start += 2
+			case !front:
+				if j := offset + delta - k2; j >= 0 && j < len(forward) && forward[j] != -1 {
+					fx := forward[j]
+					if fx >= n-x {
+						return al.checkSplit(x0, y0, fx, fx-(j-offset), n, m)
+					}
+				}
+			}
+		}
+	}
+
+	if limit == maxD {
+		return 0, 0, false
+	}
+
+	// Правок слишком много: делим участок в самой дальней точке прямого
+	// прохода, из равных - ближайшей к диагонали участка, чтобы различающиеся
+	// кадры сопоставлялись по порядку
+	bestX, bestY := -1, -1
+	for k := -limit; k <= limit; k++ {
+		x := forward[offset+k]
+		y := x - k
+		if x < 0 || x > n || y < 0 || y > m {
+			continue
+		}
+		if x+y > bestX+bestY || (x+y == bestX+bestY && absInt(k-delta) < absInt(bestX-bestY-delta)) {
+			bestX, bestY = x, y
+		}
+	}
+	return al.checkSplit(x0, y0, bestX, bestY, n, m)
+}
+
+// checkSplit переводит точку деления в координаты записей. Точка в углу
+// участка не уменьшила бы задачу, такой участок выравнивается без совпадений.
+func (al *aligner) checkSplit(x0, y0, x, y, n, m int) (int, int, bool) {
+	if x < 0 || y < 0 || (x == 0 && y == 0) || (x == n && y == m) {
+		return 0, 0, false
+	}
+	return x0 + x, y0 + y, true
+}
+
+// WriteText записывает результат сравнения в человекочитаемом виде
+func (r *DiffResult) WriteText(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	for _, change := range r.Changes {
+		switch change.Kind {
+		case DiffInserted:
+			fmt.Fprintf(writer, "+ кадр %d (%.6f)\n", change.NewFrame, change.NewTime)
+		case DiffRemoved:
+			fmt.Fprintf(writer, "- кадр %d (%.6f)\n", change.OldFrame, change.OldTime)
+		case DiffChanged:
+			fmt.Fprintf(writer, "~ кадр %d (%.6f) -> %d (%.6f)\n", change.OldFrame, change.OldTime, change.NewFrame, change.NewTime)
+		case DiffTiming:
+			fmt.Fprintf(writer, "@ кадр %d (%.6f) -> %d (%.6f): интервал изменён\n", change.OldFrame, change.OldTime, change.NewFrame, change.NewTime)
+		}
+		for _, event := range change.Removed {
+			fmt.Fprintf(writer, "    - %s\n", event)
+		}
+		for _, event := range change.Added {
+			fmt.Fprintf(writer, "    + %s\n", event)
+		}
+	}
+
+	fmt.Fprintf(writer, "Кадров: %d -> %d, совпало: %d, отличий: %d\n", r.OldFrames, r.NewFrames, r.Matched, len(r.Changes))
+	fmt.Fprintf(writer, "Дрейф времени: макс %.6f с, средний %.6f с, итоговый %+.6f с\n", r.Drift.Max, r.Drift.Mean, r.Drift.Final)
+	return writer.Flush()
+}
+
+// WriteJSON записывает результат сравнения в формате JSON
+func (r *DiffResult) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// absInt возвращает модуль целого числа
+func absInt(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
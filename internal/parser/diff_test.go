@@ -0,0 +1,207 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// newDiffTestFile создаёт запись из последовательности нажатий с заданными временами
+func newDiffTestFile(times []float64, codes []int) *EvemuFile {
+	file := &EvemuFile{}
+	for i, code := range codes {
+		file.Events = append(file.Events,
+			NewEvent(times[i], EvKey, code, 1),
+			NewEvent(times[i], EvSyn, SynReport, 0),
+		)
+	}
+	return file
+}
+
+// TestDiffEqual тестирует сравнение одинаковых записей со сдвигом и дрожанием времени
+func TestDiffEqual(t *testing.T) {
+	a := newDiffTestFile([]float64{1.0, 1.5, 2.0}, []int{0x130, 0x131, 0x133})
+	b := newDiffTestFile([]float64{5.0, 5.5004, 6.0}, []int{0x130, 0x131, 0x133})
+
+	result := Diff(a, b, DiffOptions{Tolerance: 0.001})
+	if !result.Equal() || result.Matched != 3 {
+		t.Errorf("Expected equal recordings, got %+v", result)
+	}
+	if math.Abs(result.Drift.Max-0.0004) > 1e-9 {
+		t.Errorf("Unexpected drift: %+v", result.Drift)
+	}
+
+	result = Diff(a, b, DiffOptions{Tolerance: 0.0001})
+	if result.Equal() || result.Changes[0].Kind != DiffTiming {
+		t.Errorf("Expected timing difference, got %+v", result.Changes)
+	}
+}
+
+// TestDiffChanges тестирует обнаружение вставленных, удалённых и изменённых кадров
+func TestDiffChanges(t *testing.T) {
+	a := newDiffTestFile([]float64{0, 1, 2, 3}, []int{0x130, 0x131, 0x133, 0x134})
+	b := newDiffTestFile([]float64{0, 1, 2, 3}, []int{0x130, 0x136, 0x133, 0x134})
+	b.Events = append(b.Events, NewEvent(4, EvKey, 0x137, 1), NewEvent(4, EvSyn, SynReport, 0))
+
+	result := Diff(a, b, DiffOptions{Tolerance: 0.001})
+	if len(result.Changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %+v", result.Changes)
+	}
+
+	changed := result.Changes[0]
+	if changed.Kind != DiffChanged || changed.OldFrame != 1 || changed.NewFrame != 1 ||
+		len(changed.Removed) != 1 || changed.Removed[0] != "EV_KEY BTN_EAST 1" ||
+		len(changed.Added) != 1 || changed.Added[0] != "EV_KEY BTN_TL 1" {
+		t.Errorf("Unexpected changed frame: %+v", changed)
+	}
+
+	inserted := result.Changes[1]
+	if inserted.Kind != DiffInserted || inserted.OldFrame != -1 || inserted.NewFrame != 4 {
+		t.Errorf("Unexpected inserted frame: %+v", inserted)
+	}
+
+	result = Diff(b, a, DiffOptions{})
+	if last := result.Changes[len(result.Changes)-1]; last.Kind != DiffRemoved || last.OldFrame != 4 {
+		t.Errorf("Expected removed frame, got %+v", last)
+	}
+}
+
+// TestDiffEmpty тестирует сравнение с пустой записью
+func TestDiffEmpty(t *testing.T) {
+	a := newDiffTestFile([]float64{0, 1}, []int{0x130, 0x131})
+
+	result := Diff(a, &EvemuFile{}, DiffOptions{})
+	if len(result.Changes) != 2 || result.Changes[0].Kind != DiffRemoved {
+		t.Errorf("Unexpected changes: %+v", result.Changes)
+	}
+	if !Diff(&EvemuFile{}, &EvemuFile{}, DiffOptions{}).Equal() {
+		t.Error("Empty recordings should be equal")
+	}
+}
+
+// TestDiffOutput тестирует текстовый и JSON вывод результата
+func TestDiffOutput(t *testing.T) {
+	a := newDiffTestFile([]float64{0, 1}, []int{0x130, 0x131})
+	b := newDiffTestFile([]float64{0, 1}, []int{0x130, 0x133})
+	result := Diff(a, b, DiffOptions{})
+
+	var text bytes.Buffer
+	if err := result.WriteText(&text); err != nil {
+		t.Fatalf("WriteText() failed: %v", err)
+	}
+	for _, expected := range []string{"~ кадр 1", "- EV_KEY BTN_EAST 1", "+ EV_KEY BTN_NORTH 1"} {
+		if !strings.Contains(text.String(), expected) {
+			t.Errorf("Text output missing %q:\n%s", expected, text.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := result.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() failed: %v", err)
+	}
+	var decoded DiffResult
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded.Changes) != 1 {
+		t.Errorf("Unexpected JSON output: %s, %v", buf.String(), err)
+	}
+}
+
+// checkAlignment проверяет, что выравнивание покрывает все кадры по порядку и
+// сопоставляет только одинаковые кадры; возвращает число совпадений
+func checkAlignment(t *testing.T, a, b []diffFrame, ops []alignOp) int {
+	t.Helper()
+	x, y, matched := 0, 0, 0
+	for _, op := range ops {
+		switch {
+		case op.old >= 0 && op.new >= 0:
+			if op.old != x || op.new != y || a[x].signature != b[y].signature {
+				t.Fatalf("Invalid match %+v at %d,%d", op, x, y)
+			}
+			x, y, matched = x+1, y+1, matched+1
+		case op.old >= 0:
+			if op.old != x {
+				t.Fatalf("Invalid removal %+v at %d", op, x)
+			}
+			x++
+		default:
+			if op.new != y {
+				t.Fatalf("Invalid insertion %+v at %d", op, y)
+			}
+			y++
+		}
+	}
+	if x != len(a) || y != len(b) {
+		t.Fatalf("Alignment covers %d/%d and %d/%d frames", x, len(a), y, len(b))
+	}
+	return matched
+}
+
+// diffTestFrames создаёт кадры с заданными сигнатурами
+func diffTestFrames(signatures []int) []diffFrame {
+	frames := make([]diffFrame, len(signatures))
+	for i, signature := range signatures {
+		frames[i] = diffFrame{index: i, signature: string(rune('a' + signature))}
+	}
+	return frames
+}
+
+// TestAlignFramesMinimal сверяет число совпадений с наибольшей общей
+// подпоследовательностью на случайных записях
+func TestAlignFramesMinimal(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for iteration := 0; iteration < 500; iteration++ {
+		sa := make([]int, random.Intn(40))
+		sb := make([]int, random.Intn(40))
+		for i := range sa {
+			sa[i] = random.Intn(4)
+		}
+		for i := range sb {
+			sb[i] = random.Intn(4)
+		}
+
+		lcs := make([][]int, len(sa)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(sb)+1)
+		}
+		for i := len(sa) - 1; i >= 0; i-- {
+			for j := len(sb) - 1; j >= 0; j-- {
+				if sa[i] == sb[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+
+		a, b := diffTestFrames(sa), diffTestFrames(sb)
+		if matched := checkAlignment(t, a, b, alignFrames(a, b)); matched != lcs[0][0] {
+			t.Fatalf("%v / %v: matched %d, expected %d", sa, sb, matched, lcs[0][0])
+		}
+	}
+}
+
+// TestDiffLargeDifferent тестирует сравнение длинных полностью различных
+// записей (стики в каждом кадре): память линейна, время ограничено
+func TestDiffLargeDifferent(t *testing.T) {
+	const frames = 100000
+	a, b := &EvemuFile{}, &EvemuFile{}
+	for i := 0; i < frames; i++ {
+		timestamp := float64(i) * 0.004
+		a.Events = append(a.Events, NewEvent(timestamp, EvAbs, 0x00, i), NewEvent(timestamp, EvSyn, SynReport, 0))
+		b.Events = append(b.Events, NewEvent(timestamp, EvAbs, 0x01, i), NewEvent(timestamp, EvSyn, SynReport, 0))
+	}
+	// Общий кадр в середине должен найтись
+	middle := NewEvent(200.0, EvKey, 0x130, 1)
+	a.Events[frames] = middle
+	b.Events[frames] = middle
+
+	result := Diff(a, b, DiffOptions{})
+	if result.OldFrames != frames || result.NewFrames != frames || result.Matched != 1 {
+		t.Fatalf("Unexpected result: %d/%d frames, matched %d", result.OldFrames, result.NewFrames, result.Matched)
+	}
+	if len(result.Changes) != frames-1 || result.Changes[0].Kind != DiffChanged {
+		t.Errorf("Expected %d changed frames, got %d", frames-1, len(result.Changes))
+	}
+}
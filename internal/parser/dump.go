@@ -0,0 +1,196 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// dumpEventPattern - строка события в стиле evtest:
+// "Event: time 0.583966, type 3 (EV_ABS), code 17 (ABS_HAT0Y), value 0"
+var dumpEventPattern = regexp.MustCompile(
+	`^(?:Event:\s*)?time\s+(-?[0-9.]+),\s*type\s+([^\s,(]+)?\s*(?:\(([^)]*)\))?,\s*code\s+([^\s,(]+)?\s*(?:\(([^)]*)\))?,\s*value\s+(-?[0-9a-fA-Fx]+)\s*$`)
+
+// dumpSyncPattern - разделитель кадров в стиле evtest:
+// "Event: time 0.583966, -------------- SYN_REPORT ------------"
+var dumpSyncPattern = regexp.MustCompile(`^(?:Event:\s*)?time\s+(-?[0-9.]+),\s*[-+>]+\s*(SYN_[A-Z_]+)\s*[-+<]+\s*$`)
+
+// isDumpEventLine проверяет, является ли строка событием или разделителем в стиле evtest
+func isDumpEventLine(line string) bool {
+	return dumpEventPattern.MatchString(line) || dumpSyncPattern.MatchString(line)
+}
+
+// WriteDump записывает EvemuFile в читаемом виде, как его выводит evtest:
+// заголовок evemu без изменений, затем по строке на событие с символьными
+// именами и разделитель после каждого SYN_REPORT. Комментарии сохраняются.
+func (f *EvemuFile) WriteDump(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	for _, line := range f.Header {
+		writer.WriteString(line)
+	}
+
+	comment := 0
+	for i, event := range f.Events {
+		writer.WriteString(formatDumpEvent(event) + "\n")
+
+		if i+1 < len(f.Events) {
+			next := f.Events[i+1].Timestamp
+			for comment < len(f.Comments) && f.Comments[comment].Timestamp < next {
+				writer.WriteString(f.Comments[comment].Text + "\n")
+				comment++
+			}
+		}
+	}
+	for ; comment < len(f.Comments); comment++ {
+		writer.WriteString(f.Comments[comment].Text + "\n")
+	}
+	return writer.Flush()
+}
+
+// formatDumpEvent форматирует событие так же, как evtest
+func formatDumpEvent(event Event) string {
+	typ, code, value := event.TypeNum(), event.CodeNum(), event.IntValue()
+	prefix := fmt.Sprintf("Event: time %.6f, ", event.Timestamp)
+
+	if typ == EvSyn {
+		switch code {
+		case 0x02: // SYN_MT_REPORT
+			return prefix + "++++++++++++++ " + CodeLabel(typ, code) + " ++++++++++++"
+		case 0x03: // SYN_DROPPED
+			return prefix + ">>>>>>>>>>>>>> " + CodeLabel(typ, code) + " <<<<<<<<<<<<"
+		case SynReport:
+			return prefix + "-------------- " + CodeLabel(typ, code) + " ------------"
+		}
+	}
+
+	valueText := strconv.Itoa(value)
+	if isHexValue(typ, code) {
+		valueText = fmt.Sprintf("%02x", value)
+	}
+	return fmt.Sprintf("%stype %d (%s), code %d (%s), value %s",
+		prefix, typ, TypeLabel(typ), code, CodeLabel(typ, code), valueText)
+}
+
+// isHexValue сообщает, что evtest выводит значение события в шестнадцатеричном виде
+func isHexValue(typ, code int) bool {
+	return typ == EvMsc && (code == 0x03 || code == 0x04) // MSC_RAW, MSC_SCAN
+}
+
+// ParseDump разбирает запись в стиле evtest, созданную WriteDump и, возможно,
+// отредактированную вручную. Строки до первого события считаются заголовком.
+// Тип и код можно указать числом (в десятичном виде, как в evtest), символьным
+// именем или обоими; если в скобках указано известное имя, оно важнее числа.
+func ParseDump(r io.Reader) (*EvemuFile, error) {
+	result := &EvemuFile{}
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNumber++
+
+		trimmed := strings.TrimSpace(line)
+		if isDumpEventLine(trimmed) {
+			event, err := parseDumpEvent(trimmed)
+			if err != nil {
+				return nil, fmt.Errorf("строка %d: %v", lineNumber, err)
+			}
+			event.Line = lineNumber
+			result.Events = append(result.Events, event)
+			continue
+		}
+
+		if len(result.Events) == 0 {
+			result.Header = append(result.Header, line+"\n")
+		} else if strings.HasPrefix(line, "#") {
+			result.Comments = append(result.Comments, Comment{
+				Timestamp: result.Events[len(result.Events)-1].Timestamp,
+				Text:      line,
+			})
+		} else if trimmed != "" {
+			return nil, fmt.Errorf("строка %d: не удалось разобрать событие: %s", lineNumber, trimmed)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла: %v", err)
+	}
+	return result, nil
+}
+
+// parseDumpEvent разбирает строку события или разделителя кадров
+func parseDumpEvent(line string) (Event, error) {
+	if match := dumpSyncPattern.FindStringSubmatch(line); match != nil {
+		timestamp, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			return Event{}, fmt.Errorf("некорректное время: %s", match[1])
+		}
+		code, ok := LookupCode(EvSyn, match[2])
+		if !ok {
+			return Event{}, fmt.Errorf("неизвестный разделитель: %s", match[2])
+		}
+		return NewEvent(timestamp, EvSyn, code, 0), nil
+	}
+
+	match := dumpEventPattern.FindStringSubmatch(line)
+	timestamp, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return Event{}, fmt.Errorf("некорректное время: %s", match[1])
+	}
+
+	typ, ok := dumpNumber(match[2], match[3], LookupType)
+	if !ok {
+		return Event{}, fmt.Errorf("неизвестный тип события: %s %s", match[2], match[3])
+	}
+	code, ok := dumpNumber(match[4], match[5], func(name string) (int, bool) {
+		return LookupCode(typ, name)
+	})
+	if !ok {
+		return Event{}, fmt.Errorf("неизвестный код события: %s %s", match[4], match[5])
+	}
+
+	base := 10
+	if isHexValue(typ, code) {
+		base = 16
+	}
+	value, err := strconv.ParseInt(strings.TrimPrefix(match[6], "0x"), base, 32)
+	if err != nil {
+		return Event{}, fmt.Errorf("некорректное значение: %s", match[6])
+	}
+	return NewEvent(timestamp, typ, code, int(value)), nil
+}
+
+// dumpNumber определяет тип или код по имени в скобках либо по числу
+func dumpNumber(number, name string, lookup func(string) (int, bool)) (int, bool) {
+	// Имя в скобках важнее числа: при ручной правке обычно меняют имя
+	if name != "" && !isNumeric(name) && !strings.HasPrefix(name, "0x") {
+		if value, ok := lookup(name); ok {
+			return value, true
+		}
+	}
+	if number == "" {
+		return 0, false
+	}
+	if isNumeric(number) {
+		n, err := strconv.Atoi(number)
+		return n, err == nil && n >= 0
+	}
+	// Вместо числа указано имя: "code BTN_SOUTH"
+	return lookup(number)
+}
+
+// isNumeric проверяет, что строка состоит только из десятичных цифр
+func isNumeric(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
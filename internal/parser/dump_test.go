@@ -0,0 +1,110 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestWriteDump тестирует вывод событий в стиле evtest
+func TestWriteDump(t *testing.T) {
+	f := &EvemuFile{
+		Header: []string{"# EVEMU 1.3\n", "N: pad\n"},
+		Events: []Event{
+			NewEvent(0.583966, EvAbs, 0x11, 0),
+			NewEvent(0.583966, EvMsc, 0x04, 0x90001),
+			NewEvent(0.583966, EvSyn, SynReport, 0),
+			NewEvent(0.6, EvKey, 0x130, 1),
+			NewEvent(0.6, EvSyn, SynReport, 0),
+		},
+		Comments: []Comment{{Timestamp: 0.583966, Text: "# marker: start"}},
+	}
+
+	var buf bytes.Buffer
+	if err := f.WriteDump(&buf); err != nil {
+		t.Fatalf("WriteDump failed: %v", err)
+	}
+
+	expected := "# EVEMU 1.3\n" +
+		"N: pad\n" +
+		"Event: time 0.583966, type 3 (EV_ABS), code 17 (ABS_HAT0Y), value 0\n" +
+		"Event: time 0.583966, type 4 (EV_MSC), code 4 (MSC_SCAN), value 90001\n" +
+		"Event: time 0.583966, -------------- SYN_REPORT ------------\n" +
+		"# marker: start\n" +
+		"Event: time 0.600000, type 1 (EV_KEY), code 304 (BTN_SOUTH), value 1\n" +
+		"Event: time 0.600000, -------------- SYN_REPORT ------------\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected dump:\n%s", buf.String())
+	}
+
+	// Обратная сборка восстанавливает исходную запись
+	parsed, err := ParseDump(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("ParseDump failed: %v", err)
+	}
+	if len(parsed.Header) != 2 || len(parsed.Comments) != 1 || parsed.Comments[0] != f.Comments[0] {
+		t.Errorf("Unexpected header or comments: %+v, %+v", parsed.Header, parsed.Comments)
+	}
+	if len(parsed.Events) != len(f.Events) {
+		t.Fatalf("Expected %d events, got %d", len(f.Events), len(parsed.Events))
+	}
+	lines := []int{3, 4, 5, 7, 8}
+	for i, event := range parsed.Events {
+		if event.Line != lines[i] {
+			t.Errorf("Event %d: unexpected line %d", i, event.Line)
+		}
+		event.Line = 0
+		if event != f.Events[i] {
+			t.Errorf("Event %d: expected %+v, got %+v", i, f.Events[i], event)
+		}
+	}
+}
+
+// TestParseDumpEdited тестирует разбор отредактированного вручную вида
+func TestParseDumpEdited(t *testing.T) {
+	text := "N: pad\n" +
+		"time 1.000000, type 1 (EV_KEY), code 304 (BTN_EAST), value 1\n" + // имя важнее числа
+		"  Event: time 1.000000, type EV_KEY, code A, value 1\n" +
+		"Event: time 1.000000, type 3, code 0, value -1200\n" +
+		"Event: time 1.000000, type 3 (EV_ABS), code 64 (0x40), value 5\n" +
+		"\n" +
+		"Event: time 1.000000, >>>>>>>>>>>>>> SYN_DROPPED <<<<<<<<<<<<\n" +
+		"Event: time 1.000000, --- SYN_REPORT ---\n"
+	f, err := ParseDump(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("ParseDump failed: %v", err)
+	}
+
+	expected := []Event{
+		NewEvent(1.0, EvKey, 0x131, 1),
+		NewEvent(1.0, EvKey, 0x130, 1),
+		NewEvent(1.0, EvAbs, 0x00, -1200),
+		NewEvent(1.0, EvAbs, 0x40, 5),
+		NewEvent(1.0, EvSyn, 0x03, 0),
+		NewEvent(1.0, EvSyn, SynReport, 0),
+	}
+	if len(f.Events) != len(expected) {
+		t.Fatalf("Expected %d events, got %+v", len(expected), f.Events)
+	}
+	for i, event := range f.Events {
+		event.Line = 0
+		if event != expected[i] {
+			t.Errorf("Event %d: expected %+v, got %+v", i, expected[i], event)
+		}
+	}
+}
+
+// TestParseDumpErrors тестирует сообщения об ошибках с номером строки
+func TestParseDumpErrors(t *testing.T) {
+	tests := map[string]string{
+		"Event: time 1.0, type 1, code NOPE, value 1\n":                                            "строка 1",
+		"Event: time 1.0, type 1, code 304, value 1\nгарбаж\n":                                     "строка 2",
+		"Event: time 1.0, type 1, code 304, value 1\nEvent: time 1.0, type 1, code 304, value x\n": "строка 2",
+	}
+	for text, want := range tests {
+		_, err := ParseDump(strings.NewReader(text))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error with %q for %q, got %v", want, text, err)
+		}
+	}
+}
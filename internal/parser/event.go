@@ -0,0 +1,176 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Типы событий evdev, используемые утилитами
+const (
+	EvSyn = 0x00
+	EvKey = 0x01
+	EvRel = 0x02
+	EvAbs = 0x03
+	EvMsc = 0x04
+)
+
+// SynReport - код события SYN_REPORT, завершающего кадр
+const SynReport = 0x00
+
+// NewEvent создаёт событие из числовых значений в формате evemu
+func NewEvent(timestamp float64, typ, code, value int) Event {
+	return Event{
+		Timestamp: timestamp,
+		Type:      fmt.Sprintf("%04x", typ),
+		Code:      fmt.Sprintf("%04x", code),
+		Value:     fmt.Sprintf("%04d", value),
+	}
+}
+
+// TypeNum возвращает тип события как число (-1 при ошибке разбора)
+func (e Event) TypeNum() int {
+	n, err := strconv.ParseInt(e.Type, 16, 32)
+	if err != nil {
+		return -1
+	}
+	return int(n)
+}
+
+// CodeNum возвращает код события как число (-1 при ошибке разбора)
+func (e Event) CodeNum() int {
+	n, err := strconv.ParseInt(e.Code, 16, 32)
+	if err != nil {
+		return -1
+	}
+	return int(n)
+}
+
+// IntValue возвращает значение события как число (0 при ошибке разбора)
+func (e Event) IntValue() int {
+	n, err := strconv.Atoi(e.Value)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// IsSynReport проверяет, является ли событие разделителем кадров SYN_REPORT
+func (e Event) IsSynReport() bool {
+	return e.TypeNum() == EvSyn && e.CodeNum() == SynReport
+}
+
+// Frame представляет группу событий, завершающуюся SYN_REPORT
+type Frame struct {
+	Events []Event
+}
+
+// Timestamp возвращает время кадра (время его первого события)
+func (fr Frame) Timestamp() float64 {
+	if len(fr.Events) == 0 {
+		return 0
+	}
+	return fr.Events[0].Timestamp
+}
+
+// Frames разбивает события файла на кадры по SYN_REPORT.
+// События после последнего SYN_REPORT образуют отдельный незавершённый кадр.
+func (f *EvemuFile) Frames() []Frame {
+	var frames []Frame
+	var current []Event
+	for _, event := range f.Events {
+		current = append(current, event)
+		if event.IsSynReport() {
+			frames = append(frames, Frame{Events: current})
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		frames = append(frames, Frame{Events: current})
+	}
+	return frames
+}
+
+// removeEvents разбивает события на кадры, исключая события, для которых
+// drop возвращает true. Кадры, в которых после удаления остался только
+// SYN_REPORT, отбрасываются; исходно пустые кадры сохраняются.
+func removeEvents(events []Event, drop func(i int, event Event) bool) []Frame {
+	var frames []Frame
+	var current []Event
+	hasPayload, dropped := false, false
+	for i, event := range events {
+		if drop(i, event) {
+			dropped = true
+			continue
+		}
+		current = append(current, event)
+		if !event.IsSynReport() {
+			hasPayload = true
+			continue
+		}
+		if hasPayload || !dropped {
+			frames = append(frames, Frame{Events: current})
+		}
+		current = nil
+		hasPayload, dropped = false, false
+	}
+	if hasPayload {
+		frames = append(frames, Frame{Events: current})
+	}
+	return frames
+}
+
+// mergeFrames объединяет наборы кадров в одну последовательность событий,
+// упорядоченную по времени. При равном времени сохраняется исходный порядок.
+func mergeFrames(groups ...[]Frame) []Event {
+	var frames []Frame
+	for _, group := range groups {
+		frames = append(frames, group...)
+	}
+
+	sort.SliceStable(frames, func(i, j int) bool {
+		return frames[i].Timestamp() < frames[j].Timestamp()
+	})
+
+	var result []Event
+	for _, frame := range frames {
+		result = append(result, frame.Events...)
+	}
+	return result
+}
+
+// newFrame создаёт кадр из событий с завершающим SYN_REPORT
+func newFrame(timestamp float64, events ...Event) Frame {
+	return Frame{Events: append(events, NewEvent(timestamp, EvSyn, SynReport, 0))}
+}
+
+// heldKeys возвращает коды кнопок, оставшихся нажатыми в конце последовательности
+func heldKeys(events []Event) []int {
+	state := make(map[int]bool)
+	for _, event := range events {
+		if event.TypeNum() == EvKey {
+			state[event.CodeNum()] = event.IntValue() != 0
+		}
+	}
+
+	var held []int
+	for code, pressed := range state {
+		if pressed {
+			held = append(held, code)
+		}
+	}
+	sort.Ints(held)
+	return held
+}
+
+// releaseEvents формирует кадр отпускания для перечисленных кнопок
+func releaseEvents(timestamp float64, codes []int) []Event {
+	if len(codes) == 0 {
+		return nil
+	}
+	var events []Event
+	for _, code := range codes {
+		events = append(events, NewEvent(timestamp, EvKey, code, 0))
+	}
+	return append(events, NewEvent(timestamp, EvSyn, SynReport, 0))
+}
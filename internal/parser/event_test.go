@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"testing"
+)
+
+// TestNewEvent тестирует формирование события из числовых значений
+func TestNewEvent(t *testing.T) {
+	event := NewEvent(1.5, EvAbs, 0x11, -1)
+	if event.Type != "0003" || event.Code != "0011" || event.Value != "-001" {
+		t.Errorf("NewEvent() = %+v", event)
+	}
+
+	if event.TypeNum() != EvAbs || event.CodeNum() != 0x11 || event.IntValue() != -1 {
+		t.Errorf("Numeric accessors mismatch: %d %d %d", event.TypeNum(), event.CodeNum(), event.IntValue())
+	}
+
+	invalid := Event{Type: "zz", Code: "zz", Value: "zz"}
+	if invalid.TypeNum() != -1 || invalid.CodeNum() != -1 || invalid.IntValue() != 0 {
+		t.Error("Invalid event should return default numeric values")
+	}
+}
+
+// TestFrames тестирует разбиение событий на кадры
+func TestFrames(t *testing.T) {
+	file := &EvemuFile{
+		Events: []Event{
+			{Timestamp: 0.1, Type: "0003", Code: "0000", Value: "0100"},
+			{Timestamp: 0.1, Type: "0003", Code: "0001", Value: "0200"},
+			{Timestamp: 0.1, Type: "0000", Code: "0000", Value: "0000"},
+			{Timestamp: 0.2, Type: "0001", Code: "0130", Value: "0001"},
+			{Timestamp: 0.2, Type: "0000", Code: "0000", Value: "0000"},
+			{Timestamp: 0.3, Type: "0001", Code: "0130", Value: "0000"},
+		},
+	}
+
+	frames := file.Frames()
+	if len(frames) != 3 {
+		t.Fatalf("Expected 3 frames, got %d", len(frames))
+	}
+
+	expectedSizes := []int{3, 2, 1}
+	for i, frame := range frames {
+		if len(frame.Events) != expectedSizes[i] {
+			t.Errorf("Frame %d: expected %d events, got %d", i, expectedSizes[i], len(frame.Events))
+		}
+	}
+
+	if frames[1].Timestamp() != 0.2 {
+		t.Errorf("Expected frame timestamp 0.2, got %f", frames[1].Timestamp())
+	}
+}
+
+// TestHeldKeys тестирует поиск удерживаемых кнопок
+func TestHeldKeys(t *testing.T) {
+	events := []Event{
+		NewEvent(0.1, EvKey, 0x131, 1),
+		NewEvent(0.2, EvKey, 0x130, 1),
+		NewEvent(0.3, EvKey, 0x131, 0),
+		NewEvent(0.4, EvKey, 0x133, 2),
+	}
+
+	held := heldKeys(events)
+	if len(held) != 2 || held[0] != 0x130 || held[1] != 0x133 {
+		t.Errorf("heldKeys() = %v, expected [0x130 0x133]", held)
+	}
+
+	release := releaseEvents(1.0, held)
+	if len(release) != 3 || !release[2].IsSynReport() || release[0].IntValue() != 0 {
+		t.Errorf("releaseEvents() = %+v", release)
+	}
+
+	if releaseEvents(1.0, nil) != nil {
+		t.Error("releaseEvents() should return nil for no keys")
+	}
+}
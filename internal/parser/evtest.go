@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Строки вступления, которое evtest выводит перед событиями
+var (
+	evtestIDPattern    = regexp.MustCompile(`^Input device ID: bus (0x[0-9a-fA-F]+) vendor (0x[0-9a-fA-F]+) product (0x[0-9a-fA-F]+) version (0x[0-9a-fA-F]+)`)
+	evtestNamePattern  = regexp.MustCompile(`^Input device name: "(.*)"`)
+	evtestTypePattern  = regexp.MustCompile(`^\s*Event type (\d+)`)
+	evtestCodePattern  = regexp.MustCompile(`^\s*Event code (\d+)`)
+	evtestAbsPattern   = regexp.MustCompile(`^\s*(Value|Min|Max|Fuzz|Flat|Resolution)\s+(-?\d+)`)
+	evtestPropPattern  = regexp.MustCompile(`^\s*Property type (\d+)`)
+	evtestDriverPrefix = "Input driver version is"
+)
+
+// isEvtestPreamble проверяет, начинается ли с этой строки вывод evtest
+func isEvtestPreamble(line string) bool {
+	return strings.HasPrefix(line, evtestDriverPrefix) || evtestIDPattern.MatchString(line)
+}
+
+// ParseEvtest импортирует вывод evtest. Заголовок evemu синтезируется из
+// вступления evtest (имя, идентификатор, поддерживаемые события и параметры
+// осей) и дополняется кодами, встретившимися в событиях. Время событий
+// отсчитывается от первого события.
+func ParseEvtest(r io.Reader) (*EvemuFile, error) {
+	descriptor := ParseDescriptor(nil)
+	result := &EvemuFile{}
+
+	typ, code := -1, -1
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		lineNumber++
+		trimmed := strings.TrimSpace(line)
+
+		if isDumpEventLine(trimmed) {
+			event, err := parseDumpEvent(trimmed)
+			if err != nil {
+				return nil, fmt.Errorf("строка %d: %v", lineNumber, err)
+			}
+			event.Line = lineNumber
+			result.Events = append(result.Events, event)
+			continue
+		}
+
+		if match := evtestIDPattern.FindStringSubmatch(line); match != nil {
+			descriptor.Bus = parseHex(match[1])
+			descriptor.Vendor = parseHex(match[2])
+			descriptor.Product = parseHex(match[3])
+			descriptor.Version = parseHex(match[4])
+		} else if match := evtestNamePattern.FindStringSubmatch(line); match != nil {
+			descriptor.Name = match[1]
+		} else if match := evtestTypePattern.FindStringSubmatch(line); match != nil {
+			typ, code = parseInt(match[1]), -1
+			if _, ok := descriptor.Bits[typ]; !ok {
+				descriptor.Bits[typ] = nil
+			}
+		} else if match := evtestCodePattern.FindStringSubmatch(line); match != nil && typ >= 0 {
+			code = parseInt(match[1])
+			descriptor.SetCode(typ, code)
+		} else if match := evtestAbsPattern.FindStringSubmatch(line); match != nil && typ == EvAbs && code >= 0 {
+			info := descriptor.Abs[code]
+			value := parseInt(match[2])
+			switch match[1] {
+			case "Min":
+				info.Min = value
+			case "Max":
+				info.Max = value
+			case "Fuzz":
+				info.Fuzz = value
+			case "Flat":
+				info.Flat = value
+			case "Resolution":
+				info.Resolution = value
+			}
+			descriptor.Abs[code] = info
+		} else if match := evtestPropPattern.FindStringSubmatch(line); match != nil {
+			descriptor.SetProp(parseInt(match[1]))
+		}
+		// Остальные строки (список устройств, "Testing ...") пропускаются
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла: %v", err)
+	}
+
+	if len(result.Events) > 0 {
+		start := result.Events[0].Timestamp
+		for i := range result.Events {
+			result.Events[i].Timestamp -= start
+		}
+	}
+	descriptor.declareEvents(result.Events)
+
+	result.Header = descriptor.HeaderLines()
+	return result, nil
+}
@@ -0,0 +1,126 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// testEvtestLog - фрагмент вывода evtest для геймпада Xbox 360
+const testEvtestLog = `No device specified, trying to scan all of /dev/input/event*
+Available devices:
+/dev/input/event5:	Microsoft X-Box 360 pad
+Select the device event number [0-5]: 5
+Input driver version is 1.0.1
+Input device ID: bus 0x3 vendor 0x45e product 0x28e version 0x114
+Input device name: "Microsoft X-Box 360 pad"
+Supported events:
+  Event type 0 (EV_SYN)
+  Event type 1 (EV_KEY)
+    Event code 304 (BTN_SOUTH)
+    Event code 305 (BTN_EAST)
+  Event type 3 (EV_ABS)
+    Event code 0 (ABS_X)
+      Value   -129
+      Min   -32768
+      Max    32767
+      Fuzz      16
+      Flat     128
+    Event code 17 (ABS_HAT0Y)
+      Value      0
+      Min       -1
+      Max        1
+  Event type 21 (EV_FF)
+    Event code 80 (FF_RUMBLE)
+Properties:
+  Property type 1 (INPUT_PROP_DIRECT)
+Testing ... (interrupt to exit)
+Event: time 1700000000.500000, type 1 (EV_KEY), code 304 (BTN_SOUTH), value 1
+Event: time 1700000000.500000, -------------- SYN_REPORT ------------
+Event: time 1700000000.583966, type 3 (EV_ABS), code 17 (ABS_HAT0Y), value -1
+Event: time 1700000000.583966, type 3 (EV_ABS), code 1 (ABS_Y), value 300
+Event: time 1700000000.583966, -------------- SYN_REPORT ------------
+Event: time 1700000000.700000, type 1 (EV_KEY), code 304 (BTN_SOUTH), value 0
+Event: time 1700000000.700000, -------------- SYN_REPORT ------------
+`
+
+// TestParseEvtest тестирует импорт вывода evtest с синтезом заголовка
+func TestParseEvtest(t *testing.T) {
+	f, err := ParseEvtest(strings.NewReader(testEvtestLog))
+	if err != nil {
+		t.Fatalf("ParseEvtest failed: %v", err)
+	}
+
+	expectedHeader := []string{
+		"# EVEMU 1.3\n",
+		"N: Microsoft X-Box 360 pad\n",
+		"I: 0003 045e 028e 0114\n",
+		"P: 02 00 00 00 00 00 00 00\n",
+		"B: 00 0b 00 20 00 00 00 00 00\n",
+	}
+	for i, want := range expectedHeader {
+		if f.Header[i] != want {
+			t.Errorf("Header line %d: expected %q, got %q", i, want, f.Header[i])
+		}
+	}
+	if last := f.Header[len(f.Header)-1]; last != "################################\n" {
+		t.Errorf("Expected banner at the end of header, got %q", last)
+	}
+
+	d := ParseDescriptor(f.Header)
+	if !d.HasCode(EvKey, 0x130) || !d.HasCode(EvKey, 0x131) || !d.HasCode(0x15, 0x50) {
+		t.Errorf("Expected declared codes, got %v", d.Bits)
+	}
+	if info, _ := d.AbsRange(0x00); info != (AbsInfo{Min: -32768, Max: 32767, Fuzz: 16, Flat: 128}) {
+		t.Errorf("Unexpected ABS_X info: %+v", info)
+	}
+	if info, _ := d.AbsRange(0x11); info.Min != -1 || info.Max != 1 {
+		t.Errorf("Unexpected ABS_HAT0Y info: %+v", info)
+	}
+	// ABS_Y не объявлена во вступлении: код и диапазон берутся из событий
+	if info, ok := d.AbsRange(0x01); !ok || !d.HasCode(EvAbs, 0x01) || info.Min != 300 || info.Max != 300 {
+		t.Errorf("Unexpected ABS_Y info: %+v, %v", info, ok)
+	}
+
+	expected := []Event{
+		NewEvent(0, EvKey, 0x130, 1),
+		NewEvent(0, EvSyn, SynReport, 0),
+		NewEvent(0.083966, EvAbs, 0x11, -1),
+		NewEvent(0.083966, EvAbs, 0x01, 300),
+		NewEvent(0.083966, EvSyn, SynReport, 0),
+		NewEvent(0.2, EvKey, 0x130, 0),
+		NewEvent(0.2, EvSyn, SynReport, 0),
+	}
+	if len(f.Events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d", len(expected), len(f.Events))
+	}
+	for i, event := range f.Events {
+		if event.Type != expected[i].Type || event.Code != expected[i].Code || event.Value != expected[i].Value ||
+			event.Timestamp-expected[i].Timestamp > 1e-6 || expected[i].Timestamp-event.Timestamp > 1e-6 {
+			t.Errorf("Event %d: expected %+v, got %+v", i, expected[i], event)
+		}
+	}
+	if f.Events[0].Line != 29 {
+		t.Errorf("Expected first event at line 29, got %d", f.Events[0].Line)
+	}
+
+	// Импортированная запись проходит проверку и читается как файл evemu
+	if report := f.Validate(ValidateOptions{}); len(report.Issues) != 0 {
+		t.Errorf("Unexpected issues: %+v", report.Issues)
+	}
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	reparsed, err := ParseData(buf.Bytes())
+	if err != nil || len(reparsed.Events) != len(expected) {
+		t.Errorf("Failed to read back imported recording: %v", err)
+	}
+}
+
+// TestDetectEvtest тестирует автоматическое определение вывода evtest
+func TestDetectEvtest(t *testing.T) {
+	if format := DetectFormat([]byte(testEvtestLog)); format != FormatEvtest {
+		t.Errorf("Expected %s, got %s", FormatEvtest, format)
+	}
+}
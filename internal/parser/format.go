@@ -0,0 +1,207 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Форматы записей, распознаваемые при чтении
+const (
+	FormatEvemu    = "evemu"    // формат evemu-record
+	FormatDump     = "dump"     // читаемый вид в стиле evtest (WriteDump)
+	FormatEvtest   = "evtest"   // вывод evtest со вступлением об устройстве
+	FormatJSON     = "json"     // JSON-представление (WriteJSON)
+	FormatJSONL    = "jsonl"    // JSON Lines: строка на событие (WriteJSONL)
+	FormatCSV      = "csv"      // таблица событий с заголовком в преамбуле (WriteCSV)
+	FormatLibinput = "libinput" // YAML libinput record (WriteLibinput)
+	FormatRaw      = "raw"      // дамп struct input_event (WriteInputEvents)
+	FormatCompact  = "compact"  // компактный двоичный формат (WriteCompact)
+
+	// FormatJSONLFrames - JSON Lines со строкой на кадр; при чтении не
+	// отличается от FormatJSONL
+	FormatJSONLFrames = "jsonl-frames"
+)
+
+// DetectFormat определяет формат записи по вступлению evtest или по первой
+// строке с событием. Если событий нет, запись считается файлом evemu.
+// Данные, начинающиеся с "{", считаются JSON или, если первая строка -
+// самостоятельный объект, JSON Lines. Запись libinput record узнаётся по
+// баннеру или ключу ndevices, компактный формат - по сигнатуре, остальные
+// двоичные данные считаются дампом struct input_event; ParseData отвергает
+// их, если раскладка дампа не определяется.
+func DetectFormat(data []byte) string {
+	if isCompact(data) {
+		return FormatCompact
+	}
+	if isBinary(data) {
+		return FormatRaw
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		if isJSONL(data) {
+			return FormatJSONL
+		}
+		return FormatJSON
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case isLibinputLine(line):
+			return FormatLibinput
+		case isCSVLine(line):
+			return FormatCSV
+		case isEvtestPreamble(line):
+			return FormatEvtest
+		case strings.HasPrefix(line, "E:"):
+			return FormatEvemu
+		case isDumpEventLine(line):
+			return FormatDump
+		}
+	}
+	return FormatEvemu
+}
+
+// IsRecording проверяет по началу данных, что это запись в одном из
+// поддерживаемых форматов или архив записей. В отличие от DetectFormat текст
+// без событий и строк заголовка evemu и двоичные данные, не похожие на дамп,
+// записью не считаются.
+func IsRecording(data []byte) bool {
+	if IsBundle(data) {
+		return true
+	}
+	switch DetectFormat(data) {
+	case FormatEvemu:
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if strings.HasPrefix(line, "E:") || strings.HasPrefix(line, "N:") || strings.HasPrefix(line, "# EVEMU") {
+				return true
+			}
+		}
+		return false
+	case FormatRaw:
+		// Начало длинного дампа обрезается до целого числа записей любой раскладки
+		whole := len(data) - len(data)%(InputEventSize64*InputEventSize32/8)
+		if whole == 0 {
+			whole = len(data)
+		}
+		_, err := DetectInputEventLayout(data[:whole])
+		return err == nil
+	default:
+		return true
+	}
+}
+
+// ParseData разбирает запись в любом из поддерживаемых форматов; данные,
+// сжатые gzip, предварительно распаковываются
+func ParseData(data []byte) (*EvemuFile, error) {
+	data, err := Decompress(data)
+	if err != nil {
+		return nil, err
+	}
+	if IsBundle(data) {
+		return nil, fmt.Errorf("данные - архив записей, укажите запись как bundle.zip%sимя", BundleClipSeparator)
+	}
+	switch DetectFormat(data) {
+	case FormatDump:
+		return ParseDump(bytes.NewReader(data))
+	case FormatEvtest:
+		return ParseEvtest(bytes.NewReader(data))
+	case FormatJSON:
+		return ParseJSON(bytes.NewReader(data))
+	case FormatJSONL:
+		return ParseJSONL(bytes.NewReader(data))
+	case FormatCSV:
+		return ParseCSV(bytes.NewReader(data))
+	case FormatLibinput:
+		return ParseLibinput(bytes.NewReader(data))
+	case FormatRaw:
+		// Нулевые байты бывают не только в дампах: если раскладка не
+		// подошла, формат данных не определён
+		layout, err := DetectInputEventLayout(data)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось определить формат данных: двоичные данные не похожи ни на компактный формат, ни на дамп struct input_event (%v)", err)
+		}
+		return ParseInputEventsLayout(data, layout)
+	case FormatCompact:
+		return ParseCompact(bytes.NewReader(data))
+	default:
+		return ParseEvemu(bytes.NewReader(data))
+	}
+}
+
+// recordingPeekSize - объём начала потока, по которому ReadRecording
+// определяет формат
+const recordingPeekSize = 64 * 1024
+
+// ReadRecording читает запись из потока в любом из поддерживаемых форматов;
+// поток, сжатый gzip, распаковывается по мере чтения. Формат определяется по
+// началу потока: JSON Lines разбирается построчно, компактный формат - по
+// блокам прямо из потока, остальные форматы читаются целиком и разбираются
+// ParseData.
+func ReadRecording(r io.Reader) (*EvemuFile, error) {
+	result, _, err := readRecording(r)
+	return result, err
+}
+
+// readRecording читает запись из потока и возвращает определённый формат
+func readRecording(r io.Reader) (*EvemuFile, string, error) {
+	reader, err := decompressStream(bufio.NewReaderSize(r, recordingPeekSize))
+	if err != nil {
+		return nil, "", err
+	}
+	prefix, err := reader.Peek(recordingPeekSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, "", fmt.Errorf("ошибка чтения файла: %v", err)
+	}
+
+	// Первая строка JSON Lines могла не поместиться в начало потока: тогда
+	// формат уточняется по данным целиком
+	format := DetectFormat(prefix)
+	switch format {
+	case FormatJSONL:
+		result, err := ParseJSONL(reader)
+		return result, format, err
+	case FormatCompact:
+		result, err := ParseCompact(reader)
+		return result, format, err
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, "", fmt.Errorf("ошибка чтения файла: %v", err)
+	}
+	result, err := ParseData(data)
+	return result, DetectFormat(data), err
+}
+
+// WriteFormat записывает запись в заданном формате: evemu, dump, json,
+// jsonl, jsonl-frames, csv, libinput, compact или raw[-<раскладка>]
+func (f *EvemuFile) WriteFormat(w io.Writer, format string) error {
+	if layout, ok := RawFormatLayout(format); ok {
+		return f.WriteInputEvents(w, layout)
+	}
+	switch format {
+	case "", FormatEvemu:
+		return f.Write(w)
+	case FormatDump:
+		return f.WriteDump(w)
+	case FormatJSON:
+		return f.WriteJSON(w)
+	case FormatJSONL:
+		return f.WriteJSONL(w, false)
+	case FormatJSONLFrames:
+		return f.WriteJSONL(w, true)
+	case FormatCSV:
+		return f.WriteCSV(w, true)
+	case FormatLibinput:
+		return f.WriteLibinput(w)
+	case FormatCompact:
+		return f.WriteCompact(w)
+	default:
+		return fmt.Errorf("неизвестный формат записи: %s", format)
+	}
+}
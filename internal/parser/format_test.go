@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestDetectFormat тестирует определение формата записи
+func TestDetectFormat(t *testing.T) {
+	tests := map[string]string{
+		"# EVEMU 1.3\nN: pad\nE: 0.000000 0001 0130 0001\n":                               FormatEvemu,
+		"# EVEMU 1.3\nN: pad\nEvent: time 0.000000, type 1 (EV_KEY), code 304, value 1\n": FormatDump,
+		"N: pad\n":                         FormatEvemu,
+		"  {\"format\": \"evemu-json\"}\n": FormatJSON,
+	}
+	for text, expected := range tests {
+		if format := DetectFormat([]byte(text)); format != expected {
+			t.Errorf("DetectFormat(%q): expected %s, got %s", text, expected, format)
+		}
+	}
+
+	f, err := ParseData([]byte("Event: time 0.5, type 1, code 304, value 1\nEvent: time 0.5, ---- SYN_REPORT ----\n"))
+	if err != nil || len(f.Events) != 2 || f.Events[0].CodeNum() != 0x130 {
+		t.Errorf("Unexpected parse result: %+v, %v", f, err)
+	}
+}
+
+// TestWriteFormat тестирует запись в каждом из форматов
+func TestWriteFormat(t *testing.T) {
+	f := &EvemuFile{Header: testDeviceHeader, Events: []Event{
+		NewEvent(0.5, EvKey, 0x130, 1),
+		NewEvent(0.5, EvSyn, SynReport, 0),
+	}}
+	for _, format := range []string{FormatEvemu, FormatDump, FormatJSON, FormatLibinput} {
+		var buf bytes.Buffer
+		if err := f.WriteFormat(&buf, format); err != nil {
+			t.Fatalf("WriteFormat(%s) failed: %v", format, err)
+		}
+		if detected := DetectFormat(buf.Bytes()); detected != format {
+			t.Errorf("Expected %s output to be detected as such, got %s", format, detected)
+		}
+		parsed, err := ParseData(buf.Bytes())
+		if err != nil || len(parsed.Events) != 2 || parsed.Events[0].CodeNum() != 0x130 {
+			t.Errorf("Unexpected %s round-trip: %+v, %v", format, parsed, err)
+		}
+	}
+	if err := f.WriteFormat(&bytes.Buffer{}, "xml"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+// endlessReader бесконечно повторяет строку
+type endlessReader struct {
+	line []byte
+	read int
+}
+
+func (r *endlessReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		copied := copy(p[n:], r.line[r.read%len(r.line):])
+		r.read += copied
+		n += copied
+	}
+	return n, nil
+}
+
+// TestReadRecordingStreamsJSONL тестирует, что JSON Lines разбирается по мере
+// чтения: ошибка в начале бесконечного потока обнаруживается сразу
+func TestReadRecordingStreamsJSONL(t *testing.T) {
+	f, err := ParseEvemu(strings.NewReader(jsonlTestText))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	var stream bytes.Buffer
+	if err := f.WriteJSONL(&stream, false); err != nil {
+		t.Fatalf("WriteJSONL failed: %v", err)
+	}
+
+	// Сжатый поток длиннее начала, по которому определяется формат
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	head, events, _ := strings.Cut(stream.String(), "\n")
+	writer.Write([]byte(head + "\n"))
+	for i := 0; i < 2000; i++ {
+		writer.Write([]byte(events))
+	}
+	writer.Close()
+	result, err := ReadRecording(&compressed)
+	if err != nil || len(result.Events) != 2000*len(f.Events) {
+		t.Fatalf("Unexpected result of compressed stream: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		endless := &endlessReader{line: []byte(`{"time": 0.1, "type": 1, "code": 304, "value": 1}` + "\n")}
+		_, err := ReadRecording(io.MultiReader(strings.NewReader(head+"\n{broken\n"), endless))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "строка 2") {
+			t.Errorf("Expected error at line 2, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ReadRecording did not stop at the broken line: input is not streamed")
+	}
+}
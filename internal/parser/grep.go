@@ -0,0 +1,385 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Действия шагов шаблона поиска
+const (
+	StepPress   = "press"   // нажатие кнопки
+	StepRelease = "release" // отпускание кнопки
+	StepHold    = "hold"    // нажатие кнопки, удерживаемой до следующего шага
+	StepAxis    = "axis"    // значение оси, удовлетворяющее сравнению
+)
+
+// PatternStep - один шаг шаблона
+type PatternStep struct {
+	Action string
+	Type   int
+	Code   int
+	Op     string  // оператор сравнения для осей: = != < <= > >=
+	Value  int     // значение для сравнения
+	For    float64 // для hold: минимальная длительность удержания, секунды
+	Within float64 // наибольшая пауза после предыдущего шага, секунды (0 - без ограничения)
+}
+
+// Pattern - последовательность шагов, разделённых "then"
+type Pattern struct {
+	Text  string
+	Steps []PatternStep
+}
+
+// Match - найденное вхождение шаблона: время и строка первого события
+// и события, совпавшие с шагами
+type Match struct {
+	Time   float64
+	Line   int
+	Events []Event
+}
+
+// ParsePattern разбирает шаблон вида
+//
+//	hold LB then press A within 200ms
+//	press START then ABS_HAT0Y = -1 within 0.5 then release START
+//	hold RB for 1s
+//
+// Шаги: press <кнопка>, release <кнопка>, hold <кнопка> [for <время>],
+// <ось> <оператор> <значение>. После шага, начиная со второго, можно
+// указать within <время> - наибольшую паузу после предыдущего шага.
+func ParsePattern(text string) (*Pattern, error) {
+	pattern := &Pattern{Text: text}
+	for i, part := range splitWords(text, "then") {
+		step, err := parseStep(part)
+		if err != nil {
+			return nil, fmt.Errorf("шаг %d: %v", i+1, err)
+		}
+		if i == 0 && step.Within > 0 {
+			return nil, fmt.Errorf("шаг 1: within допускается только после then")
+		}
+		pattern.Steps = append(pattern.Steps, step)
+	}
+	return pattern, nil
+}
+
+// splitWords делит список слов по разделителю, отбрасывая пустые части
+func splitWords(text, separator string) [][]string {
+	parts := [][]string{nil}
+	for _, word := range strings.Fields(text) {
+		if strings.EqualFold(word, separator) {
+			parts = append(parts, nil)
+			continue
+		}
+		parts[len(parts)-1] = append(parts[len(parts)-1], word)
+	}
+	return parts
+}
+
+// parseStep разбирает один шаг шаблона
+func parseStep(words []string) (PatternStep, error) {
+	var step PatternStep
+	if len(words) == 0 {
+		return step, fmt.Errorf("пустой шаг")
+	}
+
+	// Необязательные модификаторы в конце шага: for <время>, within <время>
+	for len(words) >= 3 {
+		n := len(words)
+		keyword := strings.ToLower(words[n-2])
+		if keyword != "for" && keyword != "within" {
+			break
+		}
+		d, err := parseDuration(words[n-1])
+		if err != nil {
+			return step, err
+		}
+		if keyword == "for" {
+			step.For = d
+		} else {
+			step.Within = d
+		}
+		words = words[:n-2]
+	}
+
+	switch action := strings.ToLower(words[0]); action {
+	case StepPress, StepRelease, StepHold:
+		if len(words) != 2 {
+			return step, fmt.Errorf("ожидается %s <кнопка>", action)
+		}
+		code, ok := LookupKey(words[1])
+		if !ok {
+			return step, fmt.Errorf("неизвестная кнопка: %s", words[1])
+		}
+		step.Action, step.Type, step.Code = action, EvKey, code
+	default:
+		if len(words) != 3 {
+			return step, fmt.Errorf("ожидается press, release, hold или <ось> <оператор> <значение>: %s", strings.Join(words, " "))
+		}
+		code, ok := LookupCode(EvAbs, words[0])
+		if !ok {
+			return step, fmt.Errorf("неизвестная ось: %s", words[0])
+		}
+		switch words[1] {
+		case "=", "==", "!=", "<", "<=", ">", ">=":
+		default:
+			return step, fmt.Errorf("неизвестный оператор: %s", words[1])
+		}
+		value, err := strconv.Atoi(words[2])
+		if err != nil {
+			return step, fmt.Errorf("некорректное значение: %s", words[2])
+		}
+		step.Action, step.Type, step.Code, step.Op, step.Value = StepAxis, EvAbs, code, words[1], value
+	}
+
+	if step.For > 0 && step.Action != StepHold {
+		return step, fmt.Errorf("for допускается только для hold")
+	}
+	return step, nil
+}
+
+// matches проверяет, совпадает ли событие с шагом без учёта времени
+func (s PatternStep) matches(event Event) bool {
+	if event.TypeNum() != s.Type || event.CodeNum() != s.Code {
+		return false
+	}
+	value := event.IntValue()
+	switch s.Action {
+	case StepPress, StepHold:
+		return value == 1
+	case StepRelease:
+		return value == 0
+	}
+	switch s.Op {
+	case "=", "==":
+		return value == s.Value
+	case "!=":
+		return value != s.Value
+	case "<":
+		return value < s.Value
+	case "<=":
+		return value <= s.Value
+	case ">":
+		return value > s.Value
+	default:
+		return value >= s.Value
+	}
+}
+
+// Grep находит все вхождения шаблона. Каждое событие, совпавшее с первым
+// шагом, начинает отдельное вхождение; для остальных шагов выбирается самое
+// раннее подходящее событие, при котором выполняются все ограничения.
+func (f *EvemuFile) Grep(pattern *Pattern) []Match {
+	events := f.Events
+	n := len(events)
+	steps := pattern.Steps
+	if len(steps) == 0 {
+		return nil
+	}
+
+	// release[i] - индекс отпускания кнопки, нажатой событием i (n, если не отпущена)
+	release := make([]int, n)
+	pending := make(map[int][]int)
+	for i, event := range events {
+		release[i] = n
+		if event.TypeNum() != EvKey {
+			continue
+		}
+		code := event.CodeNum()
+		switch event.IntValue() {
+		case 1:
+			pending[code] = append(pending[code], i)
+		case 0:
+			for _, press := range pending[code] {
+				release[press] = i
+			}
+			delete(pending, code)
+		}
+	}
+	heldFor := func(i int) float64 {
+		if release[i] == n {
+			return events[n-1].Timestamp - events[i].Timestamp
+		}
+		return events[release[i]].Timestamp - events[i].Timestamp
+	}
+
+	// Динамика с последнего шага: next[k][i] - событие следующего шага,
+	// продолжающее вхождение, в котором шаг k совпал с событием i (-1 - нет)
+	next := make([][]int, len(steps))
+	good := make([]bool, n)
+	for k := len(steps) - 1; k >= 0; k-- {
+		step := steps[k]
+		next[k] = make([]int, n)
+		following := -1 // ближайшее событие после i, подходящее для шага k+1
+		current := make([]bool, n)
+		for i := n - 1; i >= 0; i-- {
+			next[k][i] = -1
+			if step.matches(events[i]) && (step.For == 0 || heldFor(i) >= step.For) {
+				switch {
+				case k == len(steps)-1:
+					current[i] = true
+				case following >= 0:
+					within := steps[k+1].Within
+					inTime := within == 0 || events[following].Timestamp-events[i].Timestamp <= within
+					held := step.Action != StepHold || following < release[i]
+					if inTime && held {
+						current[i] = true
+						next[k][i] = following
+					}
+				}
+			}
+			if k < len(steps)-1 && good[i] {
+				following = i
+			}
+		}
+		good = current
+	}
+
+	var matches []Match
+	for i := range events {
+		if !good[i] {
+			continue
+		}
+		match := Match{Time: events[i].Timestamp, Line: events[i].Line}
+		for k, j := 0, i; j >= 0; k++ {
+			match.Events = append(match.Events, events[j])
+			j = next[k][j]
+		}
+		matches = append(matches, match)
+	}
+	return matches
+}
+
+// GrepResult - вхождения шаблона в одном файле для вывода в JSON
+type GrepResult struct {
+	File    string  `json:"file"`
+	Count   int     `json:"count"`
+	Matches []Match `json:"matches,omitempty"`
+}
+
+// MarshalJSON записывает вхождение с описанием и событиями в JSON-представлении
+func (m Match) MarshalJSON() ([]byte, error) {
+	events := make([]EventJSON, len(m.Events))
+	for i, event := range m.Events {
+		events[i] = NewEventJSON(event)
+	}
+	return json.Marshal(struct {
+		Time        float64     `json:"time"`
+		Line        int         `json:"line"`
+		Description string      `json:"description"`
+		Events      []EventJSON `json:"events"`
+	}{m.Time, m.Line, m.Describe(), events})
+}
+
+// WriteGrepJSON записывает результаты поиска в формате JSON
+func WriteGrepJSON(w io.Writer, results []GrepResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
+
+// Describe возвращает описание событий вхождения: "BTN_TL 1 -> BTN_SOUTH 1"
+func (m Match) Describe() string {
+	var parts []string
+	for _, event := range m.Events {
+		typ, code := event.TypeNum(), event.CodeNum()
+		parts = append(parts, fmt.Sprintf("%s %d", CodeLabel(typ, code), event.IntValue()))
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// ExpandPaths раскрывает список путей в список файлов. Каталоги
+// обходятся рекурсивно только при recursive, иначе возвращается ошибка; при
+// обходе пропускаются скрытые файлы и каталоги, заголовки CSV, заметки,
+// индексы evemu-split и всё, что не похоже на запись (isRecordingFile).
+// Архив записей раскрывается в ссылки "bundle.zip#имя" на все его записи.
+func ExpandPaths(paths []string, recursive bool) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		if IsStdio(path) {
+			files = append(files, path)
+			continue
+		}
+		if _, _, ok := SplitBundlePath(path); ok {
+			files = append(files, path)
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка открытия файла: %v", err)
+		}
+		if !info.IsDir() {
+			if clips := bundleClipPaths(path); clips != nil {
+				files = append(files, clips...)
+			} else {
+				files = append(files, path)
+			}
+			continue
+		}
+		if !recursive {
+			return nil, fmt.Errorf("%s - каталог (используйте --recursive)", path)
+		}
+
+		var found []string
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if file != path && strings.HasPrefix(entry.Name(), ".") {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !entry.Type().IsRegular() || !isRecordingFile(file) {
+				return nil
+			}
+			if clips := bundleClipPaths(file); clips != nil {
+				found = append(found, clips...)
+			} else {
+				found = append(found, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("ошибка обхода каталога: %v", err)
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+	return files, nil
+}
+
+// Суффиксы файлов рядом с записями, которые не являются записями
+var nonRecordingSuffixes = []string{CSVSidecarSuffix, BundleNoteSuffix, SplitIndexSuffix}
+
+// isRecordingFile проверяет, что файл, найденный при обходе каталога, -
+// запись: суффикс не относится к служебным файлам, а начало данных (после
+// распаковки gzip) распознаётся IsRecording
+func isRecordingFile(path string) bool {
+	name := strings.ToLower(strings.TrimSuffix(path, GzipSuffix))
+	for _, suffix := range nonRecordingSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return false
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	reader, err := decompressStream(bufio.NewReaderSize(file, recordingPeekSize))
+	if err != nil {
+		return false
+	}
+	prefix, _ := reader.Peek(recordingPeekSize)
+	return IsRecording(prefix)
+}
@@ -0,0 +1,196 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newGrepTestFile разбирает запись с тремя попытками комбинации LB + A
+func newGrepTestFile(t *testing.T) *EvemuFile {
+	t.Helper()
+	text := strings.Join(testDeviceHeader, "") +
+		// LB удерживается, A через 100 мс - совпадение
+		"E: 1.000000 0001 0136 0001\n" +
+		"E: 1.000000 0000 0000 0000\n" +
+		"E: 1.100000 0001 0130 0001\n" +
+		"E: 1.100000 0000 0000 0000\n" +
+		"E: 1.200000 0001 0130 0000\n" +
+		"E: 1.200000 0001 0136 0000\n" +
+		"E: 1.200000 0000 0000 0000\n" +
+		// LB отпущена до нажатия A
+		"E: 2.000000 0001 0136 0001\n" +
+		"E: 2.000000 0000 0000 0000\n" +
+		"E: 2.050000 0001 0136 0000\n" +
+		"E: 2.050000 0000 0000 0000\n" +
+		"E: 2.100000 0001 0130 0001\n" +
+		"E: 2.100000 0000 0000 0000\n" +
+		"E: 2.150000 0001 0130 0000\n" +
+		"E: 2.150000 0000 0000 0000\n" +
+		// A нажата слишком поздно
+		"E: 3.000000 0001 0136 0001\n" +
+		"E: 3.000000 0003 0011 -001\n" +
+		"E: 3.000000 0000 0000 0000\n" +
+		"E: 3.500000 0001 0130 0001\n" +
+		"E: 3.500000 0000 0000 0000\n" +
+		"E: 3.600000 0001 0130 0000\n" +
+		"E: 3.600000 0001 0136 0000\n" +
+		"E: 3.600000 0000 0000 0000\n"
+	f, err := ParseEvemu(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	return f
+}
+
+// TestParsePattern тестирует разбор шаблонов
+func TestParsePattern(t *testing.T) {
+	pattern, err := ParsePattern("hold LB for 50ms then press A within 200ms then ABS_HAT0Y <= -1")
+	if err != nil {
+		t.Fatalf("ParsePattern failed: %v", err)
+	}
+	expected := []PatternStep{
+		{Action: StepHold, Type: EvKey, Code: 0x136, For: 0.05},
+		{Action: StepPress, Type: EvKey, Code: 0x130, Within: 0.2},
+		{Action: StepAxis, Type: EvAbs, Code: 0x11, Op: "<=", Value: -1},
+	}
+	if len(pattern.Steps) != len(expected) {
+		t.Fatalf("Expected %d steps, got %+v", len(expected), pattern.Steps)
+	}
+	for i, step := range pattern.Steps {
+		if step != expected[i] {
+			t.Errorf("Step %d: expected %+v, got %+v", i, expected[i], step)
+		}
+	}
+
+	for _, text := range []string{
+		"", "press", "press NOPE", "press A within 1s", "press A then", "release A for 1s",
+		"ABS_X ~ 5", "ABS_X > big", "jump A",
+	} {
+		if _, err := ParsePattern(text); err == nil {
+			t.Errorf("Expected error for %q", text)
+		}
+	}
+}
+
+// TestGrep тестирует поиск вхождений с ограничениями по времени и удержанию
+func TestGrep(t *testing.T) {
+	f := newGrepTestFile(t)
+	first := len(testDeviceHeader) + 1
+
+	tests := []struct {
+		pattern string
+		lines   []int
+	}{
+		{"hold LB then press A within 200ms", []int{first}},
+		{"press LB then press A within 200ms", []int{first, first + 7}},
+		{"hold LB then press A", []int{first, first + 15}},
+		{"hold LB for 500ms", []int{first + 15}},
+		{"ABS_HAT0Y = -1 then release A", []int{first + 16}},
+		{"press B", nil},
+	}
+	for _, test := range tests {
+		pattern, err := ParsePattern(test.pattern)
+		if err != nil {
+			t.Fatalf("ParsePattern(%q) failed: %v", test.pattern, err)
+		}
+		matches := f.Grep(pattern)
+		if len(matches) != len(test.lines) {
+			t.Errorf("%q: expected %d matches, got %+v", test.pattern, len(test.lines), matches)
+			continue
+		}
+		for i, match := range matches {
+			if match.Line != test.lines[i] {
+				t.Errorf("%q: match %d at line %d, expected %d", test.pattern, i, match.Line, test.lines[i])
+			}
+		}
+	}
+
+	pattern, _ := ParsePattern("hold LB then press A within 200ms")
+	match := f.Grep(pattern)[0]
+	if match.Time != 1.0 || match.Describe() != "BTN_TL 1 -> BTN_SOUTH 1" {
+		t.Errorf("Unexpected match: %+v, %s", match, match.Describe())
+	}
+}
+
+// TestWriteGrepJSON тестирует вывод вхождений в JSON
+func TestWriteGrepJSON(t *testing.T) {
+	f := newGrepTestFile(t)
+	pattern, _ := ParsePattern("hold LB then press A within 200ms")
+	matches := f.Grep(pattern)
+
+	var buf bytes.Buffer
+	if err := WriteGrepJSON(&buf, []GrepResult{{File: "clip.txt", Count: len(matches), Matches: matches}}); err != nil {
+		t.Fatalf("WriteGrepJSON failed: %v", err)
+	}
+	var decoded []struct {
+		File    string
+		Count   int
+		Matches []struct {
+			Time        float64
+			Line        int
+			Description string
+			Events      []EventJSON
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(decoded) != 1 || decoded[0].Count != 1 || decoded[0].Matches[0].Description != "BTN_TL 1 -> BTN_SOUTH 1" ||
+		decoded[0].Matches[0].Events[1].CodeName != "BTN_SOUTH" || decoded[0].Matches[0].Line != len(testDeviceHeader)+1 {
+		t.Errorf("Unexpected JSON: %s", buf.String())
+	}
+}
+
+// TestExpandPaths тестирует раскрытие каталогов: при обходе остаются
+// только записи
+func TestExpandPaths(t *testing.T) {
+	dir := t.TempDir()
+	recording := []byte(strings.Join(testDeviceHeader, "") + "E: 0.000000 0001 0130 0001\nE: 0.000000 0000 0000 0000\n")
+	var compressed bytes.Buffer
+	writeCompressed(&compressed, func(w io.Writer) error {
+		_, err := w.Write(recording)
+		return err
+	})
+	for name, data := range map[string][]byte{
+		"b.txt":              recording,
+		"a.txt":              recording,
+		"sub/c.txt.gz":       compressed.Bytes(),
+		"a.txt.header":       recording,
+		"README.md":          []byte("# Комбо\n\nЗаписи ударов.\n"),
+		"notes.txt":          []byte("нажимать быстрее\nE - рывок\n"),
+		"clips-index.tsv":    []byte("file\tstart\tend\n"),
+		"cover.png":          {0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0, 0, 0, 0x0d},
+		"empty.txt":          nil,
+		".hidden.txt":        recording,
+		".git/objects/x.txt": recording,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := ExpandPaths([]string{dir, "-"}, true)
+	if err != nil {
+		t.Fatalf("ExpandPaths failed: %v", err)
+	}
+	expected := []string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt"), filepath.Join(dir, "sub", "c.txt.gz"), "-"}
+	if strings.Join(files, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, files)
+	}
+
+	if _, err := ExpandPaths([]string{dir}, false); err == nil {
+		t.Error("Expected error for directory without recursive")
+	}
+	if _, err := ExpandPaths([]string{filepath.Join(dir, "missing")}, false); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GzipSuffix - суффикс пути, при котором вывод сжимается gzip
+const GzipSuffix = ".gz"
+
+// gzipMagic - первые байты потока gzip
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// IsGzip проверяет, что данные сжаты gzip
+func IsGzip(data []byte) bool {
+	return bytes.HasPrefix(data, gzipMagic)
+}
+
+// HasGzipSuffix проверяет, что путь оканчивается на .gz
+func HasGzipSuffix(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), GzipSuffix)
+}
+
+// Decompress распаковывает данные, сжатые gzip (в том числе из нескольких
+// склеенных потоков); остальные данные возвращаются без изменений
+func Decompress(data []byte) ([]byte, error) {
+	if !IsGzip(data) {
+		return data, nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка распаковки gzip: %v", err)
+	}
+	defer reader.Close()
+	result, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка распаковки gzip: %v", err)
+	}
+	return result, nil
+}
+
+// decompressStream возвращает поток с распакованными данными, если поток
+// сжат gzip (в том числе из нескольких склеенных потоков), иначе - сам поток
+func decompressStream(r *bufio.Reader) (*bufio.Reader, error) {
+	magic, _ := r.Peek(len(gzipMagic))
+	if !IsGzip(magic) {
+		return r, nil
+	}
+	reader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка распаковки gzip: %v", err)
+	}
+	return bufio.NewReaderSize(reader, recordingPeekSize), nil
+}
+
+// writeCompressed вызывает write с приёмником, сжимающим данные gzip в w
+func writeCompressed(w io.Writer, write func(w io.Writer) error) error {
+	writer := gzip.NewWriter(w)
+	if err := write(writer); err != nil {
+		return err
+	}
+	return writer.Close()
+}
@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gzipData сжимает данные одним или несколькими потоками gzip
+func gzipData(parts ...string) []byte {
+	var buf bytes.Buffer
+	for _, part := range parts {
+		writer := gzip.NewWriter(&buf)
+		writer.Write([]byte(part))
+		writer.Close()
+	}
+	return buf.Bytes()
+}
+
+// TestDecompress тестирует распаковку по сигнатуре gzip
+func TestDecompress(t *testing.T) {
+	plain := []byte(csvTestText)
+	if data, err := Decompress(plain); err != nil || !bytes.Equal(data, plain) {
+		t.Errorf("Expected plain data unchanged, got %v", err)
+	}
+
+	half := len(csvTestText) / 2
+	data, err := Decompress(gzipData(csvTestText[:half], csvTestText[half:]))
+	if err != nil || string(data) != csvTestText {
+		t.Errorf("Unexpected multistream result: %q, %v", data, err)
+	}
+
+	if _, err := Decompress(gzipData(csvTestText)[:20]); err == nil {
+		t.Error("Expected error for truncated gzip")
+	}
+}
+
+// TestReadInputGzip тестирует чтение сжатых записей в любом формате
+func TestReadInputGzip(t *testing.T) {
+	dir := t.TempDir()
+	f, err := ParseEvemu(strings.NewReader(csvTestText))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	for _, format := range []string{FormatEvemu, FormatCSV, FormatRaw} {
+		var text bytes.Buffer
+		f.WriteFormat(&text, format)
+		// Имя без .gz: формат определяется по содержимому
+		path := filepath.Join(dir, "recording."+format)
+		if err := os.WriteFile(path, gzipData(text.String()), 0644); err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := ReadInput(path)
+		if err != nil || len(parsed.Events) != len(f.Events) {
+			t.Errorf("%s: unexpected result %+v, %v", format, parsed, err)
+		}
+	}
+}
+
+// TestWriteReportCompressed тестирует сжатие вывода по суффиксу и флагу
+func TestWriteReportCompressed(t *testing.T) {
+	dir := t.TempDir()
+	f, err := ParseEvemu(strings.NewReader(csvTestText))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name       string
+		compress   bool
+		compressed bool
+	}{
+		{"out.txt", false, false},
+		{"out.txt.gz", false, true},
+		{"out.TXT.GZ", false, true},
+		{"flag.txt", true, true},
+	} {
+		path := filepath.Join(dir, tt.name)
+		if err := f.WriteOutputFormat(path, FormatEvemu, tt.compress); err != nil {
+			t.Fatalf("%s: WriteOutputFormat failed: %v", tt.name, err)
+		}
+		data, _ := os.ReadFile(path)
+		if IsGzip(data) != tt.compressed {
+			t.Errorf("%s: expected compressed=%v", tt.name, tt.compressed)
+		}
+		parsed, err := ReadInput(path)
+		if err != nil {
+			t.Fatalf("%s: ReadInput failed: %v", tt.name, err)
+		}
+		var restored bytes.Buffer
+		parsed.Write(&restored)
+		if restored.String() != csvTestText {
+			t.Errorf("%s: round-trip mismatch:\n%s", tt.name, restored.String())
+		}
+	}
+}
+
+// TestWriteOutputCompressed тестирует сжатие записи evemu по суффиксу .gz и
+// флагу compress, а также чтение CSV со сжатым заголовком рядом
+func TestWriteOutputCompressed(t *testing.T) {
+	f, err := ParseEvemu(strings.NewReader(csvTestText))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	dir := t.TempDir()
+
+	for _, tc := range []struct {
+		path     string
+		compress bool
+		write    func(path string) error
+	}{
+		{filepath.Join(dir, "a.txt.gz"), false, f.WriteToFile},
+		{filepath.Join(dir, "b.txt.gz"), false, func(path string) error { return f.WriteOutput(path, false) }},
+		{filepath.Join(dir, "c.txt"), true, func(path string) error { return f.WriteOutput(path, true) }},
+	} {
+		if err := tc.write(tc.path); err != nil {
+			t.Fatalf("%s: write failed: %v", tc.path, err)
+		}
+		data, _ := os.ReadFile(tc.path)
+		if !IsGzip(data) {
+			t.Errorf("%s: expected gzip output", tc.path)
+		}
+		if result, err := ReadInput(tc.path); err != nil || len(result.Events) != len(f.Events) {
+			t.Errorf("%s: unexpected read back: %v", tc.path, err)
+		}
+	}
+
+	// Таблица без преамбулы и сжатый заголовок рядом
+	table := filepath.Join(dir, "table.csv.gz")
+	if err := WriteReport(table, false, func(w io.Writer) error { return f.WriteCSV(w, false) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&EvemuFile{Header: f.Header}).WriteOutput(CSVSidecarPath(table), true); err != nil {
+		t.Fatal(err)
+	}
+	result, err := ReadInput(table)
+	if err != nil || len(result.Header) != len(f.Header) {
+		t.Errorf("Expected header from compressed sidecar, got %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package parser
+
+import (
+	"fmt"
+)
+
+// HoldMode задаёт способ изменения длительности удержания
+type HoldMode int
+
+const (
+	HoldAdd   HoldMode = iota // прибавить Amount секунд (может быть отрицательным)
+	HoldSet                   // установить удержание ровно в Amount секунд
+	HoldScale                 // изменить удержание на Amount процентов от исходного
+)
+
+// HoldOptions задаёт параметры изменения удержания кнопок
+type HoldOptions struct {
+	Codes  []int    // коды кнопок, удержание которых изменяется
+	Mode   HoldMode // способ изменения
+	Amount float64  // секунды для HoldAdd/HoldSet, проценты для HoldScale
+	Shift  bool     // сдвигать последующие события вместо сохранения глобальных таймингов
+}
+
+// holdPair описывает нажатие и отпускание одной кнопки
+type holdPair struct {
+	code    int
+	press   int // индекс события нажатия
+	release int // индекс события отпускания
+}
+
+// AdjustHolds изменяет длительность удержания выбранных кнопок.
+// Без Shift переносится только событие отпускания, остальные события
+// остаются на своих местах; с Shift все события начиная с кадра
+// отпускания сдвигаются на разницу длительностей.
+func (f *EvemuFile) AdjustHolds(opts HoldOptions) (*EvemuFile, error) {
+	selected := make(map[int]bool)
+	for _, code := range opts.Codes {
+		selected[code] = true
+	}
+
+	pairs, err := findHoldPairs(f.Events, selected)
+	if err != nil {
+		return nil, err
+	}
+
+	deltas := make([]float64, len(pairs))
+	for i, pair := range pairs {
+		hold := f.Events[pair.release].Timestamp - f.Events[pair.press].Timestamp
+		newHold := opts.newHold(hold)
+		if newHold <= 0 {
+			return nil, fmt.Errorf("удержание %s в %.6f становится неположительным (%.6f с)",
+				CodeLabel(EvKey, pair.code), f.Events[pair.press].Timestamp, newHold)
+		}
+		deltas[i] = newHold - hold
+	}
+
+	var events []Event
+	comments := f.Comments
+	if opts.Shift {
+		events, err = shiftHolds(f.Events, pairs, deltas)
+		comments = mapComments(f.Comments, func(t float64) float64 {
+			offset := 0.0
+			for i, pair := range pairs {
+				if t >= f.Events[frameStart(f.Events, pair.release)].Timestamp {
+					offset += deltas[i]
+				}
+			}
+			return t + offset
+		})
+	} else {
+		events = moveReleases(f.Events, pairs, deltas)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkHoldOrder(events, selected); err != nil {
+		return nil, err
+	}
+
+	return &EvemuFile{Header: f.Header, Events: events, Comments: comments}, nil
+}
+
+// newHold вычисляет новую длительность удержания
+func (opts HoldOptions) newHold(hold float64) float64 {
+	switch opts.Mode {
+	case HoldSet:
+		return opts.Amount
+	case HoldScale:
+		return hold * opts.Amount / 100
+	default:
+		return hold + opts.Amount
+	}
+}
+
+// findHoldPairs находит пары нажатие/отпускание для выбранных кнопок.
+// Повторы автоповтора (значение 2) не считаются новым нажатием.
+func findHoldPairs(events []Event, selected map[int]bool) ([]holdPair, error) {
+	pressed := make(map[int]int)
+	var pairs []holdPair
+	for i, event := range events {
+		if event.TypeNum() != EvKey || !selected[event.CodeNum()] {
+			continue
+		}
+		code := event.CodeNum()
+		switch event.IntValue() {
+		case 1:
+			if _, ok := pressed[code]; !ok {
+				pressed[code] = i
+			}
+		case 0:
+			if press, ok := pressed[code]; ok {
+				pairs = append(pairs, holdPair{code: code, press: press, release: i})
+				delete(pressed, code)
+			}
+		}
+	}
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("не найдено ни одного нажатия выбранных кнопок")
+	}
+	return pairs, nil
+}
+
+// shiftHolds сдвигает все события начиная с кадра отпускания на разницу удержания
+func shiftHolds(events []Event, pairs []holdPair, deltas []float64) ([]Event, error) {
+	offsets := make([]float64, len(events)+1)
+	for i, pair := range pairs {
+		offsets[frameStart(events, pair.release)] += deltas[i]
+	}
+
+	result := make([]Event, len(events))
+	offset := 0.0
+	for i, event := range events {
+		offset += offsets[i]
+		event.Timestamp += offset
+		if i > 0 && event.Timestamp < result[i-1].Timestamp {
+			return nil, fmt.Errorf("сокращение удержания нарушает порядок событий в %.6f", events[i].Timestamp)
+		}
+		result[i] = event
+	}
+	return result, nil
+}
+
+// moveReleases переносит события отпускания в отдельные кадры на новое время
+func moveReleases(events []Event, pairs []holdPair, deltas []float64) []Event {
+	moved := make(map[int]float64)
+	var releases []Frame
+	for i, pair := range pairs {
+		newTime := events[pair.release].Timestamp + deltas[i]
+		moved[pair.release] = newTime
+		release := events[pair.release]
+		release.Timestamp = newTime
+		releases = append(releases, newFrame(newTime, release))
+	}
+
+	frames := removeEvents(events, func(i int, _ Event) bool {
+		_, ok := moved[i]
+		return ok
+	})
+	return mergeFrames(frames, releases)
+}
+
+// checkHoldOrder проверяет, что удержания не перекрывают повторные нажатия
+func checkHoldOrder(events []Event, selected map[int]bool) error {
+	pressed := make(map[int]bool)
+	for _, event := range events {
+		if event.TypeNum() != EvKey || !selected[event.CodeNum()] {
+			continue
+		}
+		code := event.CodeNum()
+		switch event.IntValue() {
+		case 1:
+			if pressed[code] {
+				return fmt.Errorf("удержание %s перекрывает повторное нажатие в %.6f",
+					CodeLabel(EvKey, code), event.Timestamp)
+			}
+			pressed[code] = true
+		case 0:
+			pressed[code] = false
+		}
+	}
+	return nil
+}
+
+// frameStart возвращает индекс первого события кадра, содержащего событие index
+func frameStart(events []Event, index int) int {
+	for index > 0 && !events[index-1].IsSynReport() {
+		index--
+	}
+	return index
+}
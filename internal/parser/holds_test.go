@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"testing"
+)
+
+// newHoldTestFile создаёт запись с двумя нажатиями A и движением стика
+func newHoldTestFile() *EvemuFile {
+	return &EvemuFile{
+		Header: []string{"# Test header\n"},
+		Events: []Event{
+			NewEvent(0.0, EvKey, 0x130, 1),
+			NewEvent(0.0, EvSyn, SynReport, 0),
+			NewEvent(0.1, EvKey, 0x130, 0),
+			NewEvent(0.1, EvAbs, 0x00, 500),
+			NewEvent(0.1, EvSyn, SynReport, 0),
+			NewEvent(0.3, EvAbs, 0x00, 0),
+			NewEvent(0.3, EvSyn, SynReport, 0),
+			NewEvent(0.5, EvKey, 0x130, 1),
+			NewEvent(0.5, EvSyn, SynReport, 0),
+			NewEvent(0.6, EvKey, 0x130, 0),
+			NewEvent(0.6, EvSyn, SynReport, 0),
+		},
+	}
+}
+
+// findKeyEvent возвращает время n-го события кнопки с заданным значением
+func findKeyEvent(events []Event, code, value, n int) float64 {
+	for _, event := range events {
+		if event.TypeNum() == EvKey && event.CodeNum() == code && event.IntValue() == value {
+			if n == 0 {
+				return event.Timestamp
+			}
+			n--
+		}
+	}
+	return -1
+}
+
+// TestAdjustHoldsFixed тестирует изменение удержаний с сохранением глобальных таймингов
+func TestAdjustHoldsFixed(t *testing.T) {
+	file := newHoldTestFile()
+	result, err := file.AdjustHolds(HoldOptions{Codes: []int{0x130}, Mode: HoldSet, Amount: 0.2})
+	if err != nil {
+		t.Fatalf("AdjustHolds() failed: %v", err)
+	}
+
+	if got := findKeyEvent(result.Events, 0x130, 0, 0); got != 0.2 {
+		t.Errorf("Expected first release at 0.2, got %f", got)
+	}
+	if got := findKeyEvent(result.Events, 0x130, 0, 1); got < 0.6999 || got > 0.7001 {
+		t.Errorf("Expected second release at 0.7, got %f", got)
+	}
+
+	// Остальные события остаются на месте, порядок времени не нарушен
+	if result.Events[len(result.Events)-1].Timestamp < 0.6999 {
+		t.Error("Last event should be the moved release")
+	}
+	for i := 1; i < len(result.Events); i++ {
+		if result.Events[i].Timestamp < result.Events[i-1].Timestamp {
+			t.Fatalf("Events out of order at %d", i)
+		}
+	}
+	if len(result.Events) != len(file.Events)+1 {
+		t.Errorf("Expected extra SYN_REPORT for split frame, got %d events", len(result.Events))
+	}
+}
+
+// TestAdjustHoldsShift тестирует изменение удержаний со сдвигом последующих событий
+func TestAdjustHoldsShift(t *testing.T) {
+	file := newHoldTestFile()
+	result, err := file.AdjustHolds(HoldOptions{Codes: []int{0x130}, Mode: HoldScale, Amount: 200, Shift: true})
+	if err != nil {
+		t.Fatalf("AdjustHolds() failed: %v", err)
+	}
+
+	if len(result.Events) != len(file.Events) {
+		t.Fatalf("Expected %d events, got %d", len(file.Events), len(result.Events))
+	}
+
+	expected := []float64{0.0, 0.0, 0.2, 0.2, 0.2, 0.4, 0.4, 0.6, 0.6, 0.8, 0.8}
+	for i, event := range result.Events {
+		if event.Timestamp < expected[i]-1e-9 || event.Timestamp > expected[i]+1e-9 {
+			t.Errorf("Event %d: expected timestamp %.3f, got %.6f", i, expected[i], event.Timestamp)
+		}
+	}
+}
+
+// TestAdjustHoldsOverlap тестирует обнаружение перекрытия с повторным нажатием
+func TestAdjustHoldsOverlap(t *testing.T) {
+	file := newHoldTestFile()
+	_, err := file.AdjustHolds(HoldOptions{Codes: []int{0x130}, Mode: HoldAdd, Amount: 0.5})
+	if err == nil {
+		t.Error("Expected overlap error")
+	}
+
+	_, err = file.AdjustHolds(HoldOptions{Codes: []int{0x130}, Mode: HoldAdd, Amount: -0.2})
+	if err == nil {
+		t.Error("Expected error for non-positive hold")
+	}
+
+	_, err = file.AdjustHolds(HoldOptions{Codes: []int{0x131}, Mode: HoldAdd, Amount: 0.1})
+	if err == nil {
+		t.Error("Expected error when no presses found")
+	}
+}
@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IdleOptions задаёт параметры сокращения пауз
+type IdleOptions struct {
+	Threshold float64 // паузы длиннее порога считаются простоем, секунды
+	MaxGap    float64 // длительность, до которой сокращается простой, секунды
+
+	// StartMarker и EndMarker, если заданы, ограничивают сжатие участками
+	// между маркерами "# marker: <имя>"
+	StartMarker string
+	EndMarker   string
+}
+
+// MarkerPrefix - префикс комментария, отмечающего именованную точку записи
+const MarkerPrefix = "# marker:"
+
+// Marker возвращает имя маркера, если комментарий является маркером
+func (c Comment) Marker() (string, bool) {
+	if !strings.HasPrefix(c.Text, MarkerPrefix) {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(c.Text, MarkerPrefix)), true
+}
+
+// idleGap описывает сокращаемую паузу между событиями
+type idleGap struct {
+	start float64 // время последнего события перед паузой
+	end   float64 // время первого события после паузы
+}
+
+// CompressIdle сокращает паузы без значимых (не SYN) событий длиннее
+// Threshold до MaxGap. Тайминги внутри активных участков не меняются.
+func (f *EvemuFile) CompressIdle(opts IdleOptions) (*EvemuFile, error) {
+	if opts.Threshold <= 0 || opts.MaxGap < 0 {
+		return nil, fmt.Errorf("порог простоя должен быть положительным")
+	}
+	if opts.MaxGap > opts.Threshold {
+		return nil, fmt.Errorf("максимальная пауза (%.3f) больше порога простоя (%.3f)", opts.MaxGap, opts.Threshold)
+	}
+
+	regions := f.markerRegions(opts.StartMarker, opts.EndMarker)
+
+	var gaps []idleGap
+	lastActive, seen := 0.0, false
+	for _, event := range f.Events {
+		if event.TypeNum() == EvSyn {
+			continue
+		}
+		if seen && event.Timestamp-lastActive > opts.Threshold && inRegions(regions, lastActive, event.Timestamp) {
+			gaps = append(gaps, idleGap{start: lastActive, end: event.Timestamp})
+		}
+		lastActive, seen = event.Timestamp, true
+	}
+
+	mapTime := func(t float64) float64 {
+		shift := 0.0
+		for _, gap := range gaps {
+			if t <= gap.start {
+				break
+			}
+			removed := gap.end - gap.start - opts.MaxGap
+			if t >= gap.end {
+				shift += removed
+				continue
+			}
+			// Время внутри паузы (например, одиночные SYN_REPORT) прижимается к её новому концу
+			if t-gap.start > opts.MaxGap {
+				shift += t - gap.start - opts.MaxGap
+			}
+			break
+		}
+		return t - shift
+	}
+
+	result := &EvemuFile{Header: f.Header, Comments: mapComments(f.Comments, mapTime)}
+	result.Events = make([]Event, len(f.Events))
+	for i, event := range f.Events {
+		event.Timestamp = mapTime(event.Timestamp)
+		result.Events[i] = event
+	}
+	return result, nil
+}
+
+// markerRegions возвращает участки между маркерами start и end.
+// Без заданных маркеров возвращает nil, что означает всю запись.
+func (f *EvemuFile) markerRegions(start, end string) []idleGap {
+	if start == "" && end == "" {
+		return nil
+	}
+
+	var regions []idleGap
+	open, inside := 0.0, start == ""
+	for _, comment := range f.Comments {
+		name, ok := comment.Marker()
+		if !ok {
+			continue
+		}
+		if name == start && !inside {
+			open, inside = comment.Timestamp, true
+		} else if name == end && inside {
+			regions = append(regions, idleGap{start: open, end: comment.Timestamp})
+			inside = false
+		}
+	}
+	if inside && len(f.Events) > 0 {
+		regions = append(regions, idleGap{start: open, end: f.Events[len(f.Events)-1].Timestamp})
+	}
+	if regions == nil {
+		// Маркеры заданы, но не найдены - сжимать нечего
+		return []idleGap{}
+	}
+	return regions
+}
+
+// inRegions проверяет, что пауза [from, to] целиком лежит внутри одного из участков
+func inRegions(regions []idleGap, from, to float64) bool {
+	if regions == nil {
+		return true
+	}
+	for _, region := range regions {
+		if region.start <= from && to <= region.end {
+			return true
+		}
+	}
+	return false
+}
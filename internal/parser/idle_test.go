@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"testing"
+)
+
+// newIdleTestFile создаёт запись с двумя длинными паузами и маркерами
+func newIdleTestFile() *EvemuFile {
+	return &EvemuFile{
+		Header: []string{"# Test header\n"},
+		Events: []Event{
+			NewEvent(0.0, EvKey, 0x130, 1),
+			NewEvent(0.0, EvSyn, SynReport, 0),
+			NewEvent(0.1, EvKey, 0x130, 0),
+			NewEvent(0.1, EvSyn, SynReport, 0),
+			NewEvent(3.0, EvSyn, SynReport, 0),
+			NewEvent(5.1, EvKey, 0x131, 1),
+			NewEvent(5.1, EvSyn, SynReport, 0),
+			NewEvent(5.2, EvKey, 0x131, 0),
+			NewEvent(5.2, EvSyn, SynReport, 0),
+			NewEvent(10.2, EvKey, 0x130, 1),
+			NewEvent(10.2, EvSyn, SynReport, 0),
+		},
+		Comments: []Comment{
+			{Timestamp: 0.1, Text: "# marker: menu"},
+			{Timestamp: 5.2, Text: "# marker: fight"},
+		},
+	}
+}
+
+// TestCompressIdle тестирует сокращение всех длинных пауз
+func TestCompressIdle(t *testing.T) {
+	file := newIdleTestFile()
+	result, err := file.CompressIdle(IdleOptions{Threshold: 2, MaxGap: 0.5})
+	if err != nil {
+		t.Fatalf("CompressIdle() failed: %v", err)
+	}
+
+	expected := []float64{0.0, 0.0, 0.1, 0.1, 0.6, 0.6, 0.6, 0.7, 0.7, 1.2, 1.2}
+	for i, event := range result.Events {
+		if event.Timestamp < expected[i]-1e-9 || event.Timestamp > expected[i]+1e-9 {
+			t.Errorf("Event %d: expected timestamp %.3f, got %.6f", i, expected[i], event.Timestamp)
+		}
+	}
+
+	if c := result.Comments[1].Timestamp; c < 0.6999 || c > 0.7001 {
+		t.Errorf("Comment should follow its event, got timestamp %f", c)
+	}
+
+	// Исходная запись не изменяется
+	if file.Events[5].Timestamp != 5.1 {
+		t.Error("CompressIdle() modified the source file")
+	}
+}
+
+// TestCompressIdleBetweenMarkers тестирует сжатие только между маркерами
+func TestCompressIdleBetweenMarkers(t *testing.T) {
+	file := newIdleTestFile()
+	result, err := file.CompressIdle(IdleOptions{Threshold: 2, MaxGap: 0.5, StartMarker: "menu", EndMarker: "fight"})
+	if err != nil {
+		t.Fatalf("CompressIdle() failed: %v", err)
+	}
+
+	// Пауза меню сокращена, пауза после маркера fight сохранена
+	if got := findKeyEvent(result.Events, 0x131, 1, 0); got < 0.5999 || got > 0.6001 {
+		t.Errorf("Expected menu pause compressed to 0.6, got %f", got)
+	}
+	last := result.Events[len(result.Events)-1].Timestamp
+	if last < 5.6999 || last > 5.7001 {
+		t.Errorf("Expected fight pause preserved, last event at %f", last)
+	}
+
+	result, err = file.CompressIdle(IdleOptions{Threshold: 2, MaxGap: 0.5, StartMarker: "missing", EndMarker: "fight"})
+	if err != nil {
+		t.Fatalf("CompressIdle() failed: %v", err)
+	}
+	if result.Events[len(result.Events)-1].Timestamp != 10.2 {
+		t.Error("Nothing should be compressed without matching markers")
+	}
+}
+
+// TestCompressIdleInvalidOptions тестирует проверку параметров
+func TestCompressIdleInvalidOptions(t *testing.T) {
+	file := newIdleTestFile()
+	if _, err := file.CompressIdle(IdleOptions{Threshold: 0, MaxGap: 0}); err == nil {
+		t.Error("Expected error for zero threshold")
+	}
+	if _, err := file.CompressIdle(IdleOptions{Threshold: 1, MaxGap: 2}); err == nil {
+		t.Error("Expected error for max gap above threshold")
+	}
+}
+
+// TestCommentMarker тестирует распознавание маркеров
+func TestCommentMarker(t *testing.T) {
+	if name, ok := (Comment{Text: "# marker:  jump "}).Marker(); !ok || name != "jump" {
+		t.Errorf("Marker() = (%q, %v)", name, ok)
+	}
+	if _, ok := (Comment{Text: "# just a note"}).Marker(); ok {
+		t.Error("Plain comment should not be a marker")
+	}
+}
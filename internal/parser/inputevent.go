@@ -0,0 +1,238 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// Размеры struct input_event: timeval из двух 64- или 32-битных полей,
+// затем type и code (u16) и value (s32)
+const (
+	InputEventSize64 = 24
+	InputEventSize32 = 16
+)
+
+// Пределы type и code, при которых запись считается правдоподобной
+// (EV_MAX и KEY_MAX ядра)
+const (
+	inputEventMaxType = 0x1f
+	inputEventMaxCode = 0x2ff
+)
+
+// InputEventLayout - раскладка struct input_event в дампе устройства
+type InputEventLayout struct {
+	Bits  int // разрядность полей timeval: 64 или 32
+	Order binary.ByteOrder
+}
+
+// Раскладки в порядке предпочтения при неоднозначном определении
+var InputEventLayouts = []InputEventLayout{
+	{Bits: 64, Order: binary.LittleEndian},
+	{Bits: 32, Order: binary.LittleEndian},
+	{Bits: 64, Order: binary.BigEndian},
+	{Bits: 32, Order: binary.BigEndian},
+}
+
+// Size возвращает размер записи
+func (l InputEventLayout) Size() int {
+	if l.Bits == 32 {
+		return InputEventSize32
+	}
+	return InputEventSize64
+}
+
+// String возвращает имя раскладки: 64le, 32le, 64be или 32be
+func (l InputEventLayout) String() string {
+	if l.Order == binary.BigEndian {
+		return fmt.Sprintf("%dbe", l.Bits)
+	}
+	return fmt.Sprintf("%dle", l.Bits)
+}
+
+// ParseInputEventLayout разбирает имя раскладки: 64le, 32le, 64be или 32be
+func ParseInputEventLayout(name string) (InputEventLayout, error) {
+	for _, layout := range InputEventLayouts {
+		if layout.String() == name {
+			return layout, nil
+		}
+	}
+	return InputEventLayout{}, fmt.Errorf("неизвестная раскладка input_event: %s (ожидается 64le, 32le, 64be или 32be)", name)
+}
+
+// RawFormatLayout возвращает раскладку для формата записи raw или
+// raw-<раскладка>; raw - 64le, как на x86_64 и arm64
+func RawFormatLayout(format string) (InputEventLayout, bool) {
+	if format == FormatRaw {
+		return InputEventLayouts[0], true
+	}
+	name, ok := strings.CutPrefix(format, FormatRaw+"-")
+	if !ok {
+		return InputEventLayout{}, false
+	}
+	layout, err := ParseInputEventLayout(name)
+	return layout, err == nil
+}
+
+// inputEventRecord - поля одной записи input_event
+type inputEventRecord struct {
+	sec, usec   int64
+	typ, code   int
+	value       int
+	synReport   bool
+	plausible   bool
+	timestampUs int64
+}
+
+// decode разбирает одну запись
+func (l InputEventLayout) decode(data []byte) inputEventRecord {
+	var r inputEventRecord
+	rest := data
+	if l.Bits == 32 {
+		r.sec = int64(int32(l.Order.Uint32(rest[0:4])))
+		r.usec = int64(int32(l.Order.Uint32(rest[4:8])))
+		rest = rest[8:]
+	} else {
+		r.sec = int64(l.Order.Uint64(rest[0:8]))
+		r.usec = int64(l.Order.Uint64(rest[8:16]))
+		rest = rest[16:]
+	}
+	r.typ = int(l.Order.Uint16(rest[0:2]))
+	r.code = int(l.Order.Uint16(rest[2:4]))
+	r.value = int(int32(l.Order.Uint32(rest[4:8])))
+	r.synReport = r.typ == EvSyn && r.code == SynReport
+	r.plausible = r.sec >= 0 && r.usec >= 0 && r.usec < 1e6 &&
+		r.typ <= inputEventMaxType && r.code <= inputEventMaxCode
+	r.timestampUs = r.sec*1e6 + r.usec
+	return r
+}
+
+// score оценивает, насколько данные похожи на дамп в этой раскладке:
+// -1, если размер не кратен записи, поля вне допустимых пределов или время
+// идёт назад, иначе - число SYN_REPORT
+func (l InputEventLayout) score(data []byte) int {
+	size := l.Size()
+	if len(data) == 0 || len(data)%size != 0 {
+		return -1
+	}
+	score := 0
+	previous := int64(math.MinInt64)
+	for offset := 0; offset < len(data); offset += size {
+		r := l.decode(data[offset : offset+size])
+		if !r.plausible || r.timestampUs < previous {
+			return -1
+		}
+		previous = r.timestampUs
+		if r.synReport {
+			score++
+		}
+	}
+	return score
+}
+
+// DetectInputEventLayout определяет раскладку дампа: подходят раскладки,
+// в которых все записи правдоподобны и время не убывает; из них выбирается
+// та, где больше SYN_REPORT, при равенстве - более распространённая
+func DetectInputEventLayout(data []byte) (InputEventLayout, error) {
+	best, bestScore := InputEventLayout{}, -1
+	for _, layout := range InputEventLayouts {
+		if score := layout.score(data); score > bestScore {
+			best, bestScore = layout, score
+		}
+	}
+	if bestScore < 0 {
+		return InputEventLayout{}, fmt.Errorf("данные (%d байт) не похожи на дамп struct input_event", len(data))
+	}
+	return best, nil
+}
+
+// ParseInputEvents импортирует дамп struct input_event (например,
+// "cat /dev/input/eventX > dump.bin") с автоматическим определением
+// раскладки
+func ParseInputEvents(r io.Reader) (*EvemuFile, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла: %v", err)
+	}
+	layout, err := DetectInputEventLayout(data)
+	if err != nil {
+		return nil, err
+	}
+	return ParseInputEventsLayout(data, layout)
+}
+
+// ParseInputEventsLayout импортирует дамп в заданной раскладке. Время
+// событий отсчитывается от первого события, в Event.Line записывается номер
+// записи. В дампе нет описания устройства, поэтому заголовок evemu строится
+// по встретившимся кодам, диапазоны осей - по значениям.
+func ParseInputEventsLayout(data []byte, layout InputEventLayout) (*EvemuFile, error) {
+	size := layout.Size()
+	if len(data)%size != 0 {
+		return nil, fmt.Errorf("размер дампа %d байт не кратен размеру записи %d (%s)", len(data), size, layout)
+	}
+
+	result := &EvemuFile{}
+	var start int64
+	for offset := 0; offset < len(data); offset += size {
+		r := layout.decode(data[offset : offset+size])
+		if !r.plausible {
+			return nil, fmt.Errorf("запись %d: некорректное событие: время %d.%06d, тип %d, код %d",
+				offset/size+1, r.sec, r.usec, r.typ, r.code)
+		}
+		if offset == 0 {
+			start = r.timestampUs
+		}
+		event := NewEvent(float64(r.timestampUs-start)/1e6, r.typ, r.code, r.value)
+		event.Line = offset/size + 1
+		result.Events = append(result.Events, event)
+	}
+
+	descriptor := ParseDescriptor(nil)
+	descriptor.declareEvents(result.Events)
+	result.Header = descriptor.HeaderLines()
+	return result, nil
+}
+
+// WriteInputEvents записывает события дампом struct input_event в заданной
+// раскладке. Время записывается от начала записи, заголовок и комментарии
+// не сохраняются.
+func (f *EvemuFile) WriteInputEvents(w io.Writer, layout InputEventLayout) error {
+	bw := bufio.NewWriter(w)
+	record := make([]byte, layout.Size())
+	for _, event := range f.Events {
+		micro := int64(math.Round(event.Timestamp * 1e6))
+		if micro < 0 {
+			return fmt.Errorf("строка %d: отрицательное время события %.6f", event.Line, event.Timestamp)
+		}
+		rest := record
+		if layout.Bits == 32 {
+			if micro/1e6 > math.MaxInt32 {
+				return fmt.Errorf("строка %d: время %.6f не помещается в 32-битный timeval", event.Line, event.Timestamp)
+			}
+			layout.Order.PutUint32(rest[0:4], uint32(micro/1e6))
+			layout.Order.PutUint32(rest[4:8], uint32(micro%1e6))
+			rest = rest[8:]
+		} else {
+			layout.Order.PutUint64(rest[0:8], uint64(micro/1e6))
+			layout.Order.PutUint64(rest[8:16], uint64(micro%1e6))
+			rest = rest[16:]
+		}
+		layout.Order.PutUint16(rest[0:2], uint16(event.TypeNum()))
+		layout.Order.PutUint16(rest[2:4], uint16(event.CodeNum()))
+		layout.Order.PutUint32(rest[4:8], uint32(int32(event.IntValue())))
+		if _, err := bw.Write(record); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// isBinary проверяет, что данные двоичные: в начале есть нулевые байты,
+// которых не бывает в текстовых форматах
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), 512)], 0) >= 0
+}
@@ -0,0 +1,159 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// newInputEventDump собирает дамп struct input_event с абсолютным временем,
+// как при чтении устройства
+func newInputEventDump(layout InputEventLayout, records [][5]int64) []byte {
+	var buf bytes.Buffer
+	for _, r := range records {
+		if layout.Bits == 32 {
+			binary.Write(&buf, layout.Order, []int32{int32(r[0]), int32(r[1])})
+		} else {
+			binary.Write(&buf, layout.Order, []int64{r[0], r[1]})
+		}
+		binary.Write(&buf, layout.Order, []uint16{uint16(r[2]), uint16(r[3])})
+		binary.Write(&buf, layout.Order, int32(r[4]))
+	}
+	return buf.Bytes()
+}
+
+// inputEventTestRecords - нажатие BTN_SOUTH и отклонение ABS_Y
+var inputEventTestRecords = [][5]int64{
+	{1700000000, 999000, 1, 0x130, 1},
+	{1700000000, 999000, 3, 0x01, -32768},
+	{1700000000, 999000, 0, 0, 0},
+	{1700000001, 15123, 1, 0x130, 0},
+	{1700000001, 15123, 0, 0, 0},
+}
+
+// TestParseInputEvents тестирует импорт дампов во всех раскладках
+func TestParseInputEvents(t *testing.T) {
+	for _, layout := range InputEventLayouts {
+		data := newInputEventDump(layout, inputEventTestRecords)
+		if format := DetectFormat(data); format != FormatRaw {
+			t.Errorf("%s: detected as %s", layout, format)
+		}
+		if detected, err := DetectInputEventLayout(data); err != nil || detected != layout {
+			t.Errorf("%s: detected layout %s, %v", layout, detected, err)
+		}
+
+		f, err := ParseData(data)
+		if err != nil {
+			t.Fatalf("%s: ParseData failed: %v", layout, err)
+		}
+		if len(f.Events) != 5 {
+			t.Fatalf("%s: expected 5 events, got %d", layout, len(f.Events))
+		}
+		if e := f.Events[1]; e.Timestamp != 0 || e.Type != "0003" || e.Code != "0001" || e.Value != "-32768" || e.Line != 2 {
+			t.Errorf("%s: unexpected event %+v", layout, e)
+		}
+		if e := f.Events[3]; e.Timestamp != 0.016123 || e.Value != "0000" {
+			t.Errorf("%s: unexpected event %+v", layout, e)
+		}
+
+		d := ParseDescriptor(f.Header)
+		if !d.HasCode(EvKey, 0x130) || !d.HasCode(EvAbs, 0x01) {
+			t.Errorf("%s: codes not declared: %+v", layout, d.Bits)
+		}
+		if info, ok := d.AbsRange(0x01); !ok || info.Min != -32768 || info.Max != -32768 {
+			t.Errorf("%s: unexpected ABS_Y range %+v", layout, info)
+		}
+	}
+}
+
+// TestWriteInputEvents тестирует экспорт дампа и обратный импорт
+func TestWriteInputEvents(t *testing.T) {
+	f, err := ParseEvemu(strings.NewReader(csvTestText))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	for _, format := range []string{FormatRaw, "raw-64le", "raw-32le", "raw-64be", "raw-32be"} {
+		var data bytes.Buffer
+		if err := f.WriteFormat(&data, format); err != nil {
+			t.Fatalf("WriteFormat(%s) failed: %v", format, err)
+		}
+		layout, _ := RawFormatLayout(format)
+		if data.Len() != len(f.Events)*layout.Size() {
+			t.Errorf("%s: unexpected size %d", format, data.Len())
+		}
+
+		parsed, err := ParseData(data.Bytes())
+		if err != nil {
+			t.Fatalf("%s: ParseData failed: %v", format, err)
+		}
+		for i, e := range parsed.Events {
+			if e.Timestamp != f.Events[i].Timestamp || e.TypeNum() != f.Events[i].TypeNum() ||
+				e.CodeNum() != f.Events[i].CodeNum() || e.IntValue() != f.Events[i].IntValue() {
+				t.Errorf("%s: event %d mismatch: %+v", format, i, e)
+			}
+		}
+	}
+
+	if _, ok := RawFormatLayout("raw-16le"); ok {
+		t.Error("Expected unknown layout to be rejected")
+	}
+	negative := &EvemuFile{Events: []Event{NewEvent(-1, EvKey, 0x130, 1)}}
+	if err := negative.WriteInputEvents(&bytes.Buffer{}, InputEventLayouts[0]); err == nil {
+		t.Error("Expected error for negative timestamp")
+	}
+}
+
+// TestDetectInputEventLayoutErrors тестирует отказ на данных, не похожих на дамп
+func TestDetectInputEventLayoutErrors(t *testing.T) {
+	data := newInputEventDump(InputEventLayouts[0], inputEventTestRecords)
+	for name, bad := range map[string][]byte{
+		"truncated":     data[:len(data)-5],
+		"empty":         nil,
+		"unknown type":  newInputEventDump(InputEventLayouts[0], [][5]int64{{1, 0, 0x40, 0, 0}, {1, 0, 0x40, 0, 0}, {1, 0, 0x40, 0, 0}}),
+		"time backward": newInputEventDump(InputEventLayouts[0], [][5]int64{{5, 0, 0, 0, 0}, {4, 0, 0, 0, 0}, {3, 0, 0, 0, 0}}),
+	} {
+		if _, err := DetectInputEventLayout(bad); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if _, err := ParseData(data[:len(data)-5]); err == nil {
+		t.Error("Expected ParseData error for truncated dump")
+	}
+
+	// Двоичные данные, не похожие на дамп, - ошибка определения формата
+	for name, bad := range map[string][]byte{
+		"text with NUL": []byte("E: 0.000000 0001 0130 0001\n\x00\n"),
+		"png":           {0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0, 0, 0, 0x0d},
+	} {
+		_, err := ParseData(bad)
+		if err == nil || !strings.Contains(err.Error(), "не удалось определить формат данных") {
+			t.Errorf("%s: expected format detection error, got %v", name, err)
+		}
+	}
+}
+
+// failingWriter отказывает в записи после limit байт
+type failingWriter struct {
+	limit int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		return 0, errors.New("диск заполнен")
+	}
+	w.limit -= len(p)
+	return len(p), nil
+}
+
+// TestWriteInputEventsError тестирует, что ошибка приёмника не теряется
+func TestWriteInputEventsError(t *testing.T) {
+	f := &EvemuFile{}
+	for i := 0; i < 1000; i++ {
+		f.Events = append(f.Events, NewEvent(float64(i)*0.001, EvKey, 0x130, i%2))
+	}
+	if err := f.WriteInputEvents(&failingWriter{limit: 4096}, InputEventLayouts[0]); err == nil {
+		t.Error("Expected write error")
+	}
+}
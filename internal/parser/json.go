@@ -0,0 +1,274 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Идентификатор и версия JSON-представления записи
+const (
+	JSONFormatName    = "evemu-json"
+	JSONFormatVersion = 1
+)
+
+// RecordingJSON - JSON-представление EvemuFile. Header хранит строки
+// заголовка без изменений, поэтому запись восстанавливается без потерь;
+// Device - разобранное описание устройства для внешних инструментов.
+type RecordingJSON struct {
+	Format   string        `json:"format"`
+	Version  int           `json:"version"`
+	Header   []string      `json:"header"`
+	Device   *DeviceJSON   `json:"device,omitempty"`
+	Events   []EventJSON   `json:"events"`
+	Comments []CommentJSON `json:"comments"`
+}
+
+// DeviceJSON - описание устройства с символьными именами типов и кодов
+type DeviceJSON struct {
+	Name    string              `json:"name"`
+	Bus     int                 `json:"bus"`
+	Vendor  int                 `json:"vendor"`
+	Product int                 `json:"product"`
+	Version int                 `json:"version"`
+	Props   []int               `json:"props"`
+	Codes   map[string][]string `json:"codes"` // тип -> объявленные коды
+	Abs     map[string]AbsInfo  `json:"abs"`   // ось -> параметры
+}
+
+// EventJSON - событие с числовыми и символьными полями. Raw содержит
+// исходные поля "тип код значение", только если они записаны не так,
+// как их записал бы NewEvent.
+type EventJSON struct {
+	Time     float64 `json:"time"`
+	Type     int     `json:"type"`
+	TypeName string  `json:"type_name"`
+	Code     int     `json:"code"`
+	CodeName string  `json:"code_name"`
+	Value    int     `json:"value"`
+	Raw      string  `json:"raw,omitempty"`
+}
+
+// CommentJSON - комментарий; для маркеров дополнительно указывается имя
+type CommentJSON struct {
+	Time   float64 `json:"time"`
+	Text   string  `json:"text"`
+	Marker string  `json:"marker,omitempty"`
+}
+
+// ToJSON строит JSON-представление записи
+func (f *EvemuFile) ToJSON() *RecordingJSON {
+	result := &RecordingJSON{
+		Format:   JSONFormatName,
+		Version:  JSONFormatVersion,
+		Header:   make([]string, len(f.Header)),
+		Events:   make([]EventJSON, len(f.Events)),
+		Comments: make([]CommentJSON, len(f.Comments)),
+	}
+	for i, line := range f.Header {
+		result.Header[i] = strings.TrimSuffix(line, "\n")
+	}
+	if descriptor := ParseDescriptor(f.Header); descriptor.known() {
+		result.Device = newDeviceJSON(descriptor)
+	}
+	for i, event := range f.Events {
+		result.Events[i] = NewEventJSON(event)
+	}
+	for i, comment := range f.Comments {
+		result.Comments[i] = CommentJSON{Time: comment.Timestamp, Text: comment.Text}
+		if name, ok := comment.Marker(); ok {
+			result.Comments[i].Marker = name
+		}
+	}
+	return result
+}
+
+// NewEventJSON строит JSON-представление события
+func NewEventJSON(event Event) EventJSON {
+	typ, code, value := event.TypeNum(), event.CodeNum(), event.IntValue()
+	result := EventJSON{
+		Time:     event.Timestamp,
+		Type:     typ,
+		TypeName: TypeLabel(typ),
+		Code:     code,
+		CodeName: CodeLabel(typ, code),
+		Value:    value,
+	}
+	if canonical := NewEvent(event.Timestamp, typ, code, value); canonical.Type != event.Type ||
+		canonical.Code != event.Code || canonical.Value != event.Value {
+		result.Raw = strings.Join([]string{event.Type, event.Code, event.Value}, " ")
+	}
+	return result
+}
+
+// Event восстанавливает событие. Известные символьные имена важнее чисел,
+// как и в читаемом виде. Поля raw используются, только если они совпадают
+// с числовыми, чтобы правка значения (например, в jq) не терялась.
+func (e EventJSON) Event() (Event, error) {
+	typ, code := e.Type, e.Code
+	if e.TypeName != "" {
+		if value, ok := LookupType(e.TypeName); ok {
+			typ = value
+		}
+	}
+	if e.CodeName != "" {
+		if value, ok := LookupCode(typ, e.CodeName); ok {
+			code = value
+		}
+	}
+	if typ < 0 || code < 0 {
+		return Event{}, fmt.Errorf("некорректное событие: тип %d, код %d", typ, code)
+	}
+	event := NewEvent(e.Time, typ, code, e.Value)
+
+	if e.Raw != "" {
+		fields := strings.Fields(e.Raw)
+		if len(fields) != 3 {
+			return Event{}, fmt.Errorf("ожидается \"тип код значение\": %s", e.Raw)
+		}
+		raw := Event{Timestamp: e.Time, Type: fields[0], Code: fields[1], Value: fields[2]}
+		if raw.TypeNum() == typ && raw.CodeNum() == code && raw.IntValue() == e.Value {
+			event = raw
+		}
+	}
+	return event, nil
+}
+
+// newDeviceJSON строит описание устройства с символьными именами
+func newDeviceJSON(d *Descriptor) *DeviceJSON {
+	result := &DeviceJSON{
+		Name:    d.Name,
+		Bus:     d.Bus,
+		Vendor:  d.Vendor,
+		Product: d.Product,
+		Version: d.Version,
+		Props:   []int{},
+		Codes:   make(map[string][]string),
+		Abs:     make(map[string]AbsInfo),
+	}
+	for i, b := range d.Props {
+		for bit := 0; bit < 8; bit++ {
+			if b&(1<<bit) != 0 {
+				result.Props = append(result.Props, i*8+bit)
+			}
+		}
+	}
+	for typ := range d.Bits {
+		if typ == EvSyn {
+			continue
+		}
+		names := []string{}
+		for _, code := range d.Codes(typ) {
+			names = append(names, CodeLabel(typ, code))
+		}
+		result.Codes[TypeLabel(typ)] = names
+	}
+	for code, info := range d.Abs {
+		result.Abs[CodeLabel(EvAbs, code)] = info
+	}
+	return result
+}
+
+// Descriptor восстанавливает описание устройства по символьным именам
+func (d *DeviceJSON) Descriptor() (*Descriptor, error) {
+	result := &Descriptor{
+		Name:    d.Name,
+		Bus:     d.Bus,
+		Vendor:  d.Vendor,
+		Product: d.Product,
+		Version: d.Version,
+		Bits:    make(map[int][]byte),
+		Abs:     make(map[int]AbsInfo),
+	}
+	for _, prop := range d.Props {
+		if prop < 0 {
+			return nil, fmt.Errorf("некорректное свойство устройства: %d", prop)
+		}
+		result.SetProp(prop)
+	}
+
+	var typeNames []string
+	for name := range d.Codes {
+		typeNames = append(typeNames, name)
+	}
+	sort.Strings(typeNames)
+	for _, typeName := range typeNames {
+		typ, ok := LookupType(typeName)
+		if !ok {
+			return nil, fmt.Errorf("неизвестный тип события: %s", typeName)
+		}
+		result.Bits[typ] = nil
+		for _, name := range d.Codes[typeName] {
+			code, ok := LookupCode(typ, name)
+			if !ok {
+				return nil, fmt.Errorf("неизвестный код события: %s", name)
+			}
+			result.SetCode(typ, code)
+		}
+	}
+	for name, info := range d.Abs {
+		code, ok := LookupCode(EvAbs, name)
+		if !ok {
+			return nil, fmt.Errorf("неизвестная ось: %s", name)
+		}
+		result.Abs[code] = info
+	}
+	return result, nil
+}
+
+// EvemuFile восстанавливает запись. Если строк заголовка нет, заголовок
+// строится по описанию устройства.
+func (r *RecordingJSON) EvemuFile() (*EvemuFile, error) {
+	if r.Format != "" && r.Format != JSONFormatName {
+		return nil, fmt.Errorf("неизвестный формат JSON: %s", r.Format)
+	}
+	if r.Version > JSONFormatVersion {
+		return nil, fmt.Errorf("неподдерживаемая версия JSON: %d", r.Version)
+	}
+
+	result := &EvemuFile{}
+	for _, line := range r.Header {
+		result.Header = append(result.Header, line+"\n")
+	}
+	if len(result.Header) == 0 && r.Device != nil {
+		descriptor, err := r.Device.Descriptor()
+		if err != nil {
+			return nil, err
+		}
+		result.Header = descriptor.HeaderLines()
+	}
+
+	for i, e := range r.Events {
+		event, err := e.Event()
+		if err != nil {
+			return nil, fmt.Errorf("событие %d: %v", i+1, err)
+		}
+		result.Events = append(result.Events, event)
+	}
+	for _, c := range r.Comments {
+		text := c.Text
+		if text == "" && c.Marker != "" {
+			text = MarkerPrefix + " " + c.Marker
+		}
+		result.Comments = append(result.Comments, Comment{Timestamp: c.Time, Text: text})
+	}
+	return result, nil
+}
+
+// WriteJSON записывает запись в формате JSON
+func (f *EvemuFile) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(f.ToJSON())
+}
+
+// ParseJSON разбирает запись в формате JSON, созданную WriteJSON
+func ParseJSON(r io.Reader) (*EvemuFile, error) {
+	var recording RecordingJSON
+	if err := json.NewDecoder(r).Decode(&recording); err != nil {
+		return nil, fmt.Errorf("ошибка разбора JSON: %v", err)
+	}
+	return recording.EvemuFile()
+}
@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestJSONRoundTrip тестирует восстановление записи из JSON без потерь
+func TestJSONRoundTrip(t *testing.T) {
+	text := strings.Join(testDeviceHeader, "") +
+		"E: 0.000000 0001 0130 0001\n" +
+		"E: 0.000000 0000 0000 0000\n" +
+		"# marker: combo\n" +
+		"E: 0.016123 0003 0011 -001\n" +
+		"E: 0.016123 0000 0000 0000\n" +
+		"# обычный комментарий\n" +
+		"E: 0.120000 1 130 0\n" + // нестандартная запись полей
+		"E: 0.120000 0000 0000 0000\n"
+	f, err := ParseEvemu(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	var data bytes.Buffer
+	if err := f.WriteJSON(&data); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	parsed, err := ParseData(data.Bytes())
+	if err != nil {
+		t.Fatalf("ParseData failed: %v", err)
+	}
+
+	var restored bytes.Buffer
+	if err := parsed.Write(&restored); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if restored.String() != text {
+		t.Errorf("Round-trip mismatch:\n%s\nexpected:\n%s", restored.String(), text)
+	}
+}
+
+// TestToJSON тестирует числовые и символьные поля JSON-представления
+func TestToJSON(t *testing.T) {
+	f := &EvemuFile{
+		Header: testDeviceHeader,
+		Events: []Event{
+			NewEvent(0.5, EvKey, 0x130, 1),
+			{Timestamp: 0.6, Type: "1", Code: "130", Value: "0"},
+		},
+		Comments: []Comment{{Timestamp: 0.5, Text: "# marker: jump"}},
+	}
+	r := f.ToJSON()
+
+	if r.Format != JSONFormatName || r.Version != JSONFormatVersion || r.Header[1] != "N: Microsoft X-Box 360 pad" {
+		t.Errorf("Unexpected recording: %+v", r)
+	}
+	expected := EventJSON{Time: 0.5, Type: 1, TypeName: "EV_KEY", Code: 0x130, CodeName: "BTN_SOUTH", Value: 1}
+	if r.Events[0] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, r.Events[0])
+	}
+	if r.Events[1].Raw != "1 130 0" {
+		t.Errorf("Expected raw fields for non-canonical event, got %+v", r.Events[1])
+	}
+	if r.Comments[0].Marker != "jump" {
+		t.Errorf("Expected marker name, got %+v", r.Comments[0])
+	}
+
+	d := r.Device
+	if d == nil || d.Vendor != 0x045e || d.Abs["ABS_X"].Min != -32768 || len(d.Codes["EV_KEY"]) != 15 || d.Codes["EV_ABS"][0] != "ABS_X" {
+		t.Errorf("Unexpected device: %+v", d)
+	}
+	if (&EvemuFile{}).ToJSON().Device != nil {
+		t.Error("Expected no device without header")
+	}
+}
+
+// TestParseJSONHandwritten тестирует разбор JSON без строк заголовка и числовых полей
+func TestParseJSONHandwritten(t *testing.T) {
+	r := (&EvemuFile{Header: testDeviceHeader}).ToJSON()
+	devJSON, _ := json.Marshal(r.Device)
+	text := `{"device": ` + string(devJSON) + `, "events": [
+		{"time": 0.25, "type_name": "EV_KEY", "code_name": "A", "value": 1},
+		{"time": 0.25, "type": 0, "code": 0, "value": 0}
+	], "comments": [{"time": 0.25, "marker": "start"}]}`
+
+	f, err := ParseJSON(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("ParseJSON failed: %v", err)
+	}
+	if strings.Join(f.Header, "") != strings.Join(testDeviceHeader, "") {
+		t.Errorf("Header was not rebuilt from device:\n%s", strings.Join(f.Header, ""))
+	}
+	if f.Events[0] != NewEvent(0.25, EvKey, 0x130, 1) || !f.Events[1].IsSynReport() {
+		t.Errorf("Unexpected events: %+v", f.Events)
+	}
+	if f.Comments[0].Text != "# marker: start" {
+		t.Errorf("Unexpected comment: %+v", f.Comments[0])
+	}
+
+	for _, bad := range []string{
+		`{"format": "other"}`,
+		`{"version": 99}`,
+		`{"events": [{"raw": "0001 0130"}]}`,
+		`{"device": {"codes": {"EV_NOPE": []}}}`,
+		`{"events": [`,
+	} {
+		if _, err := ParseJSON(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected error for %s", bad)
+		}
+	}
+}
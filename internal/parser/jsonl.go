@@ -0,0 +1,305 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Идентификатор и версия потокового представления JSON Lines
+const (
+	JSONLFormatName    = "evemu-jsonl"
+	JSONLFormatVersion = 1
+)
+
+// jsonlHeader - первая строка потока: заголовок и описание устройства
+type jsonlHeader struct {
+	Format  string      `json:"format"`
+	Version int         `json:"version"`
+	Header  []string    `json:"header"`
+	Device  *DeviceJSON `json:"device,omitempty"`
+}
+
+// jsonlFrame - строка с кадром: события до SYN_REPORT включительно
+type jsonlFrame struct {
+	Time   float64     `json:"time"`
+	Events []EventJSON `json:"events"`
+}
+
+// jsonlComment - строка с комментарием
+type jsonlComment struct {
+	Time    float64 `json:"time"`
+	Comment string  `json:"comment"`
+	Marker  string  `json:"marker,omitempty"`
+}
+
+// jsonlLine - любая строка потока после заголовка: событие, кадр или комментарий
+type jsonlLine struct {
+	EventJSON
+	Events  []EventJSON `json:"events"`
+	Comment *string     `json:"comment"`
+	Marker  string      `json:"marker"`
+}
+
+// JSONLRecord - одна строка потока: событие, кадр или комментарий
+type JSONLRecord struct {
+	Events  []Event // одно событие или события кадра
+	Comment *Comment
+}
+
+// JSONLWriter записывает запись построчно: первая строка - заголовок и
+// описание устройства, далее по строке на событие или, в режиме кадров,
+// на кадр. Комментарии, пришедшие внутри кадра, записываются после него.
+type JSONLWriter struct {
+	writer   *bufio.Writer
+	frames   bool
+	frame    []EventJSON
+	comments []Comment
+}
+
+// NewJSONLWriter создаёт поток и записывает строку заголовка
+func NewJSONLWriter(w io.Writer, header []string, frames bool) (*JSONLWriter, error) {
+	j := &JSONLWriter{writer: bufio.NewWriter(w), frames: frames}
+	line := jsonlHeader{Format: JSONLFormatName, Version: JSONLFormatVersion, Header: make([]string, len(header))}
+	for i, text := range header {
+		line.Header[i] = strings.TrimSuffix(text, "\n")
+	}
+	if descriptor := ParseDescriptor(header); descriptor.known() {
+		line.Device = newDeviceJSON(descriptor)
+	}
+	return j, j.writeLine(line)
+}
+
+// WriteEvent записывает событие; в режиме кадров - накапливает до SYN_REPORT
+func (j *JSONLWriter) WriteEvent(event Event) error {
+	if !j.frames {
+		return j.writeLine(NewEventJSON(event))
+	}
+	j.frame = append(j.frame, NewEventJSON(event))
+	if event.IsSynReport() {
+		return j.flushFrame()
+	}
+	return nil
+}
+
+// WriteComment записывает комментарий
+func (j *JSONLWriter) WriteComment(comment Comment) error {
+	if len(j.frame) > 0 {
+		j.comments = append(j.comments, comment)
+		return nil
+	}
+	return j.writeComment(comment)
+}
+
+// Flush записывает незавершённый кадр и сбрасывает буфер
+func (j *JSONLWriter) Flush() error {
+	if err := j.flushFrame(); err != nil {
+		return err
+	}
+	return j.writer.Flush()
+}
+
+// flushFrame записывает накопленный кадр и отложенные комментарии
+func (j *JSONLWriter) flushFrame() error {
+	if len(j.frame) > 0 {
+		if err := j.writeLine(jsonlFrame{Time: j.frame[0].Time, Events: j.frame}); err != nil {
+			return err
+		}
+		j.frame = nil
+	}
+	for _, comment := range j.comments {
+		if err := j.writeComment(comment); err != nil {
+			return err
+		}
+	}
+	j.comments = nil
+	return nil
+}
+
+// writeComment записывает строку комментария
+func (j *JSONLWriter) writeComment(comment Comment) error {
+	line := jsonlComment{Time: comment.Timestamp, Comment: comment.Text}
+	if name, ok := comment.Marker(); ok {
+		line.Marker = name
+	}
+	return j.writeLine(line)
+}
+
+// writeLine записывает значение одной строкой
+func (j *JSONLWriter) writeLine(value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	j.writer.Write(data)
+	return j.writer.WriteByte('\n')
+}
+
+// JSONLReader читает поток JSON Lines построчно
+type JSONLReader struct {
+	Header  []string
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewJSONLReader читает строку заголовка. Если строк заголовка нет,
+// заголовок строится по описанию устройства.
+func NewJSONLReader(r io.Reader) (*JSONLReader, error) {
+	j := &JSONLReader{scanner: bufio.NewScanner(r)}
+	j.scanner.Buffer(nil, 16*1024*1024)
+
+	data, err := j.nextLine()
+	if err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("пустой поток JSON Lines")
+		}
+		return nil, err
+	}
+	var header jsonlHeader
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("строка %d: ошибка разбора JSON: %v", j.line, err)
+	}
+	if header.Format != "" && header.Format != JSONLFormatName {
+		return nil, fmt.Errorf("неизвестный формат JSON Lines: %s", header.Format)
+	}
+	if header.Version > JSONLFormatVersion {
+		return nil, fmt.Errorf("неподдерживаемая версия JSON Lines: %d", header.Version)
+	}
+
+	for _, line := range header.Header {
+		j.Header = append(j.Header, line+"\n")
+	}
+	if len(j.Header) == 0 && header.Device != nil {
+		descriptor, err := header.Device.Descriptor()
+		if err != nil {
+			return nil, err
+		}
+		j.Header = descriptor.HeaderLines()
+	}
+	return j, nil
+}
+
+// Next читает следующую строку потока; в конце потока возвращает io.EOF.
+// Номер строки потока записывается в Event.Line.
+func (j *JSONLReader) Next() (JSONLRecord, error) {
+	data, err := j.nextLine()
+	if err != nil {
+		return JSONLRecord{}, err
+	}
+	var line jsonlLine
+	if err := json.Unmarshal(data, &line); err != nil {
+		return JSONLRecord{}, fmt.Errorf("строка %d: ошибка разбора JSON: %v", j.line, err)
+	}
+
+	if line.Comment != nil || line.Marker != "" {
+		text := ""
+		if line.Comment != nil {
+			text = *line.Comment
+		}
+		if text == "" && line.Marker != "" {
+			text = MarkerPrefix + " " + line.Marker
+		}
+		return JSONLRecord{Comment: &Comment{Timestamp: line.Time, Text: text}}, nil
+	}
+
+	events := line.Events
+	if events == nil {
+		events = []EventJSON{line.EventJSON}
+	}
+	var record JSONLRecord
+	for _, e := range events {
+		event, err := e.Event()
+		if err != nil {
+			return JSONLRecord{}, fmt.Errorf("строка %d: %v", j.line, err)
+		}
+		event.Line = j.line
+		record.Events = append(record.Events, event)
+	}
+	return record, nil
+}
+
+// nextLine возвращает следующую непустую строку
+func (j *JSONLReader) nextLine() ([]byte, error) {
+	for j.scanner.Scan() {
+		j.line++
+		if data := bytes.TrimSpace(j.scanner.Bytes()); len(data) > 0 {
+			return data, nil
+		}
+	}
+	if err := j.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла: %v", err)
+	}
+	return nil, io.EOF
+}
+
+// WriteJSONL записывает запись в формате JSON Lines: по строке на событие
+// или, если frames, на кадр
+func (f *EvemuFile) WriteJSONL(w io.Writer, frames bool) error {
+	j, err := NewJSONLWriter(w, f.Header, frames)
+	if err != nil {
+		return err
+	}
+
+	comment := 0
+	for i, event := range f.Events {
+		if err := j.WriteEvent(event); err != nil {
+			return err
+		}
+		if i+1 < len(f.Events) {
+			next := f.Events[i+1].Timestamp
+			for comment < len(f.Comments) && f.Comments[comment].Timestamp < next {
+				if err := j.WriteComment(f.Comments[comment]); err != nil {
+					return err
+				}
+				comment++
+			}
+		}
+	}
+	for ; comment < len(f.Comments); comment++ {
+		if err := j.WriteComment(f.Comments[comment]); err != nil {
+			return err
+		}
+	}
+	return j.Flush()
+}
+
+// ParseJSONL разбирает запись в формате JSON Lines
+func ParseJSONL(r io.Reader) (*EvemuFile, error) {
+	j, err := NewJSONLReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &EvemuFile{Header: j.Header}
+	for {
+		record, err := j.Next()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Events = append(result.Events, record.Events...)
+		if record.Comment != nil {
+			result.Comments = append(result.Comments, *record.Comment)
+		}
+	}
+}
+
+// isJSONL проверяет, что данные - поток JSON Lines: первая непустая строка
+// является самостоятельным объектом с форматом evemu-jsonl или за ней
+// следуют другие строки
+func isJSONL(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	first, rest, _ := bytes.Cut(data, []byte("\n"))
+	var header struct {
+		Format string `json:"format"`
+	}
+	if json.Unmarshal(first, &header) != nil {
+		return false
+	}
+	return header.Format == JSONLFormatName || (header.Format == "" && len(bytes.TrimSpace(rest)) > 0)
+}
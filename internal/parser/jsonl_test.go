@@ -0,0 +1,164 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+// jsonlTestText - запись с маркером, комментарием и нестандартным событием
+var jsonlTestText = strings.Join(testDeviceHeader, "") +
+	"E: 0.000000 0001 0130 0001\n" +
+	"E: 0.000000 0003 0000 1200\n" +
+	"E: 0.000000 0000 0000 0000\n" +
+	"# marker: combo\n" +
+	"E: 0.016123 0003 0011 -001\n" +
+	"E: 0.016123 0000 0000 0000\n" +
+	"# конец\n" +
+	"E: 0.120000 1 130 0\n" +
+	"E: 0.120000 0000 0000 0000\n"
+
+// TestJSONLRoundTrip тестирует восстановление записи из JSON Lines без потерь
+func TestJSONLRoundTrip(t *testing.T) {
+	f, err := ParseEvemu(strings.NewReader(jsonlTestText))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	for _, frames := range []bool{false, true} {
+		var data bytes.Buffer
+		if err := f.WriteJSONL(&data, frames); err != nil {
+			t.Fatalf("WriteJSONL failed: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(data.String()), "\n")
+		expected := 1 + len(f.Events) + len(f.Comments)
+		if frames {
+			expected = 1 + 3 + len(f.Comments)
+		}
+		if len(lines) != expected {
+			t.Errorf("frames=%v: expected %d lines, got %d:\n%s", frames, expected, len(lines), data.String())
+		}
+
+		if format := DetectFormat(data.Bytes()); format != FormatJSONL {
+			t.Errorf("frames=%v: detected as %s", frames, format)
+		}
+		parsed, err := ParseData(data.Bytes())
+		if err != nil {
+			t.Fatalf("ParseData failed: %v", err)
+		}
+		var restored bytes.Buffer
+		parsed.Write(&restored)
+		if restored.String() != jsonlTestText {
+			t.Errorf("frames=%v: round-trip mismatch:\n%s", frames, restored.String())
+		}
+	}
+}
+
+// TestJSONLWriterFrames тестирует запись кадров и отложенных комментариев
+func TestJSONLWriterFrames(t *testing.T) {
+	var data bytes.Buffer
+	j, err := NewJSONLWriter(&data, testDeviceHeader, true)
+	if err != nil {
+		t.Fatalf("NewJSONLWriter failed: %v", err)
+	}
+	j.WriteEvent(NewEvent(0.5, EvKey, 0x130, 1))
+	j.WriteComment(Comment{Timestamp: 0.5, Text: "# marker: hit"})
+	j.WriteEvent(NewEvent(0.5, EvSyn, SynReport, 0))
+	j.WriteEvent(NewEvent(0.6, EvKey, 0x130, 0))
+	if err := j.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(data.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected 4 lines, got:\n%s", data.String())
+	}
+	var header jsonlHeader
+	if err := json.Unmarshal([]byte(lines[0]), &header); err != nil || header.Format != JSONLFormatName || header.Device.Name != "Microsoft X-Box 360 pad" {
+		t.Errorf("Unexpected header line: %s, %v", lines[0], err)
+	}
+	var frame jsonlFrame
+	if err := json.Unmarshal([]byte(lines[1]), &frame); err != nil || frame.Time != 0.5 || len(frame.Events) != 2 {
+		t.Errorf("Unexpected frame line: %s, %v", lines[1], err)
+	}
+	if lines[2] != `{"time":0.5,"comment":"# marker: hit","marker":"hit"}` {
+		t.Errorf("Unexpected comment line: %s", lines[2])
+	}
+	// Незавершённый кадр записывается при Flush
+	if !strings.Contains(lines[3], `"time":0.6`) {
+		t.Errorf("Unexpected last line: %s", lines[3])
+	}
+}
+
+// TestJSONLReader тестирует построчное чтение и правку событий в потоке
+func TestJSONLReader(t *testing.T) {
+	device, _ := json.Marshal(newDeviceJSON(ParseDescriptor(testDeviceHeader)))
+	text := `{"device": ` + string(device) + "}\n" +
+		"\n" +
+		`{"time": 0.25, "type_name": "EV_KEY", "code_name": "A", "value": 1}` + "\n" +
+		// значение изменено после записи: raw больше не совпадает и игнорируется
+		`{"time": 0.25, "type": 3, "code": 0, "value": 500, "raw": "3 0 100"}` + "\n" +
+		`{"time": 0.25, "events": [{"time": 0.25, "type": 0, "code": 0, "value": 0}]}` + "\n" +
+		`{"time": 0.25, "marker": "start"}` + "\n"
+
+	j, err := NewJSONLReader(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("NewJSONLReader failed: %v", err)
+	}
+	if strings.Join(j.Header, "") != strings.Join(testDeviceHeader, "") {
+		t.Errorf("Header was not rebuilt from device:\n%s", strings.Join(j.Header, ""))
+	}
+
+	var records []JSONLRecord
+	for {
+		record, err := j.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 4 {
+		t.Fatalf("Expected 4 records, got %+v", records)
+	}
+	if records[0].Events[0].CodeNum() != 0x130 || records[0].Events[0].Line != 3 {
+		t.Errorf("Unexpected first event: %+v", records[0].Events[0])
+	}
+	if records[1].Events[0].IntValue() != 500 {
+		t.Errorf("Edited value was lost: %+v", records[1].Events[0])
+	}
+	if !records[2].Events[0].IsSynReport() || records[3].Comment.Text != "# marker: start" {
+		t.Errorf("Unexpected records: %+v", records[2:])
+	}
+}
+
+// TestDetectJSONL тестирует различение JSON и JSON Lines
+func TestDetectJSONL(t *testing.T) {
+	tests := map[string]string{
+		"{\n  \"format\": \"evemu-json\"\n}\n":           FormatJSON,
+		`{"format": "evemu-json", "events": []}`:         FormatJSON,
+		`{"format": "evemu-jsonl", "header": []}`:        FormatJSONL,
+		"{\"header\": []}\n{\"time\": 0, \"type\": 0}\n": FormatJSONL,
+	}
+	for text, expected := range tests {
+		if format := DetectFormat([]byte(text)); format != expected {
+			t.Errorf("DetectFormat(%q): expected %s, got %s", text, expected, format)
+		}
+	}
+
+	for _, bad := range []string{
+		"",
+		`{"format": "other"}` + "\n",
+		`{"version": 99}` + "\n",
+		"{}\n{\"time\": \n",
+		"{}\n{\"raw\": \"1 2\"}\n",
+	} {
+		if _, err := ParseJSONL(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Версия формата libinput record
+const LibinputRecordVersion = 1
+
+// libinputBanner - первая строка записи libinput record
+const libinputBanner = "# libinput record"
+
+// libinputCommentPrefix - префикс комментариев YAML, хранящих комментарии
+// записи evemu: "# evemu: <время> <текст>"
+const libinputCommentPrefix = "# evemu: "
+
+// libinputSynCodes - коды EV_SYN, объявляемые устройством при экспорте:
+// SYN_REPORT, SYN_CONFIG, SYN_MT_REPORT и SYN_DROPPED
+var libinputSynCodes = []int{0, 1, 2, 3}
+
+// WriteLibinput записывает запись в формате YAML libinput record с одним
+// устройством. Описание устройства строится по заголовку evemu, события
+// группируются в кадры "- evdev:" по SYN_REPORT. Комментарии записи
+// сохраняются комментариями YAML "# evemu: <время> <текст>" после кадра.
+func (f *EvemuFile) WriteLibinput(w io.Writer) error {
+	d := ParseDescriptor(f.Header)
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, libinputBanner)
+	fmt.Fprintf(bw, "version: %d\n", LibinputRecordVersion)
+	fmt.Fprintln(bw, "ndevices: 1")
+	fmt.Fprintln(bw, "libinput:")
+	fmt.Fprintln(bw, `  version: "unknown"`)
+	fmt.Fprintln(bw, `  git: "unknown"`)
+	fmt.Fprintln(bw, "system:")
+	fmt.Fprintln(bw, `  os: "unknown"`)
+	fmt.Fprintln(bw, `  kernel: "unknown"`)
+	fmt.Fprintln(bw, `  dmi: "unknown"`)
+	fmt.Fprintln(bw, "devices:")
+	fmt.Fprintln(bw, "- node: /dev/input/event0")
+	writeLibinputDevice(bw, d)
+
+	frames := f.Frames()
+	if len(frames) == 0 {
+		fmt.Fprintln(bw, "  events: []")
+	} else {
+		fmt.Fprintln(bw, "  events:")
+	}
+	comment := 0
+	previous := 0.0
+	for i, frame := range frames {
+		fmt.Fprintln(bw, "  - evdev:")
+		for _, event := range frame.Events {
+			writeLibinputEvent(bw, event, previous)
+		}
+		previous = frame.Timestamp()
+
+		for comment < len(f.Comments) && (i+1 == len(frames) || f.Comments[comment].Timestamp < frames[i+1].Timestamp()) {
+			writeLibinputComment(bw, f.Comments[comment])
+			comment++
+		}
+	}
+	for ; comment < len(f.Comments); comment++ {
+		writeLibinputComment(bw, f.Comments[comment])
+	}
+	return bw.Flush()
+}
+
+// writeLibinputDevice записывает блок evdev с описанием устройства
+func writeLibinputDevice(w io.Writer, d *Descriptor) {
+	fmt.Fprintln(w, "  evdev:")
+	fmt.Fprintf(w, "    # Name: %s\n", d.Name)
+	fmt.Fprintf(w, "    # ID: bus 0x%x vendor 0x%x product 0x%x version 0x%x\n", d.Bus, d.Vendor, d.Product, d.Version)
+	fmt.Fprintf(w, "    name: %s\n", quoteYAML(d.Name))
+	fmt.Fprintf(w, "    id: [%d, %d, %d, %d]\n", d.Bus, d.Vendor, d.Product, d.Version)
+
+	fmt.Fprintln(w, "    codes:")
+	fmt.Fprintf(w, "      %d: %s # %s\n", EvSyn, formatLibinputList(libinputSynCodes), TypeLabel(EvSyn))
+	var types []int
+	for typ := range d.Bits {
+		if typ != EvSyn {
+			types = append(types, typ)
+		}
+	}
+	sort.Ints(types)
+	for _, typ := range types {
+		fmt.Fprintf(w, "      %d: %s # %s\n", typ, formatLibinputList(d.Codes(typ)), TypeLabel(typ))
+	}
+
+	var axes []int
+	for code := range d.Abs {
+		axes = append(axes, code)
+	}
+	sort.Ints(axes)
+	if len(axes) > 0 {
+		fmt.Fprintln(w, "    absinfo:")
+		for _, code := range axes {
+			info := d.Abs[code]
+			fmt.Fprintf(w, "      %d: %s # %s\n", code,
+				formatLibinputList([]int{info.Min, info.Max, info.Fuzz, info.Flat, info.Resolution}), CodeLabel(EvAbs, code))
+		}
+	}
+
+	var props []int
+	for i, b := range d.Props {
+		for bit := 0; bit < 8; bit++ {
+			if b&(1<<bit) != 0 {
+				props = append(props, i*8+bit)
+			}
+		}
+	}
+	fmt.Fprintf(w, "    properties: %s\n", formatLibinputList(props))
+}
+
+// writeLibinputEvent записывает событие строкой [сек, мкс, тип, код, значение]
+// с расшифровкой в комментарии, как libinput record
+func writeLibinputEvent(w io.Writer, event Event, previous float64) {
+	sec, usec := splitLibinputTime(event.Timestamp)
+	typ, code, value := event.TypeNum(), event.CodeNum(), event.IntValue()
+	fmt.Fprintf(w, "    - [%3d, %6d, %3d, %3d, %6d] # ", sec, usec, typ, code, value)
+	if event.IsSynReport() {
+		fmt.Fprintf(w, "------------ SYN_REPORT (0) ---------- +%dms\n",
+			int(math.Round((event.Timestamp-previous)*1000)))
+		return
+	}
+	fmt.Fprintf(w, "%s / %-20s %6d\n", TypeLabel(typ), CodeLabel(typ, code), value)
+}
+
+// writeLibinputComment записывает комментарий записи комментарием YAML
+func writeLibinputComment(w io.Writer, comment Comment) {
+	fmt.Fprintf(w, "  %s%.6f %s\n", libinputCommentPrefix, comment.Timestamp, comment.Text)
+}
+
+// splitLibinputTime разделяет время на секунды и микросекунды
+func splitLibinputTime(seconds float64) (int, int) {
+	micro := int64(math.Round(seconds * 1e6))
+	return int(micro / 1e6), int(micro % 1e6)
+}
+
+// formatLibinputList форматирует список чисел однострочным списком YAML
+func formatLibinputList(values []int) string {
+	items := make([]string, len(values))
+	for i, value := range values {
+		items[i] = strconv.Itoa(value)
+	}
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+// ParseLibinput импортирует запись libinput record. Заголовок evemu
+// строится по блоку evdev первого устройства (имя, идентификатор, коды,
+// параметры осей и свойства), события берутся из его кадров "- evdev:";
+// остальные устройства и записи libinput пропускаются. Время событий
+// сохраняется как есть: libinput отсчитывает его от начала записи.
+func ParseLibinput(r io.Reader) (*EvemuFile, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла: %v", err)
+	}
+	root, comments, err := parseYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора YAML: %v", err)
+	}
+	if version := root.get("version"); version != nil {
+		if number, err := version.int(); err != nil || number > LibinputRecordVersion {
+			return nil, fmt.Errorf("неподдерживаемая версия libinput record: %s", version.Value)
+		}
+	}
+	devices := root.get("devices")
+	if devices == nil || devices.Kind != yamlList || len(devices.List) == 0 {
+		return nil, fmt.Errorf("в записи libinput нет устройств")
+	}
+	device := devices.List[0]
+
+	descriptor, err := parseLibinputDevice(device.get("evdev"))
+	if err != nil {
+		return nil, err
+	}
+	result := &EvemuFile{Header: descriptor.HeaderLines()}
+
+	if events := device.get("events"); events != nil && events.Kind == yamlList {
+		for _, item := range events.List {
+			frame := item.get("evdev")
+			if frame == nil || frame.Kind != yamlList {
+				continue
+			}
+			for _, row := range frame.List {
+				event, err := parseLibinputEvent(row)
+				if err != nil {
+					return nil, err
+				}
+				result.Events = append(result.Events, event)
+			}
+		}
+	}
+
+	for _, c := range comments {
+		text, ok := strings.CutPrefix(c.Text, libinputCommentPrefix)
+		if !ok {
+			continue
+		}
+		timeText, text, _ := strings.Cut(text, " ")
+		timestamp, err := strconv.ParseFloat(timeText, 64)
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: некорректное время комментария: %s", c.Line, timeText)
+		}
+		result.Comments = append(result.Comments, Comment{Timestamp: timestamp, Text: text})
+	}
+	return result, nil
+}
+
+// Пределы номеров осей и свойств устройства (ABS_MAX и INPUT_PROP_MAX ядра);
+// пределы типов и кодов - как у дампа struct input_event
+const (
+	libinputMaxAbs  = 0x3f
+	libinputMaxProp = 0x1f
+)
+
+// parseLibinputDevice строит описание устройства по блоку evdev
+func parseLibinputDevice(evdev *yamlNode) (*Descriptor, error) {
+	if evdev == nil || evdev.Kind != yamlMap {
+		return nil, fmt.Errorf("в описании устройства нет блока evdev")
+	}
+	d := ParseDescriptor(nil)
+	d.Name = evdev.get("name").valueOrEmpty()
+
+	if id := evdev.get("id"); id != nil {
+		values, err := id.ints()
+		if err != nil || len(values) != 4 {
+			return nil, fmt.Errorf("строка %d: ожидается id: [bus, vendor, product, version]", id.Line)
+		}
+		d.Bus, d.Vendor, d.Product, d.Version = values[0], values[1], values[2], values[3]
+	}
+
+	if codes := evdev.get("codes"); codes != nil && codes.Kind == yamlMap {
+		for _, key := range codes.Keys {
+			typ, err := strconv.Atoi(key)
+			if err != nil || typ < 0 || typ > inputEventMaxType {
+				return nil, fmt.Errorf("строка %d: некорректный тип события: %s", codes.Map[key].Line, key)
+			}
+			values, err := codes.Map[key].ints()
+			if err != nil {
+				return nil, fmt.Errorf("строка %d: коды типа %s: %v", codes.Map[key].Line, key, err)
+			}
+			// Маска EV_SYN строится по объявленным типам
+			if typ == EvSyn {
+				continue
+			}
+			d.Bits[typ] = nil
+			for _, code := range values {
+				if code < 0 || code > inputEventMaxCode {
+					return nil, fmt.Errorf("строка %d: код %d типа %s вне диапазона 0..%d", codes.Map[key].Line, code, key, inputEventMaxCode)
+				}
+				d.SetCode(typ, code)
+			}
+		}
+	}
+
+	if absinfo := evdev.get("absinfo"); absinfo != nil && absinfo.Kind == yamlMap {
+		for _, key := range absinfo.Keys {
+			code, err := strconv.Atoi(key)
+			values, listErr := absinfo.Map[key].ints()
+			if err != nil || listErr != nil || len(values) != 5 {
+				return nil, fmt.Errorf("строка %d: ожидается %s: [min, max, fuzz, flat, resolution]", absinfo.Map[key].Line, key)
+			}
+			if code < 0 || code > libinputMaxAbs {
+				return nil, fmt.Errorf("строка %d: ось %d вне диапазона 0..%d", absinfo.Map[key].Line, code, libinputMaxAbs)
+			}
+			d.Abs[code] = AbsInfo{Min: values[0], Max: values[1], Fuzz: values[2], Flat: values[3], Resolution: values[4]}
+		}
+	}
+
+	if props := evdev.get("properties"); props != nil && props.Kind == yamlList {
+		values, err := props.ints()
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: свойства устройства: %v", props.Line, err)
+		}
+		for _, prop := range values {
+			if prop < 0 || prop > libinputMaxProp {
+				return nil, fmt.Errorf("строка %d: свойство устройства %d вне диапазона 0..%d", props.Line, prop, libinputMaxProp)
+			}
+			d.SetProp(prop)
+		}
+	}
+	return d, nil
+}
+
+// parseLibinputEvent разбирает строку события [сек, мкс, тип, код, значение]
+func parseLibinputEvent(row *yamlNode) (Event, error) {
+	values, err := row.ints()
+	if err != nil || len(values) != 5 {
+		return Event{}, fmt.Errorf("строка %d: ожидается [sec, usec, type, code, value]", row.Line)
+	}
+	if values[2] < 0 || values[3] < 0 {
+		return Event{}, fmt.Errorf("строка %d: некорректное событие: тип %d, код %d", row.Line, values[2], values[3])
+	}
+	event := NewEvent(float64(values[0]*1000000+values[1])/1e6, values[2], values[3], values[4])
+	event.Line = row.Line
+	return event, nil
+}
+
+// isLibinputLine проверяет, что строка начинает запись libinput record
+func isLibinputLine(line string) bool {
+	return line == libinputBanner || strings.HasPrefix(line, "ndevices:")
+}
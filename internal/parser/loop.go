@@ -0,0 +1,330 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// Параметры поиска петли по умолчанию
+const (
+	DefaultLoopJitter   = 0.05 // допустимое расхождение интервалов между кадрами, секунды
+	DefaultLoopMinScore = 0.8  // минимальная доля кадров, совпавших по времени
+)
+
+// Ограничения поиска периода: каждый кадр голосует за расстояния до
+// нескольких следующих похожих кадров, проверяются лишь периоды с наибольшим
+// числом голосов
+const (
+	loopNeighbours = 8
+	loopCandidates = 64
+)
+
+// LoopOptions задаёт параметры поиска повторяющегося периода
+type LoopOptions struct {
+	Jitter         float64 // допустимое расхождение интервалов (0 - DefaultLoopJitter)
+	MinScore       float64 // минимальная доля кадров, совпавших по времени (0 - DefaultLoopMinScore)
+	MaxPeriod      int     // наибольший период в кадрах (0 - половина записи)
+	ValueTolerance int     // допуск значений осей, если он больше fuzz и flat из заголовка
+}
+
+// LoopResult описывает найденную петлю. Номера кадров соответствуют Frames(),
+// EndFrame - первый кадр следующей итерации.
+type LoopResult struct {
+	Period     int     `json:"period"`      // длина итерации в кадрах с событиями
+	PeriodTime float64 `json:"period_time"` // медианная длительность итерации, секунды
+	Jitter     float64 `json:"jitter"`      // наибольшее отклонение длительности итерации от медианы
+	Score      float64 `json:"score"`       // доля кадров, совпавших по времени со сдвинутыми на период
+	Repeats    int     `json:"repeats"`     // количество целых итераций подряд
+	StartFrame int     `json:"start_frame"`
+	EndFrame   int     `json:"end_frame"`
+	StartTime  float64 `json:"start_time"`
+	EndTime    float64 `json:"end_time"`
+}
+
+// loopEvent - событие кадра без SYN
+type loopEvent struct {
+	typ, code, value int
+}
+
+// loopFrame - кадр с событиями без SYN, подготовленный для поиска петли
+type loopFrame struct {
+	index  int
+	time   float64
+	events []loopEvent
+}
+
+// loopFrames готовит кадры к поиску петли, пропуская кадры только из SYN
+func loopFrames(f *EvemuFile) []loopFrame {
+	var result []loopFrame
+	for i, frame := range f.Frames() {
+		var events []loopEvent
+		for _, event := range frame.Events {
+			if event.TypeNum() != EvSyn {
+				events = append(events, loopEvent{event.TypeNum(), event.CodeNum(), event.IntValue()})
+			}
+		}
+		if len(events) > 0 {
+			result = append(result, loopFrame{index: i, time: frame.Timestamp(), events: events})
+		}
+	}
+	return result
+}
+
+// loopTolerances возвращает допуск значений осей: наибольшее из fuzz и flat
+// заголовка и заданного допуска
+func loopTolerances(d *Descriptor, tolerance int) map[int]int {
+	result := make(map[int]int)
+	for code, info := range d.Abs {
+		result[code] = max(info.Fuzz, info.Flat, tolerance)
+	}
+	return result
+}
+
+// FindLoop ищет повторяющийся период записи. Кадры сравниваются по
+// содержимому без SYN, значения осей - с допуском (fuzz и flat из заголовка
+// или ValueTolerance); период должен повторяться хотя бы дважды подряд.
+// Кандидаты в периоды выбираются голосованием: каждый кадр голосует за
+// расстояния до нескольких следующих кадров с той же грубой сигнатурой
+// (значения осей округлены до допуска), поэтому время поиска линейно по
+// длине записи. Из периодов, покрывающих почти наибольшую часть записи,
+// выбирается самый короткий, чтобы не принять за период несколько итераций
+// подряд. Интервалы до следующего кадра сравниваются с допуском Jitter,
+// доля совпавших по времени кадров должна быть не меньше MinScore.
+func (f *EvemuFile) FindLoop(opts LoopOptions) (*LoopResult, error) {
+	if opts.Jitter <= 0 {
+		opts.Jitter = DefaultLoopJitter
+	}
+	if opts.MinScore <= 0 {
+		opts.MinScore = DefaultLoopMinScore
+	}
+
+	frames := loopFrames(f)
+	n := len(frames)
+	maxPeriod := n / 2
+	if opts.MaxPeriod > 0 {
+		maxPeriod = min(maxPeriod, opts.MaxPeriod)
+	}
+	tolerances := loopTolerances(ParseDescriptor(f.Header), opts.ValueTolerance)
+
+	same := func(i, period int) bool {
+		a, b := frames[i].events, frames[i+period].events
+		if len(a) != len(b) {
+			return false
+		}
+		for j := range a {
+			if a[j].typ != b[j].typ || a[j].code != b[j].code {
+				return false
+			}
+			tolerance := 0
+			if a[j].typ == EvAbs {
+				tolerance = tolerances[a[j].code]
+			}
+			if absInt(a[j].value-b[j].value) > tolerance {
+				return false
+			}
+		}
+		return true
+	}
+	onTime := func(i, period int) bool {
+		if i+period+1 >= n {
+			return true
+		}
+		interval := frames[i+1].time - frames[i].time
+		shifted := frames[i+period+1].time - frames[i+period].time
+		return math.Abs(interval-shifted) <= opts.Jitter
+	}
+
+	// Для каждого кандидата ищем самый длинный участок, где кадры совпадают
+	// с кадрами через период: участок длиной run покрывает run+period кадров
+	periods := loopCandidatePeriods(frames, tolerances, maxPeriod)
+	runs := make(map[int]int)
+	runStarts := make(map[int]int)
+	bestCoverage := 0
+	for _, period := range periods {
+		run := 0
+		for i := 0; i+period < n; i++ {
+			if !same(i, period) {
+				run = 0
+				continue
+			}
+			run++
+			if run > runs[period] {
+				runs[period], runStarts[period] = run, i-run+1
+			}
+		}
+		if runs[period] >= period {
+			bestCoverage = max(bestCoverage, runs[period]+period)
+		}
+	}
+
+	period, score := 0, 0.0
+	for _, p := range periods {
+		if runs[p] < p || float64(runs[p]+p) < 0.95*float64(bestCoverage) {
+			continue
+		}
+		count := 0
+		for i := runStarts[p]; i < runStarts[p]+runs[p]; i++ {
+			if onTime(i, p) {
+				count++
+			}
+		}
+		if s := float64(count) / float64(runs[p]); s >= opts.MinScore {
+			period, score = p, s
+			break
+		}
+	}
+	if period == 0 {
+		return nil, fmt.Errorf("повторяющийся период не найден")
+	}
+	first, run := runStarts[period], runs[period]
+
+	// Начало петли - итерация участка с наибольшим числом совпадений по времени
+	window := 0
+	for i := first; i < first+period; i++ {
+		if onTime(i, period) {
+			window++
+		}
+	}
+	start, bestWindow := first, window
+	for s := first + 1; s+period <= first+run; s++ {
+		if onTime(s-1, period) {
+			window--
+		}
+		if onTime(s+period-1, period) {
+			window++
+		}
+		if window > bestWindow {
+			start, bestWindow = s, window
+		}
+	}
+
+	var durations []float64
+	for i := first; i < first+run; i++ {
+		durations = append(durations, frames[i+period].time-frames[i].time)
+	}
+	sort.Float64s(durations)
+	periodTime := durations[len(durations)/2]
+	jitter := 0.0
+	for _, d := range durations {
+		jitter = math.Max(jitter, math.Abs(d-periodTime))
+	}
+
+	return &LoopResult{
+		Period:     period,
+		PeriodTime: periodTime,
+		Jitter:     jitter,
+		Score:      score,
+		Repeats:    (run + period) / period,
+		StartFrame: frames[start].index,
+		EndFrame:   frames[start+period].index,
+		StartTime:  frames[start].time,
+		EndTime:    frames[start+period].time,
+	}, nil
+}
+
+// loopCandidatePeriods выбирает периоды для проверки: кадр голосует за
+// расстояния до loopNeighbours следующих кадров с той же грубой сигнатурой,
+// возвращается до loopCandidates периодов с наибольшим числом голосов в
+// порядке возрастания
+func loopCandidatePeriods(frames []loopFrame, tolerances map[int]int, maxPeriod int) []int {
+	ids := make([]int, len(frames))
+	known := make(map[string]int)
+	var key []byte
+	for i, frame := range frames {
+		key = key[:0]
+		for _, event := range frame.events {
+			value := event.value
+			if tolerance := tolerances[event.code]; event.typ == EvAbs && tolerance > 0 {
+				value = int(math.Floor(float64(value) / float64(2*tolerance+1)))
+			}
+			key = fmt.Appendf(key, "%d %d %d;", event.typ, event.code, value)
+		}
+		id, ok := known[string(key)]
+		if !ok {
+			id = len(known)
+			known[string(key)] = id
+		}
+		ids[i] = id
+	}
+
+	// Позиции кадров с той же сигнатурой по порядку
+	occurrences := make([][]int, len(known))
+	for i, id := range ids {
+		occurrences[id] = append(occurrences[id], i)
+	}
+	votes := make(map[int]int)
+	for _, positions := range occurrences {
+		for i, position := range positions {
+			for _, next := range positions[i+1 : min(len(positions), i+1+loopNeighbours)] {
+				if next-position > maxPeriod {
+					break
+				}
+				votes[next-position]++
+			}
+		}
+	}
+
+	periods := make([]int, 0, len(votes))
+	for period := range votes {
+		periods = append(periods, period)
+	}
+	sort.Slice(periods, func(i, j int) bool {
+		if votes[periods[i]] != votes[periods[j]] {
+			return votes[periods[i]] > votes[periods[j]]
+		}
+		return periods[i] < periods[j]
+	})
+	periods = periods[:min(len(periods), loopCandidates)]
+	sort.Ints(periods)
+	return periods
+}
+
+// ExtractLoop вырезает одну итерацию петли, начиная с нулевого времени.
+// В конец добавляется пустой SYN_REPORT в момент окончания периода, чтобы
+// GenerateRepeatedEvents ставил следующую итерацию точно через период.
+func (f *EvemuFile) ExtractLoop(loop *LoopResult) *EvemuFile {
+	frames := f.Frames()
+	var events []Event
+	for _, frame := range frames[loop.StartFrame:loop.EndFrame] {
+		for _, event := range frame.Events {
+			event.Timestamp -= loop.StartTime
+			events = append(events, event)
+		}
+	}
+
+	end := loop.PeriodTime
+	if n := len(events); n > 0 && events[n-1].Timestamp > end {
+		end = events[n-1].Timestamp
+	}
+	events = append(events, NewEvent(end, EvSyn, SynReport, 0))
+
+	var comments []Comment
+	for _, comment := range f.Comments {
+		if comment.Timestamp >= loop.StartTime && comment.Timestamp < loop.EndTime {
+			comments = append(comments, Comment{Timestamp: comment.Timestamp - loop.StartTime, Text: comment.Text})
+		}
+	}
+
+	return &EvemuFile{Header: f.Header, Events: events, Comments: comments}
+}
+
+// WriteText записывает описание петли в человекочитаемом виде
+func (r *LoopResult) WriteText(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	fmt.Fprintf(writer, "Период:      %d кадров, %.6f с (разброс %.6f с)\n", r.Period, r.PeriodTime, r.Jitter)
+	fmt.Fprintf(writer, "Повторов:    %d\n", r.Repeats)
+	fmt.Fprintf(writer, "Совпадение:  %.1f%%\n", r.Score*100)
+	fmt.Fprintf(writer, "Петля:       кадры %d-%d, %.6f-%.6f с\n", r.StartFrame, r.EndFrame, r.StartTime, r.EndTime)
+	return writer.Flush()
+}
+
+// WriteJSON записывает описание петли в формате JSON
+func (r *LoopResult) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
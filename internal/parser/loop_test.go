@@ -0,0 +1,182 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// newLoopTestFile создаёт запись: вступление, четыре итерации с небольшим
+// разбросом времени (A, затем B, период 1 с) и завершение
+func newLoopTestFile() *EvemuFile {
+	f := &EvemuFile{Header: testDeviceHeader}
+	add := func(ts float64, code, value int) {
+		f.Events = append(f.Events, NewEvent(ts, EvKey, code, value), NewEvent(ts, EvSyn, SynReport, 0))
+	}
+
+	add(0.0, 0x13a, 1) // BACK
+	add(0.2, 0x13a, 0)
+	jitter := []float64{0, 0.004, -0.003, 0.002}
+	for i, d := range jitter {
+		base := 0.5 + float64(i) + d
+		add(base, 0x130, 1)
+		add(base+0.1, 0x130, 0)
+		add(base+0.4+d, 0x131, 1)
+		add(base+0.6, 0x131, 0)
+	}
+	add(4.6, 0x13b, 1) // START
+	add(4.7, 0x13b, 0)
+	f.Comments = []Comment{{Timestamp: 0.5, Text: "# marker: grind"}, {Timestamp: 2.5, Text: "# third"}}
+	return f
+}
+
+// TestFindLoop тестирует поиск периода с разбросом времени
+func TestFindLoop(t *testing.T) {
+	loop, err := newLoopTestFile().FindLoop(LoopOptions{})
+	if err != nil {
+		t.Fatalf("FindLoop failed: %v", err)
+	}
+
+	if loop.Period != 4 || loop.Repeats != 4 {
+		t.Errorf("Expected period of 4 frames, got %+v", loop)
+	}
+	if math.Abs(loop.PeriodTime-1.0) > 0.005 || loop.Jitter > 0.02 {
+		t.Errorf("Unexpected period time: %+v", loop)
+	}
+	if loop.StartFrame != 2 || loop.EndFrame != 6 || loop.StartTime != 0.5 {
+		t.Errorf("Expected loop at frames 2-6, got %+v", loop)
+	}
+	if loop.Score < 0.8 {
+		t.Errorf("Unexpected score: %f", loop.Score)
+	}
+}
+
+// TestFindLoopNone тестирует запись без повторов и слишком строгий допуск
+func TestFindLoopNone(t *testing.T) {
+	f := &EvemuFile{}
+	for i := 0; i < 6; i++ {
+		ts := float64(i)
+		f.Events = append(f.Events, NewEvent(ts, EvKey, 0x130+i, 1), NewEvent(ts, EvSyn, SynReport, 0))
+	}
+	if _, err := f.FindLoop(LoopOptions{}); err == nil {
+		t.Error("Expected error for recording without loop")
+	}
+
+	if _, err := newLoopTestFile().FindLoop(LoopOptions{Jitter: 0.0001, MinScore: 0.95}); err == nil {
+		t.Error("Expected error for strict jitter")
+	}
+}
+
+// TestExtractLoop тестирует вырезание итерации, пригодной для повторения
+func TestExtractLoop(t *testing.T) {
+	f := newLoopTestFile()
+	loop, err := f.FindLoop(LoopOptions{})
+	if err != nil {
+		t.Fatalf("FindLoop failed: %v", err)
+	}
+	iteration := f.ExtractLoop(loop)
+
+	if len(iteration.Events) != 9 {
+		t.Fatalf("Expected 4 frames and trailing SYN_REPORT, got %+v", iteration.Events)
+	}
+	if iteration.Events[0].Timestamp != 0 || iteration.Events[0].CodeNum() != 0x130 {
+		t.Errorf("Unexpected first event: %+v", iteration.Events[0])
+	}
+	last := iteration.Events[8]
+	if !last.IsSynReport() || last.Timestamp != loop.PeriodTime {
+		t.Errorf("Expected SYN_REPORT at period end, got %+v", last)
+	}
+	if len(iteration.Comments) != 1 || iteration.Comments[0].Timestamp != 0 {
+		t.Errorf("Unexpected comments: %+v", iteration.Comments)
+	}
+
+	// Следующая итерация начинается ровно через период
+	repeated := iteration.GenerateRepeatedEvents(2)
+	if second := repeated.Events[9]; second.CodeNum() != 0x130 || math.Abs(second.Timestamp-loop.PeriodTime) > 1e-9 {
+		t.Errorf("Unexpected start of second iteration: %+v", second)
+	}
+}
+
+// TestLoopResultOutput тестирует текстовый и JSON-отчёт о петле
+func TestLoopResultOutput(t *testing.T) {
+	loop := &LoopResult{Period: 4, PeriodTime: 1, Score: 0.9, Repeats: 3, StartFrame: 2, EndFrame: 10, StartTime: 0.5, EndTime: 1.5}
+
+	var text bytes.Buffer
+	if err := loop.WriteText(&text); err != nil || !strings.Contains(text.String(), "кадры 2-10") {
+		t.Errorf("Unexpected text report: %s, %v", text.String(), err)
+	}
+
+	var buf bytes.Buffer
+	if err := loop.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	var decoded LoopResult
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || decoded != *loop {
+		t.Errorf("Unexpected JSON: %s, %v", buf.String(), err)
+	}
+}
+
+// newStickLoopTestFile создаёт длинную запись, сделанную вручную: стик
+// описывает круг за period кадров с дрожанием на несколько единиц, кнопка
+// нажимается раз за итерацию
+func newStickLoopTestFile(period, iterations int) *EvemuFile {
+	random := rand.New(rand.NewSource(1))
+	f := &EvemuFile{Header: testDeviceHeader}
+	timestamp := 0.0
+	for i := 0; i < period*iterations; i++ {
+		phase := 2 * math.Pi * float64(i%period) / float64(period)
+		x := int(20000*math.Cos(phase)) + random.Intn(9) - 4
+		y := int(20000*math.Sin(phase)) + random.Intn(9) - 4
+		timestamp += 0.008 + float64(random.Intn(3))*0.001
+		f.Events = append(f.Events, NewEvent(timestamp, EvAbs, 0x00, x), NewEvent(timestamp, EvAbs, 0x01, y))
+		if i%period == 0 {
+			f.Events = append(f.Events, NewEvent(timestamp, EvKey, 0x130, 1))
+		}
+		f.Events = append(f.Events, NewEvent(timestamp, EvSyn, SynReport, 0))
+	}
+	return f
+}
+
+// TestFindLoopAnalog тестирует поиск петли стика с дрожанием значений:
+// допуск берётся из fuzz и flat заголовка
+func TestFindLoopAnalog(t *testing.T) {
+	f := newStickLoopTestFile(50, 6)
+	loop, err := f.FindLoop(LoopOptions{})
+	if err != nil {
+		t.Fatalf("FindLoop failed: %v", err)
+	}
+	if loop.Period != 50 || loop.Repeats != 6 {
+		t.Errorf("Expected period of 50 frames repeated 6 times, got %+v", loop)
+	}
+
+	// Без допуска дрожание не даёт найти период
+	var exact []string
+	for _, line := range testDeviceHeader {
+		exact = append(exact, strings.Replace(line, " 16 128 0", " 0 0 0", 1))
+	}
+	f.Header = exact
+	if loop, err := f.FindLoop(LoopOptions{}); err == nil && loop.Period == 50 {
+		t.Errorf("Expected no exact match with jittery values, got %+v", loop)
+	}
+	if loop, err := f.FindLoop(LoopOptions{ValueTolerance: 8}); err != nil || loop.Period != 50 {
+		t.Errorf("Expected period with --value-tolerance, got %+v, %v", loop, err)
+	}
+}
+
+// TestFindLoopLong тестирует поиск петли в длинной записи и ограничение периода
+func TestFindLoopLong(t *testing.T) {
+	f := newStickLoopTestFile(250, 400) // 100 000 кадров, около 15 минут
+	loop, err := f.FindLoop(LoopOptions{})
+	if err != nil {
+		t.Fatalf("FindLoop failed: %v", err)
+	}
+	if loop.Period != 250 || loop.Repeats != 400 {
+		t.Errorf("Expected period of 250 frames, got %+v", loop)
+	}
+	if _, err := f.FindLoop(LoopOptions{MaxPeriod: 100}); err == nil {
+		t.Error("Expected no loop with --max-period below the period")
+	}
+}
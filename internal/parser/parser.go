@@ -3,6 +3,7 @@ package parser
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -10,8 +11,20 @@ import (
 
 // EvemuFile представляет файл с событиями геймпада
 type EvemuFile struct {
-	Header []string
-	Events []Event
+	Header   []string
+	Events   []Event
+	Comments []Comment
+}
+
+// Comment представляет строку комментария между событиями.
+// Timestamp равен времени предшествующего события: при записи комментарий
+// выводится после последнего события с таким временем. Положение хранится
+// только временем, чтобы переживать преобразования, поэтому комментарий
+// внутри кадра (между событиями с одинаковым временем) переносится в конец
+// кадра, после его SYN_REPORT.
+type Comment struct {
+	Timestamp float64
+	Text      string
 }
 
 // Event представляет одно событие геймпада
@@ -20,6 +33,7 @@ type Event struct {
 	Type      string
 	Code      string
 	Value     string
+	Line      int // номер строки в исходном файле (0, если событие создано программно)
 }
 
 // ParseEvemuFile читает и разбирает файл evemu
@@ -30,12 +44,19 @@ func ParseEvemuFile(filename string) (*EvemuFile, error) {
 	}
 	defer file.Close()
 
+	return ParseEvemu(file)
+}
+
+// ParseEvemu читает и разбирает данные evemu из произвольного источника
+func ParseEvemu(r io.Reader) (*EvemuFile, error) {
 	result := &EvemuFile{}
 	inEventsSection := false
 
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
 	for scanner.Scan() {
 		line := scanner.Text()
+		lineNumber++
 
 		if strings.HasPrefix(line, "################################") {
 			inEventsSection = true
@@ -49,8 +70,14 @@ func ParseEvemuFile(filename string) (*EvemuFile, error) {
 			if strings.HasPrefix(line, "E:") {
 				event := parseEventLine(line)
 				if event.Type != "" { // Пропускаем некорректные строки
+					event.Line = lineNumber
 					result.Events = append(result.Events, event)
 				}
+			} else if strings.HasPrefix(line, "#") && len(result.Events) > 0 {
+				result.Comments = append(result.Comments, Comment{
+					Timestamp: result.Events[len(result.Events)-1].Timestamp,
+					Text:      line,
+				})
 			} else {
 				result.Header = append(result.Header, line+"\n")
 			}
@@ -84,25 +111,36 @@ func parseEventLine(line string) Event {
 	}
 }
 
-// WriteToFile записывает EvemuFile в файл
+// WriteToFile записывает EvemuFile в файл; путь с .gz сжимается gzip
 func (f *EvemuFile) WriteToFile(filename string) error {
-	file, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("ошибка создания файла: %v", err)
-	}
-	defer file.Close()
+	return WriteReport(filename, false, f.Write)
+}
 
-	writer := bufio.NewWriter(file)
+// Write записывает EvemuFile в формате evemu в произвольный приёмник
+func (f *EvemuFile) Write(w io.Writer) error {
+	writer := bufio.NewWriter(w)
 
 	// Записываем заголовок
 	for _, line := range f.Header {
 		writer.WriteString(line)
 	}
 
-	// Записываем события
-	for _, event := range f.Events {
+	// Записываем события вместе с комментариями между ними
+	comment := 0
+	for i, event := range f.Events {
 		writer.WriteString(fmt.Sprintf("E: %.6f %s %s %s\n",
 			event.Timestamp, event.Type, event.Code, event.Value))
+
+		if i+1 < len(f.Events) {
+			next := f.Events[i+1].Timestamp
+			for comment < len(f.Comments) && f.Comments[comment].Timestamp < next {
+				writer.WriteString(f.Comments[comment].Text + "\n")
+				comment++
+			}
+		}
+	}
+	for ; comment < len(f.Comments); comment++ {
+		writer.WriteString(f.Comments[comment].Text + "\n")
 	}
 
 	return writer.Flush()
@@ -133,6 +171,9 @@ func (f *EvemuFile) GenerateRepeatedEvents(repeatCount int) *EvemuFile {
 				Value:     event.Value,
 			})
 		}
+		result.Comments = append(result.Comments, mapComments(f.Comments, func(t float64) float64 {
+			return baseTime + (t - startTime)
+		})...)
 	}
 
 	return result
@@ -172,5 +213,20 @@ func (f *EvemuFile) Merge(other *EvemuFile) *EvemuFile {
 	return &EvemuFile{
 		Header: f.Header,
 		Events: append(f.Events, adjustedEvents...),
+		Comments: append(append([]Comment{}, f.Comments...), mapComments(other.Comments, func(t float64) float64 {
+			return t + timeOffset
+		})...),
 	}
 }
+
+// mapComments пересчитывает время комментариев функцией mapTime
+func mapComments(comments []Comment, mapTime func(float64) float64) []Comment {
+	if len(comments) == 0 {
+		return nil
+	}
+	result := make([]Comment, len(comments))
+	for i, comment := range comments {
+		result[i] = Comment{Timestamp: mapTime(comment.Timestamp), Text: comment.Text}
+	}
+	return result
+}
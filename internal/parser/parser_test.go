@@ -1,8 +1,10 @@
 package parser
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -299,3 +301,97 @@ func TestWriteToInvalidPath(t *testing.T) {
 		t.Error("WriteToFile should return error for invalid path")
 	}
 }
+
+// TestCommentsRoundTrip тестирует сохранение комментариев между событиями
+func TestCommentsRoundTrip(t *testing.T) {
+	testContent := `# EVEMU 1.3
+################################
+#      Waiting for events      #
+################################
+E: 0.100000 0001 0130 0001
+E: 0.100000 0000 0000 0000
+# marker: jump
+E: 0.500000 0001 0130 0000
+E: 0.500000 0000 0000 0000
+# end of recording
+`
+
+	result, err := ParseEvemu(strings.NewReader(testContent))
+	if err != nil {
+		t.Fatalf("ParseEvemu() failed: %v", err)
+	}
+
+	if len(result.Header) != 4 {
+		t.Errorf("Expected 4 header lines, got %d", len(result.Header))
+	}
+	if len(result.Comments) != 2 || result.Comments[0].Timestamp != 0.1 || result.Comments[1].Timestamp != 0.5 {
+		t.Fatalf("Unexpected comments: %+v", result.Comments)
+	}
+
+	var buf bytes.Buffer
+	if err := result.Write(&buf); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if buf.String() != testContent {
+		t.Errorf("Round trip mismatch.\nExpected:\n%s\nGot:\n%s", testContent, buf.String())
+	}
+}
+
+// TestCommentInsideFrame тестирует перенос комментария внутри кадра в конец кадра
+func TestCommentInsideFrame(t *testing.T) {
+	testContent := `# EVEMU 1.3
+################################
+E: 0.100000 0001 0130 0001
+# marker: inside
+E: 0.100000 0003 0000 1200
+E: 0.100000 0000 0000 0000
+E: 0.200000 0001 0130 0000
+E: 0.200000 0000 0000 0000
+`
+	expected := `# EVEMU 1.3
+################################
+E: 0.100000 0001 0130 0001
+E: 0.100000 0003 0000 1200
+E: 0.100000 0000 0000 0000
+# marker: inside
+E: 0.200000 0001 0130 0000
+E: 0.200000 0000 0000 0000
+`
+
+	result, err := ParseEvemu(strings.NewReader(testContent))
+	if err != nil {
+		t.Fatalf("ParseEvemu() failed: %v", err)
+	}
+	if len(result.Comments) != 1 || result.Comments[0].Timestamp != 0.1 {
+		t.Fatalf("Unexpected comments: %+v", result.Comments)
+	}
+
+	var buf bytes.Buffer
+	if err := result.Write(&buf); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if buf.String() != expected {
+		t.Errorf("Expected comment after the frame.\nExpected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
+
+// TestCommentsFollowTransforms тестирует перенос комментариев при повторе и слиянии
+func TestCommentsFollowTransforms(t *testing.T) {
+	file := &EvemuFile{
+		Events: []Event{
+			{Timestamp: 1.0, Type: "0001", Code: "0130", Value: "0001"},
+			{Timestamp: 2.0, Type: "0001", Code: "0130", Value: "0000"},
+		},
+		Comments: []Comment{{Timestamp: 1.0, Text: "# marker: hold"}},
+	}
+
+	repeated := file.GenerateRepeatedEvents(2)
+	if len(repeated.Comments) != 2 || repeated.Comments[0].Timestamp != 0.0 || repeated.Comments[1].Timestamp != 1.0 {
+		t.Errorf("Unexpected repeated comments: %+v", repeated.Comments)
+	}
+
+	merged := file.Merge(file)
+	if len(merged.Comments) != 2 || merged.Comments[1].Timestamp != 2.0 {
+		t.Errorf("Unexpected merged comments: %+v", merged.Comments)
+	}
+}
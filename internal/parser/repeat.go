@@ -0,0 +1,81 @@
+package parser
+
+import "math"
+
+// RepeatOptions задаёт режим генерации повторений
+type RepeatOptions struct {
+	Count    int     // количество повторов (используется, если Duration не задан)
+	Duration float64 // целевая длительность в секундах
+	Truncate bool    // обрезать последний повтор по границе кадра и отпустить кнопки
+
+	// Sanitize, если задан, возвращает контроллер в покой на каждом стыке повторов
+	Sanitize *SanitizeOptions
+}
+
+// Repeat генерирует повторения по количеству или до достижения целевой длительности
+func (f *EvemuFile) Repeat(opts RepeatOptions) *EvemuFile {
+	source := f
+	if opts.Sanitize != nil {
+		// Кадр покоя добавляется во время последнего события, поэтому период не меняется
+		source = f.Sanitize(*opts.Sanitize)
+	}
+
+	if opts.Duration <= 0 {
+		return source.GenerateRepeatedEvents(opts.Count)
+	}
+
+	result := source.GenerateEventsForDuration(opts.Duration, opts.Truncate)
+	if opts.Sanitize != nil && opts.Truncate {
+		result = result.Sanitize(*opts.Sanitize)
+	}
+	return result
+}
+
+// GenerateEventsForDuration повторяет последовательность целыми итерациями,
+// пока не будет достигнута длительность duration (в секундах).
+// При truncate последняя итерация обрезается по границе кадра, не выходящего
+// за duration, а в конце отпускаются все удерживаемые кнопки.
+func (f *EvemuFile) GenerateEventsForDuration(duration float64, truncate bool) *EvemuFile {
+	if len(f.Events) == 0 {
+		return f
+	}
+
+	startTime := f.Events[0].Timestamp
+	totalDuration := f.Events[len(f.Events)-1].Timestamp - startTime
+
+	// Запись нулевой длины невозможно растянуть по времени; малая поправка
+	// защищает от лишнего повтора из-за ошибок округления
+	count := 1
+	if totalDuration > 0 {
+		count = max(1, int(math.Ceil(duration/totalDuration-1e-9)))
+	}
+
+	result := f.GenerateRepeatedEvents(count)
+	if !truncate {
+		return result
+	}
+
+	var events []Event
+	for _, frame := range result.Frames() {
+		if frame.Timestamp() > duration {
+			break
+		}
+		events = append(events, frame.Events...)
+	}
+
+	releaseTime := duration
+	if len(events) > 0 && events[len(events)-1].Timestamp > releaseTime {
+		releaseTime = events[len(events)-1].Timestamp
+	}
+	result.Events = append(events, releaseEvents(releaseTime, heldKeys(events))...)
+
+	var comments []Comment
+	for _, comment := range result.Comments {
+		if comment.Timestamp <= duration {
+			comments = append(comments, comment)
+		}
+	}
+	result.Comments = comments
+
+	return result
+}
@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"testing"
+)
+
+// newRepeatTestFile создаёт запись длительностью 1 секунда с удержанием кнопки
+func newRepeatTestFile() *EvemuFile {
+	return &EvemuFile{
+		Header: []string{"# Test header\n"},
+		Events: []Event{
+			NewEvent(0.0, EvKey, 0x130, 1),
+			NewEvent(0.0, EvSyn, SynReport, 0),
+			NewEvent(0.6, EvKey, 0x130, 0),
+			NewEvent(0.6, EvSyn, SynReport, 0),
+			NewEvent(0.7, EvKey, 0x131, 1),
+			NewEvent(0.7, EvSyn, SynReport, 0),
+			NewEvent(1.0, EvKey, 0x131, 0),
+			NewEvent(1.0, EvSyn, SynReport, 0),
+		},
+	}
+}
+
+// TestRepeatByCount тестирует, что режим по количеству совпадает с GenerateRepeatedEvents
+func TestRepeatByCount(t *testing.T) {
+	file := newRepeatTestFile()
+	result := file.Repeat(RepeatOptions{Count: 3})
+	if len(result.Events) != 24 {
+		t.Errorf("Expected 24 events, got %d", len(result.Events))
+	}
+}
+
+// TestGenerateEventsForDuration тестирует повторение до заданной длительности
+func TestGenerateEventsForDuration(t *testing.T) {
+	file := newRepeatTestFile()
+
+	// 2.5 секунды требуют трёх целых итераций
+	result := file.GenerateEventsForDuration(2.5, false)
+	if len(result.Events) != 24 {
+		t.Errorf("Expected 24 events, got %d", len(result.Events))
+	}
+	last := result.Events[len(result.Events)-1].Timestamp
+	if last != 3.0 {
+		t.Errorf("Expected last timestamp 3.0, got %f", last)
+	}
+
+	// Точное попадание в длительность не добавляет лишнюю итерацию
+	result = file.GenerateEventsForDuration(2.0, false)
+	if len(result.Events) != 16 {
+		t.Errorf("Expected 16 events, got %d", len(result.Events))
+	}
+
+	// Длительность короче записи - одна итерация
+	result = file.GenerateEventsForDuration(0.4, false)
+	if len(result.Events) != 8 {
+		t.Errorf("Expected 8 events, got %d", len(result.Events))
+	}
+}
+
+// TestGenerateEventsForDurationTruncate тестирует обрезку последней итерации
+func TestGenerateEventsForDurationTruncate(t *testing.T) {
+	file := newRepeatTestFile()
+
+	// Третья итерация начинается в 2.0: нажатие в 2.0 попадает, отпускание в 2.6 - нет
+	result := file.GenerateEventsForDuration(2.5, true)
+
+	last := result.Events[len(result.Events)-1]
+	if !last.IsSynReport() || last.Timestamp != 2.5 {
+		t.Errorf("Expected closing SYN_REPORT at 2.5, got %+v", last)
+	}
+
+	for _, event := range result.Events {
+		if event.Timestamp > 2.5 {
+			t.Errorf("Event beyond duration: %+v", event)
+		}
+	}
+
+	if held := heldKeys(result.Events); len(held) != 0 {
+		t.Errorf("Expected all keys released, still held: %v", held)
+	}
+
+	release := result.Events[len(result.Events)-2]
+	if release.TypeNum() != EvKey || release.CodeNum() != 0x130 || release.IntValue() != 0 {
+		t.Errorf("Expected release of 0x130, got %+v", release)
+	}
+}
+
+// TestGenerateEventsForDurationZeroLength тестирует запись нулевой длительности
+func TestGenerateEventsForDurationZeroLength(t *testing.T) {
+	file := &EvemuFile{
+		Events: []Event{
+			NewEvent(1.0, EvKey, 0x130, 1),
+			NewEvent(1.0, EvSyn, SynReport, 0),
+		},
+	}
+
+	result := file.GenerateEventsForDuration(10, false)
+	if len(result.Events) != 2 {
+		t.Errorf("Expected single iteration, got %d events", len(result.Events))
+	}
+}
@@ -0,0 +1,295 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Interpolation задаёт способ интерполяции значений оси
+type Interpolation int
+
+const (
+	InterpolateLinear Interpolation = iota // линейная интерполяция
+	InterpolateCubic                       // монотонный кубический сплайн без выбросов
+)
+
+// Smoothing задаёт способ сглаживания траектории оси
+type Smoothing int
+
+const (
+	SmoothNone          Smoothing = iota // без сглаживания
+	SmoothMovingAverage                  // центрированное скользящее среднее
+	SmoothLowPass                        // фильтр нижних частот первого порядка
+)
+
+// DefaultHoldGap - пауза между отчётами оси, после которой значение считается удерживаемым
+const DefaultHoldGap = 0.05
+
+// ResampleOptions задаёт параметры передискретизации абсолютных осей
+type ResampleOptions struct {
+	Rate          float64       // частота выходного потока, Гц
+	Interpolation Interpolation // способ интерполяции
+	Smoothing     Smoothing     // способ сглаживания
+	Window        int           // окно скользящего среднего, в отсчётах (нечётное)
+	Cutoff        float64       // частота среза фильтра нижних частот, Гц
+	HoldGap       float64       // пауза, после которой значение оси считается удерживаемым (0 - DefaultHoldGap)
+	Axes          []int         // оси для обработки (по умолчанию все, кроме крестовин ABS_HAT*)
+}
+
+// axisSample - значение оси в момент времени
+type axisSample struct {
+	t float64
+	v float64
+}
+
+// Resample переводит абсолютные оси в поток с постоянной частотой.
+// Отсчёты выравниваются по сетке от начала записи, значения ограничиваются
+// диапазоном из строк "A:" заголовка. Остальные события не изменяются.
+func (f *EvemuFile) Resample(opts ResampleOptions) (*EvemuFile, error) {
+	if opts.Rate <= 0 {
+		return nil, fmt.Errorf("частота передискретизации должна быть положительной")
+	}
+	if opts.Smoothing == SmoothMovingAverage && opts.Window%2 == 0 {
+		return nil, fmt.Errorf("окно скользящего среднего должно быть нечётным: %d", opts.Window)
+	}
+	if len(f.Events) == 0 {
+		return f, nil
+	}
+	if opts.HoldGap <= 0 {
+		opts.HoldGap = DefaultHoldGap
+	}
+
+	selected := make(map[int]bool)
+	for _, code := range opts.Axes {
+		selected[code] = true
+	}
+	isSelected := func(event Event) bool {
+		if event.TypeNum() != EvAbs {
+			return false
+		}
+		code := event.CodeNum()
+		if len(opts.Axes) == 0 {
+			return !isHatAxis(code)
+		}
+		return selected[code]
+	}
+
+	samples := make(map[int][]axisSample)
+	for _, event := range f.Events {
+		if isSelected(event) {
+			code := event.CodeNum()
+			samples[code] = appendSample(samples[code], event.Timestamp, float64(event.IntValue()))
+		}
+	}
+
+	var codes []int
+	for code := range samples {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	descriptor := ParseDescriptor(f.Header)
+	start := f.Events[0].Timestamp
+	step := 1 / opts.Rate
+	ticks := make(map[int][]Event)
+	for _, code := range codes {
+		points := holdGaps(samples[code], opts.HoldGap, step)
+		first, values := resampleAxis(points, start, step, opts.Interpolation)
+		values = smoothValues(values, opts, step)
+
+		info, hasRange := descriptor.AbsRange(code)
+		last := math.MinInt
+		for i, value := range values {
+			v := int(math.Round(value))
+			if hasRange {
+				v = info.Clamp(v)
+			}
+			if v == last {
+				continue
+			}
+			last = v
+			tick := first + i
+			ticks[tick] = append(ticks[tick], NewEvent(start+float64(tick)*step, EvAbs, code, v))
+		}
+	}
+
+	var axisFrames []Frame
+	for tick, events := range ticks {
+		axisFrames = append(axisFrames, newFrame(start+float64(tick)*step, events...))
+	}
+	sort.Slice(axisFrames, func(i, j int) bool {
+		return axisFrames[i].Timestamp() < axisFrames[j].Timestamp()
+	})
+
+	frames := removeEvents(f.Events, func(_ int, event Event) bool {
+		return isSelected(event)
+	})
+
+	return &EvemuFile{Header: f.Header, Events: mergeFrames(frames, axisFrames), Comments: f.Comments}, nil
+}
+
+// isHatAxis проверяет, является ли ось дискретной крестовиной ABS_HAT*
+func isHatAxis(code int) bool {
+	return code >= 0x10 && code <= 0x17
+}
+
+// appendSample добавляет отсчёт, заменяя предыдущий с тем же временем
+func appendSample(points []axisSample, t, v float64) []axisSample {
+	if n := len(points); n > 0 && points[n-1].t == t {
+		points[n-1].v = v
+		return points
+	}
+	return append(points, axisSample{t: t, v: v})
+}
+
+// holdGaps добавляет отсчёты удержания: evdev сообщает только изменения,
+// поэтому при длинной паузе значение не менялось до самого следующего отчёта
+func holdGaps(points []axisSample, holdGap, step float64) []axisSample {
+	var result []axisSample
+	for i, point := range points {
+		if i > 0 {
+			prev := points[i-1]
+			if point.t-prev.t > holdGap && point.t-step > prev.t {
+				result = append(result, axisSample{t: point.t - step, v: prev.v})
+			}
+		}
+		result = append(result, point)
+	}
+	return result
+}
+
+// resampleAxis вычисляет значения оси на сетке start + k*step.
+// Возвращает номер первого отсчёта сетки и значения начиная с него.
+func resampleAxis(points []axisSample, start, step float64, interpolation Interpolation) (int, []float64) {
+	const eps = 1e-9
+	first := int(math.Ceil((points[0].t-start)/step - eps))
+	last := int(math.Ceil((points[len(points)-1].t-start)/step - eps))
+
+	var tangents []float64
+	if interpolation == InterpolateCubic {
+		tangents = monotoneTangents(points)
+	}
+
+	values := make([]float64, 0, last-first+1)
+	segment := 0
+	for tick := first; tick <= last; tick++ {
+		t := start + float64(tick)*step
+		for segment < len(points)-2 && points[segment+1].t <= t {
+			segment++
+		}
+		values = append(values, interpolate(points, tangents, segment, t))
+	}
+	return first, values
+}
+
+// interpolate вычисляет значение на отрезке segment в момент t
+func interpolate(points []axisSample, tangents []float64, segment int, t float64) float64 {
+	if len(points) == 1 || t <= points[0].t {
+		return points[0].v
+	}
+	if t >= points[len(points)-1].t {
+		return points[len(points)-1].v
+	}
+
+	p0, p1 := points[segment], points[segment+1]
+	h := p1.t - p0.t
+	s := (t - p0.t) / h
+	if tangents == nil {
+		return p0.v + (p1.v-p0.v)*s
+	}
+
+	// Кубический полином Эрмита
+	s2, s3 := s*s, s*s*s
+	return (2*s3-3*s2+1)*p0.v + (s3-2*s2+s)*h*tangents[segment] +
+		(-2*s3+3*s2)*p1.v + (s3-s2)*h*tangents[segment+1]
+}
+
+// monotoneTangents вычисляет касательные Фритча-Карлсона, исключающие выбросы сплайна
+func monotoneTangents(points []axisSample) []float64 {
+	n := len(points)
+	tangents := make([]float64, n)
+	if n < 2 {
+		return tangents
+	}
+
+	slopes := make([]float64, n-1)
+	for i := 0; i < n-1; i++ {
+		slopes[i] = (points[i+1].v - points[i].v) / (points[i+1].t - points[i].t)
+	}
+
+	tangents[0] = slopes[0]
+	tangents[n-1] = slopes[n-2]
+	for i := 1; i < n-1; i++ {
+		if slopes[i-1]*slopes[i] <= 0 {
+			tangents[i] = 0
+		} else {
+			tangents[i] = (slopes[i-1] + slopes[i]) / 2
+		}
+	}
+
+	for i := 0; i < n-1; i++ {
+		if slopes[i] == 0 {
+			tangents[i], tangents[i+1] = 0, 0
+			continue
+		}
+		alpha := tangents[i] / slopes[i]
+		beta := tangents[i+1] / slopes[i]
+		if sum := alpha*alpha + beta*beta; sum > 9 {
+			tau := 3 / math.Sqrt(sum)
+			tangents[i] = tau * alpha * slopes[i]
+			tangents[i+1] = tau * beta * slopes[i]
+		}
+	}
+	return tangents
+}
+
+// smoothValues применяет выбранное сглаживание к равномерным отсчётам.
+// Фильтр нижних частот может добавить отсчёты после последнего.
+func smoothValues(values []float64, opts ResampleOptions, step float64) []float64 {
+	switch opts.Smoothing {
+	case SmoothMovingAverage:
+		half := opts.Window / 2
+		if half < 1 {
+			return values
+		}
+		result := make([]float64, len(values))
+		for i := range values {
+			from, to := i-half, i+half
+			if from < 0 {
+				from = 0
+			}
+			if to > len(values)-1 {
+				to = len(values) - 1
+			}
+			sum := 0.0
+			for j := from; j <= to; j++ {
+				sum += values[j]
+			}
+			result[i] = sum / float64(to-from+1)
+		}
+		return result
+	case SmoothLowPass:
+		if opts.Cutoff <= 0 || len(values) == 0 {
+			return values
+		}
+		rc := 1 / (2 * math.Pi * opts.Cutoff)
+		alpha := step / (rc + step)
+		result := make([]float64, len(values))
+		result[0] = values[0]
+		for i := 1; i < len(values); i++ {
+			result[i] = result[i-1] + alpha*(values[i]-result[i-1])
+		}
+		// Фильтр лишь приближается к последнему значению: отсчёты
+		// продолжаются после конца оси, пока до него не останется меньше
+		// единицы, чтобы ось остановилась там же, где в исходной записи
+		target := values[len(values)-1]
+		for last := result[len(result)-1]; math.Abs(target-last) >= 1; {
+			last += alpha * (target - last)
+			result = append(result, last)
+		}
+		return result
+	default:
+		return values
+	}
+}
@@ -0,0 +1,164 @@
+package parser
+
+import (
+	"math"
+	"testing"
+)
+
+// axisValues собирает значения оси по времени
+func axisValues(events []Event, code int) map[float64]int {
+	values := make(map[float64]int)
+	for _, event := range events {
+		if event.TypeNum() == EvAbs && event.CodeNum() == code {
+			values[math.Round(event.Timestamp*1e6)/1e6] = event.IntValue()
+		}
+	}
+	return values
+}
+
+// TestResampleLinear тестирует линейную передискретизацию оси
+func TestResampleLinear(t *testing.T) {
+	file := &EvemuFile{
+		Header: testDeviceHeader,
+		Events: []Event{
+			NewEvent(0.0, EvAbs, 0x00, 0),
+			NewEvent(0.0, EvSyn, SynReport, 0),
+			NewEvent(0.013, EvAbs, 0x00, 1300),
+			NewEvent(0.013, EvKey, 0x130, 1),
+			NewEvent(0.013, EvSyn, SynReport, 0),
+			NewEvent(0.027, EvAbs, 0x00, 2700),
+			NewEvent(0.027, EvSyn, SynReport, 0),
+			NewEvent(0.040, EvAbs, 0x00, 4000),
+			NewEvent(0.040, EvSyn, SynReport, 0),
+		},
+	}
+
+	result, err := file.Resample(ResampleOptions{Rate: 100})
+	if err != nil {
+		t.Fatalf("Resample() failed: %v", err)
+	}
+
+	values := axisValues(result.Events, 0x00)
+	expected := map[float64]int{0.0: 0, 0.01: 1000, 0.02: 2000, 0.03: 3000, 0.04: 4000}
+	if len(values) != len(expected) {
+		t.Fatalf("Expected %d samples, got %v", len(expected), values)
+	}
+	for time, value := range expected {
+		if values[time] != value {
+			t.Errorf("At %.2f: expected %d, got %d", time, value, values[time])
+		}
+	}
+
+	// Нажатие кнопки сохранилось в своём кадре
+	if got := findKeyEvent(result.Events, 0x130, 1, 0); got != 0.013 {
+		t.Errorf("Key press moved to %f", got)
+	}
+
+	for i := 1; i < len(result.Events); i++ {
+		if result.Events[i].Timestamp < result.Events[i-1].Timestamp {
+			t.Fatalf("Events out of order at %d", i)
+		}
+	}
+	if !result.Events[len(result.Events)-1].IsSynReport() {
+		t.Error("Stream should end with SYN_REPORT")
+	}
+}
+
+// TestResampleHoldAndClamp тестирует удержание значения на паузах и ограничение диапазоном
+func TestResampleHoldAndClamp(t *testing.T) {
+	file := &EvemuFile{
+		Header: testDeviceHeader,
+		Events: []Event{
+			NewEvent(0.0, EvAbs, 0x02, 0),
+			NewEvent(0.0, EvSyn, SynReport, 0),
+			NewEvent(0.5, EvAbs, 0x02, 255),
+			NewEvent(0.5, EvSyn, SynReport, 0),
+			NewEvent(0.6, EvAbs, 0x02, 255),
+			NewEvent(0.6, EvSyn, SynReport, 0),
+		},
+	}
+
+	result, err := file.Resample(ResampleOptions{Rate: 100, Interpolation: InterpolateCubic})
+	if err != nil {
+		t.Fatalf("Resample() failed: %v", err)
+	}
+
+	values := axisValues(result.Events, 0x02)
+	if values[0.48] != 0 {
+		t.Errorf("Value should be held during pause, got %d at 0.48", values[0.48])
+	}
+	if values[0.5] != 255 {
+		t.Errorf("Expected 255 at 0.5, got %d", values[0.5])
+	}
+	for time, value := range values {
+		if value < 0 || value > 255 {
+			t.Errorf("Value %d at %.2f outside of A: range", value, time)
+		}
+	}
+}
+
+// TestResampleSkipsHats тестирует, что крестовина по умолчанию не обрабатывается
+func TestResampleSkipsHats(t *testing.T) {
+	file := &EvemuFile{
+		Header: testDeviceHeader,
+		Events: []Event{
+			NewEvent(0.003, EvAbs, 0x10, -1),
+			NewEvent(0.003, EvSyn, SynReport, 0),
+		},
+	}
+
+	result, err := file.Resample(ResampleOptions{Rate: 100})
+	if err != nil {
+		t.Fatalf("Resample() failed: %v", err)
+	}
+	if len(result.Events) != 2 || result.Events[0].Timestamp != 0.003 {
+		t.Errorf("Hat events should be preserved, got %+v", result.Events)
+	}
+
+	if _, err := file.Resample(ResampleOptions{}); err == nil {
+		t.Error("Expected error for zero rate")
+	}
+}
+
+// TestSmoothValues тестирует сглаживание отсчётов
+func TestSmoothValues(t *testing.T) {
+	values := []float64{0, 0, 30, 0, 0}
+
+	averaged := smoothValues(values, ResampleOptions{Smoothing: SmoothMovingAverage, Window: 3}, 0.01)
+	if averaged[1] != 10 || averaged[2] != 10 || averaged[3] != 10 || averaged[0] != 0 {
+		t.Errorf("Unexpected moving average: %v", averaged)
+	}
+
+	filtered := smoothValues(values, ResampleOptions{Smoothing: SmoothLowPass, Cutoff: 5}, 0.01)
+	if filtered[2] <= 0 || filtered[2] >= 30 || filtered[3] <= 0 {
+		t.Errorf("Unexpected low-pass output: %v", filtered)
+	}
+
+	// Фильтр доходит до последнего значения без скачка: шаг не больше
+	// alpha·Δ, последний отсчёт ближе единицы к цели
+	step := []float64{0, 0, 1000, 1000, 1000}
+	filtered = smoothValues(step, ResampleOptions{Smoothing: SmoothLowPass, Cutoff: 5}, 0.01)
+	alpha := 0.01 / (1/(2*math.Pi*5) + 0.01)
+	if len(filtered) <= len(step) || math.Abs(filtered[len(filtered)-1]-1000) >= 1 {
+		t.Errorf("Low-pass tail did not settle: %v", filtered)
+	}
+	for i := 1; i < len(filtered); i++ {
+		if jump := filtered[i] - filtered[i-1]; jump < 0 || jump > alpha*1000+1e-9 {
+			t.Fatalf("Step %d of %.3f is larger than alpha*delta %.3f", i, jump, alpha*1000)
+		}
+	}
+}
+
+// TestResampleEvenWindow тестирует отказ на чётном окне скользящего среднего
+func TestResampleEvenWindow(t *testing.T) {
+	f := &EvemuFile{Header: testDeviceHeader, Events: []Event{
+		NewEvent(0, EvAbs, 0x00, 100),
+		NewEvent(0, EvSyn, SynReport, 0),
+	}}
+	if _, err := f.Resample(ResampleOptions{Rate: 100, Smoothing: SmoothMovingAverage, Window: 4}); err == nil {
+		t.Error("Expected error for even window")
+	}
+	if _, err := f.Resample(ResampleOptions{Rate: 100, Smoothing: SmoothMovingAverage, Window: 5}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"sort"
+)
+
+// RestMode задаёт, какое значение оси считается положением покоя
+type RestMode int
+
+const (
+	RestCenter  RestMode = iota // центр диапазона из строки "A:" (для триггеров - минимум)
+	RestInitial                 // первое записанное значение оси
+)
+
+// SanitizeOptions задаёт параметры приведения контроллера в исходное состояние
+type SanitizeOptions struct {
+	Rest RestMode
+}
+
+// triggerAxes - оси курков и педалей, которые покоятся в минимуме:
+// ABS_Z, ABS_RZ, ABS_GAS и ABS_BRAKE
+var triggerAxes = map[int]bool{0x02: true, 0x05: true, 0x09: true, 0x0a: true}
+
+// Center возвращает середину диапазона оси
+func (a AbsInfo) Center() int {
+	return (a.Min + a.Max) / 2
+}
+
+// Rest возвращает положение покоя оси code: минимум для курков и педалей,
+// середину диапазона для остальных осей, в том числе стиков 0..255
+func (a AbsInfo) Rest(code int) int {
+	if triggerAxes[code] {
+		return a.Min
+	}
+	return a.Center()
+}
+
+// Sanitize отслеживает состояние контроллера и добавляет в конец записи кадр,
+// отпускающий удерживаемые кнопки и возвращающий оси в положение покоя
+func (f *EvemuFile) Sanitize(opts SanitizeOptions) *EvemuFile {
+	if len(f.Events) == 0 {
+		return f
+	}
+
+	events := append([]Event{}, f.Events...)
+	timestamp := events[len(events)-1].Timestamp
+	events = append(events, restEvents(f.Events, timestamp, ParseDescriptor(f.Header), opts)...)
+
+	return &EvemuFile{Header: f.Header, Events: events, Comments: f.Comments}
+}
+
+// restEvents формирует кадр, возвращающий контроллер в исходное состояние
+// после последовательности events. Если всё уже в покое, возвращает nil.
+func restEvents(events []Event, timestamp float64, descriptor *Descriptor, opts SanitizeOptions) []Event {
+	initial := make(map[int]int)
+	current := make(map[int]int)
+	for _, event := range events {
+		if event.TypeNum() != EvAbs {
+			continue
+		}
+		code := event.CodeNum()
+		if _, ok := initial[code]; !ok {
+			initial[code] = event.IntValue()
+		}
+		current[code] = event.IntValue()
+	}
+
+	var codes []int
+	for code := range current {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	var result []Event
+	for _, code := range heldKeys(events) {
+		result = append(result, NewEvent(timestamp, EvKey, code, 0))
+	}
+	for _, code := range codes {
+		rest := initial[code]
+		if info, ok := descriptor.AbsRange(code); ok && opts.Rest == RestCenter {
+			rest = info.Rest(code)
+		}
+		if current[code] != rest {
+			result = append(result, NewEvent(timestamp, EvAbs, code, rest))
+		}
+	}
+
+	if len(result) == 0 {
+		return nil
+	}
+	return append(result, NewEvent(timestamp, EvSyn, SynReport, 0))
+}
@@ -0,0 +1,144 @@
+package parser
+
+import (
+	"testing"
+)
+
+// newSanitizeTestFile создаёт запись, остановленную с нажатой кнопкой и отклонённым стиком
+func newSanitizeTestFile() *EvemuFile {
+	return &EvemuFile{
+		Header: testDeviceHeader,
+		Events: []Event{
+			NewEvent(0.0, EvAbs, 0x00, 120),
+			NewEvent(0.0, EvSyn, SynReport, 0),
+			NewEvent(0.1, EvKey, 0x130, 1),
+			NewEvent(0.1, EvAbs, 0x00, 20000),
+			NewEvent(0.1, EvAbs, 0x02, 200),
+			NewEvent(0.1, EvSyn, SynReport, 0),
+			NewEvent(0.2, EvKey, 0x131, 1),
+			NewEvent(0.2, EvKey, 0x131, 0),
+			NewEvent(0.2, EvSyn, SynReport, 0),
+		},
+	}
+}
+
+// TestSanitizeCenter тестирует возврат осей в центр диапазона из заголовка
+func TestSanitizeCenter(t *testing.T) {
+	file := newSanitizeTestFile()
+	result := file.Sanitize(SanitizeOptions{Rest: RestCenter})
+
+	tail := result.Events[len(file.Events):]
+	if len(tail) != 4 {
+		t.Fatalf("Expected 4 appended events, got %+v", tail)
+	}
+
+	expected := []Event{
+		NewEvent(0.2, EvKey, 0x130, 0),
+		NewEvent(0.2, EvAbs, 0x00, 0),
+		NewEvent(0.2, EvAbs, 0x02, 0),
+		NewEvent(0.2, EvSyn, SynReport, 0),
+	}
+	for i, event := range tail {
+		if event != expected[i] {
+			t.Errorf("Event %d: expected %+v, got %+v", i, expected[i], event)
+		}
+	}
+
+	// Исходная запись не изменяется
+	if len(file.Events) != 9 {
+		t.Error("Sanitize() modified the source file")
+	}
+}
+
+// TestSanitizeUnsignedStick тестирует центр стика с диапазоном 0..255
+// (DualShock 4 через hid-sony): в покой стик возвращается в середину
+// диапазона, а курок - в минимум
+func TestSanitizeUnsignedStick(t *testing.T) {
+	var header []string
+	for _, line := range testDeviceHeader {
+		if line == "A: 00 -32768 32767 16 128 0\n" {
+			line = "A: 00 0 255 0 0 0\n"
+		}
+		header = append(header, line)
+	}
+	file := &EvemuFile{
+		Header: header,
+		Events: []Event{
+			NewEvent(0.0, EvAbs, 0x00, 255),
+			NewEvent(0.0, EvAbs, 0x05, 180),
+			NewEvent(0.0, EvSyn, SynReport, 0),
+		},
+	}
+
+	tail := file.Sanitize(SanitizeOptions{Rest: RestCenter}).Events[len(file.Events):]
+	expected := []Event{
+		NewEvent(0.0, EvAbs, 0x00, 127),
+		NewEvent(0.0, EvAbs, 0x05, 0),
+		NewEvent(0.0, EvSyn, SynReport, 0),
+	}
+	if len(tail) != len(expected) {
+		t.Fatalf("Expected %d appended events, got %+v", len(expected), tail)
+	}
+	for i, event := range tail {
+		if event != expected[i] {
+			t.Errorf("Event %d: expected %+v, got %+v", i, expected[i], event)
+		}
+	}
+}
+
+// TestSanitizeInitial тестирует возврат осей к первому записанному значению
+func TestSanitizeInitial(t *testing.T) {
+	file := newSanitizeTestFile()
+	result := file.Sanitize(SanitizeOptions{Rest: RestInitial})
+
+	last := result.Events[len(result.Events)-2]
+	if last.TypeNum() != EvAbs || last.CodeNum() != 0x00 || last.IntValue() != 120 {
+		t.Errorf("Expected ABS_X back to 120, got %+v", last)
+	}
+
+	// Ось ABS_Z записана один раз - её начальное значение совпадает с текущим
+	for _, event := range result.Events[len(file.Events):] {
+		if event.TypeNum() == EvAbs && event.CodeNum() == 0x02 {
+			t.Errorf("ABS_Z should already be at rest, got %+v", event)
+		}
+	}
+}
+
+// TestSanitizeAtRest тестирует, что запись в покое не изменяется
+func TestSanitizeAtRest(t *testing.T) {
+	file := &EvemuFile{
+		Header: testDeviceHeader,
+		Events: []Event{
+			NewEvent(0.0, EvKey, 0x130, 1),
+			NewEvent(0.0, EvSyn, SynReport, 0),
+			NewEvent(0.1, EvKey, 0x130, 0),
+			NewEvent(0.1, EvSyn, SynReport, 0),
+		},
+	}
+
+	result := file.Sanitize(SanitizeOptions{})
+	if len(result.Events) != len(file.Events) {
+		t.Errorf("Expected no appended events, got %d", len(result.Events)-len(file.Events))
+	}
+}
+
+// TestRepeatSanitizeSeams тестирует возврат в покой на каждом стыке повторов
+func TestRepeatSanitizeSeams(t *testing.T) {
+	file := newSanitizeTestFile()
+	result := file.Repeat(RepeatOptions{Count: 3, Sanitize: &SanitizeOptions{Rest: RestCenter}})
+
+	releases := 0
+	for _, event := range result.Events {
+		if event.TypeNum() == EvKey && event.CodeNum() == 0x130 && event.IntValue() == 0 {
+			releases++
+		}
+	}
+	if releases != 3 {
+		t.Errorf("Expected 3 releases of BTN_SOUTH, got %d", releases)
+	}
+
+	last := result.Events[len(result.Events)-1].Timestamp
+	if last < 0.5999 || last > 0.6001 {
+		t.Errorf("Sanitizing should not change the period, last timestamp %f", last)
+	}
+}
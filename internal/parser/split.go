@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// SplitOptions задаёт способ разбиения записи на фрагменты
+type SplitOptions struct {
+	IdleGap   float64 // разрезать на паузах длиннее IdleGap секунд
+	Delimiter int     // код кнопки-разделителя (используется, если Every > 0)
+	Every     int     // начинать новый фрагмент с каждого Every-го нажатия разделителя
+
+	// Sanitize, если задан, возвращает контроллер в покой в конце каждого фрагмента
+	Sanitize *SanitizeOptions
+}
+
+// Segment описывает фрагмент записи
+type Segment struct {
+	Start   float64    // время начала в исходной записи
+	End     float64    // время окончания в исходной записи
+	File    *EvemuFile // фрагмент с исходным заголовком и временем от нуля
+	Buttons []int      // кнопки, нажимавшиеся во фрагменте
+}
+
+// Split разбивает запись на фрагменты по паузам или по нажатиям кнопки-разделителя.
+// Кадры, содержащие только SYN_REPORT, в фрагменты не попадают.
+func (f *EvemuFile) Split(opts SplitOptions) ([]Segment, error) {
+	if opts.Every <= 0 && opts.IdleGap <= 0 {
+		return nil, fmt.Errorf("не задан способ разбиения: пауза или кнопка-разделитель")
+	}
+
+	var groups [][]Frame
+	var current []Frame
+	lastActive := 0.0
+	presses := 0
+	for _, frame := range f.Frames() {
+		if !frameHasPayload(frame) {
+			continue
+		}
+
+		cut := false
+		if opts.Every > 0 {
+			for _, event := range frame.Events {
+				if event.TypeNum() == EvKey && event.CodeNum() == opts.Delimiter && event.IntValue() == 1 {
+					cut = cut || (presses > 0 && presses%opts.Every == 0)
+					presses++
+				}
+			}
+		} else {
+			cut = len(current) > 0 && frame.Timestamp()-lastActive > opts.IdleGap
+		}
+
+		if cut && len(current) > 0 {
+			groups = append(groups, current)
+			current = nil
+		}
+		current = append(current, frame)
+		lastActive = frame.Events[len(frame.Events)-1].Timestamp
+	}
+	if len(current) > 0 {
+		groups = append(groups, current)
+	}
+
+	segments := make([]Segment, 0, len(groups))
+	for _, group := range groups {
+		segments = append(segments, f.newSegment(group, opts.Sanitize))
+	}
+	return segments, nil
+}
+
+// newSegment собирает фрагмент из кадров, сдвигая время к нулю
+func (f *EvemuFile) newSegment(frames []Frame, sanitize *SanitizeOptions) Segment {
+	start := frames[0].Timestamp()
+	last := frames[len(frames)-1]
+	end := last.Events[len(last.Events)-1].Timestamp
+
+	file := &EvemuFile{Header: f.Header}
+	pressed := make(map[int]bool)
+	for _, frame := range frames {
+		for _, event := range frame.Events {
+			if event.TypeNum() == EvKey && event.IntValue() == 1 {
+				pressed[event.CodeNum()] = true
+			}
+			event.Timestamp -= start
+			file.Events = append(file.Events, event)
+		}
+	}
+	for _, comment := range f.Comments {
+		if comment.Timestamp >= start && comment.Timestamp <= end {
+			file.Comments = append(file.Comments, Comment{Timestamp: comment.Timestamp - start, Text: comment.Text})
+		}
+	}
+	if sanitize != nil {
+		file = file.Sanitize(*sanitize)
+	}
+
+	var buttons []int
+	for code := range pressed {
+		buttons = append(buttons, code)
+	}
+	sort.Ints(buttons)
+
+	return Segment{Start: start, End: end, File: file, Buttons: buttons}
+}
+
+// frameHasPayload проверяет, содержит ли кадр события кроме SYN
+func frameHasPayload(frame Frame) bool {
+	for _, event := range frame.Events {
+		if event.TypeNum() != EvSyn {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitIndexSuffix - окончание имени файла индекса фрагментов
+const SplitIndexSuffix = ".tsv"
+
+// WriteSplitIndex записывает индекс фрагментов в формате TSV:
+// имя файла, начало, конец, длительность и нажимавшиеся кнопки
+func WriteSplitIndex(w io.Writer, segments []Segment, names []string) error {
+	writer := bufio.NewWriter(w)
+	writer.WriteString("# file\tstart\tend\tduration\tbuttons\n")
+	for i, segment := range segments {
+		var buttons []string
+		for _, code := range segment.Buttons {
+			buttons = append(buttons, CodeLabel(EvKey, code))
+		}
+		writer.WriteString(fmt.Sprintf("%s\t%.6f\t%.6f\t%.6f\t%s\n",
+			names[i], segment.Start, segment.End, segment.End-segment.Start, strings.Join(buttons, ",")))
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// newSplitTestFile создаёт запись из трёх действий, разделённых паузами
+func newSplitTestFile() *EvemuFile {
+	return &EvemuFile{
+		Header: []string{"# Test header\n"},
+		Events: []Event{
+			NewEvent(1.0, EvKey, 0x130, 1),
+			NewEvent(1.0, EvSyn, SynReport, 0),
+			NewEvent(1.2, EvKey, 0x130, 0),
+			NewEvent(1.2, EvSyn, SynReport, 0),
+			NewEvent(2.0, EvSyn, SynReport, 0),
+			NewEvent(4.0, EvKey, 0x131, 1),
+			NewEvent(4.0, EvSyn, SynReport, 0),
+			NewEvent(4.1, EvKey, 0x130, 1),
+			NewEvent(4.1, EvSyn, SynReport, 0),
+			NewEvent(4.3, EvKey, 0x131, 0),
+			NewEvent(4.3, EvKey, 0x130, 0),
+			NewEvent(4.3, EvSyn, SynReport, 0),
+			NewEvent(8.0, EvKey, 0x130, 1),
+			NewEvent(8.0, EvSyn, SynReport, 0),
+		},
+		Comments: []Comment{{Timestamp: 4.1, Text: "# marker: combo"}},
+	}
+}
+
+// TestSplitByIdle тестирует разбиение по паузам
+func TestSplitByIdle(t *testing.T) {
+	segments, err := newSplitTestFile().Split(SplitOptions{IdleGap: 1})
+	if err != nil {
+		t.Fatalf("Split() failed: %v", err)
+	}
+	if len(segments) != 3 {
+		t.Fatalf("Expected 3 segments, got %d", len(segments))
+	}
+
+	second := segments[1]
+	if second.Start != 4.0 || second.End != 4.3 {
+		t.Errorf("Unexpected boundaries: %.3f-%.3f", second.Start, second.End)
+	}
+	if len(second.Buttons) != 2 || second.Buttons[0] != 0x130 || second.Buttons[1] != 0x131 {
+		t.Errorf("Unexpected buttons: %v", second.Buttons)
+	}
+	if second.File.Events[0].Timestamp != 0 || len(second.File.Events) != 7 {
+		t.Errorf("Segment should start at zero with 7 events, got %+v", second.File.Events)
+	}
+	if len(second.File.Comments) != 1 || second.File.Comments[0].Timestamp < 0.0999 || second.File.Comments[0].Timestamp > 0.1001 {
+		t.Errorf("Unexpected segment comments: %+v", second.File.Comments)
+	}
+	if second.File.Header[0] != "# Test header\n" {
+		t.Error("Segment should keep the original header")
+	}
+
+	// Кадр, состоящий только из SYN_REPORT, отбрасывается
+	if len(segments[0].File.Events) != 4 {
+		t.Errorf("Expected 4 events in the first segment, got %d", len(segments[0].File.Events))
+	}
+}
+
+// TestSplitByDelimiter тестирует разбиение по нажатиям кнопки-разделителя
+func TestSplitByDelimiter(t *testing.T) {
+	file := newSplitTestFile()
+
+	segments, err := file.Split(SplitOptions{Delimiter: 0x130, Every: 1})
+	if err != nil {
+		t.Fatalf("Split() failed: %v", err)
+	}
+	if len(segments) != 3 || segments[1].Start != 4.1 || segments[2].Start != 8.0 {
+		t.Errorf("Unexpected segments: %+v", segments)
+	}
+
+	segments, err = file.Split(SplitOptions{Delimiter: 0x130, Every: 2})
+	if err != nil {
+		t.Fatalf("Split() failed: %v", err)
+	}
+	if len(segments) != 2 || segments[1].Start != 8.0 {
+		t.Errorf("Unexpected segments: %+v", segments)
+	}
+
+	if _, err := file.Split(SplitOptions{}); err == nil {
+		t.Error("Expected error without split mode")
+	}
+}
+
+// TestSplitSanitize тестирует очистку фрагментов
+func TestSplitSanitize(t *testing.T) {
+	segments, err := newSplitTestFile().Split(SplitOptions{Delimiter: 0x131, Every: 1, Sanitize: &SanitizeOptions{}})
+	if err != nil {
+		t.Fatalf("Split() failed: %v", err)
+	}
+	for i, segment := range segments {
+		if held := heldKeys(segment.File.Events); len(held) != 0 {
+			t.Errorf("Segment %d ends with held keys: %v", i, held)
+		}
+	}
+}
+
+// TestWriteSplitIndex тестирует запись индекса фрагментов
+func TestWriteSplitIndex(t *testing.T) {
+	segments, _ := newSplitTestFile().Split(SplitOptions{IdleGap: 1})
+
+	var buf bytes.Buffer
+	names := []string{"a-001.txt", "a-002.txt", "a-003.txt"}
+	if err := WriteSplitIndex(&buf, segments, names); err != nil {
+		t.Fatalf("WriteSplitIndex() failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected 4 index lines, got %d", len(lines))
+	}
+	if lines[2] != "a-002.txt\t4.000000\t4.300000\t0.300000\tBTN_SOUTH,BTN_EAST" {
+		t.Errorf("Unexpected index line: %q", lines[2])
+	}
+}
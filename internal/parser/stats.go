@@ -0,0 +1,233 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Stats содержит сводку по записи
+type Stats struct {
+	Device      string        `json:"device,omitempty"`
+	Duration    float64       `json:"duration"`
+	Events      int           `json:"events"`
+	Frames      int           `json:"frames"`
+	PollingRate float64       `json:"polling_rate"`
+	Types       []TypeCount   `json:"types"`
+	Codes       []CodeCount   `json:"codes"`
+	Buttons     []ButtonStats `json:"buttons"`
+	Axes        []AxisStats   `json:"axes"`
+}
+
+// TypeCount - количество событий одного типа
+type TypeCount struct {
+	Type  int    `json:"type"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// CodeCount - количество событий одного кода
+type CodeCount struct {
+	Type     int    `json:"type"`
+	TypeName string `json:"type_name"`
+	Code     int    `json:"code"`
+	Name     string `json:"name"`
+	Count    int    `json:"count"`
+}
+
+// ButtonStats - нажатия и длительности удержания кнопки, секунды
+type ButtonStats struct {
+	Code    int     `json:"code"`
+	Name    string  `json:"name"`
+	Presses int     `json:"presses"`
+	MinHold float64 `json:"min_hold"`
+	AvgHold float64 `json:"avg_hold"`
+	MaxHold float64 `json:"max_hold"`
+}
+
+// AxisStats - использованный диапазон оси и её пределы из строки "A:"
+type AxisStats struct {
+	Code     int    `json:"code"`
+	Name     string `json:"name"`
+	Events   int    `json:"events"`
+	Min      int    `json:"min"`
+	Max      int    `json:"max"`
+	Declared bool   `json:"declared"`
+	LimitMin int    `json:"limit_min"`
+	LimitMax int    `json:"limit_max"`
+}
+
+// ComputeStats собирает сводку по записи
+func (f *EvemuFile) ComputeStats() *Stats {
+	descriptor := ParseDescriptor(f.Header)
+	stats := &Stats{
+		Device:  descriptor.Name,
+		Events:  len(f.Events),
+		Types:   []TypeCount{},
+		Codes:   []CodeCount{},
+		Buttons: []ButtonStats{},
+		Axes:    []AxisStats{},
+	}
+	if len(f.Events) == 0 {
+		return stats
+	}
+	stats.Duration = f.Events[len(f.Events)-1].Timestamp - f.Events[0].Timestamp
+
+	frames := f.Frames()
+	stats.Frames = len(frames)
+	stats.PollingRate = estimatePollingRate(frames)
+
+	typeCounts := make(map[int]int)
+	codeCounts := make(map[[2]int]int)
+	buttons := make(map[int]*ButtonStats)
+	holds := make(map[int][]float64)
+	pressedAt := make(map[int]float64)
+	axes := make(map[int]*AxisStats)
+
+	for _, event := range f.Events {
+		typ, code, value := event.TypeNum(), event.CodeNum(), event.IntValue()
+		typeCounts[typ]++
+		codeCounts[[2]int{typ, code}]++
+
+		switch typ {
+		case EvKey:
+			button, ok := buttons[code]
+			if !ok {
+				button = &ButtonStats{Code: code, Name: CodeLabel(EvKey, code)}
+				buttons[code] = button
+			}
+			if value == 1 {
+				if _, held := pressedAt[code]; !held {
+					button.Presses++
+					pressedAt[code] = event.Timestamp
+				}
+			} else if value == 0 {
+				if start, held := pressedAt[code]; held {
+					holds[code] = append(holds[code], event.Timestamp-start)
+					delete(pressedAt, code)
+				}
+			}
+		case EvAbs:
+			axis, ok := axes[code]
+			if !ok {
+				axis = &AxisStats{Code: code, Name: CodeLabel(EvAbs, code), Min: value, Max: value}
+				if info, declared := descriptor.AbsRange(code); declared {
+					axis.Declared, axis.LimitMin, axis.LimitMax = true, info.Min, info.Max
+				}
+				axes[code] = axis
+			}
+			axis.Events++
+			axis.Min = min(axis.Min, value)
+			axis.Max = max(axis.Max, value)
+		}
+	}
+
+	for typ, count := range typeCounts {
+		stats.Types = append(stats.Types, TypeCount{Type: typ, Name: TypeLabel(typ), Count: count})
+	}
+	sort.Slice(stats.Types, func(i, j int) bool { return stats.Types[i].Type < stats.Types[j].Type })
+
+	for key, count := range codeCounts {
+		stats.Codes = append(stats.Codes, CodeCount{
+			Type: key[0], TypeName: TypeLabel(key[0]),
+			Code: key[1], Name: CodeLabel(key[0], key[1]),
+			Count: count,
+		})
+	}
+	sort.Slice(stats.Codes, func(i, j int) bool {
+		if stats.Codes[i].Type != stats.Codes[j].Type {
+			return stats.Codes[i].Type < stats.Codes[j].Type
+		}
+		return stats.Codes[i].Code < stats.Codes[j].Code
+	})
+
+	for code, button := range buttons {
+		if values := holds[code]; len(values) > 0 {
+			button.MinHold, button.MaxHold = values[0], values[0]
+			sum := 0.0
+			for _, hold := range values {
+				button.MinHold = min(button.MinHold, hold)
+				button.MaxHold = max(button.MaxHold, hold)
+				sum += hold
+			}
+			button.AvgHold = sum / float64(len(values))
+		}
+		stats.Buttons = append(stats.Buttons, *button)
+	}
+	sort.Slice(stats.Buttons, func(i, j int) bool { return stats.Buttons[i].Code < stats.Buttons[j].Code })
+
+	for _, axis := range axes {
+		stats.Axes = append(stats.Axes, *axis)
+	}
+	sort.Slice(stats.Axes, func(i, j int) bool { return stats.Axes[i].Code < stats.Axes[j].Code })
+
+	return stats
+}
+
+// estimatePollingRate оценивает частоту опроса по медианному интервалу между кадрами.
+// Паузы длиннее 100 мс не учитываются: в них устройство ничего не сообщало.
+func estimatePollingRate(frames []Frame) float64 {
+	var intervals []float64
+	for i := 1; i < len(frames); i++ {
+		interval := frames[i].Timestamp() - frames[i-1].Timestamp()
+		if interval > 0 && interval <= 0.1 {
+			intervals = append(intervals, interval)
+		}
+	}
+	if len(intervals) == 0 {
+		return 0
+	}
+	sort.Float64s(intervals)
+	return 1 / intervals[len(intervals)/2]
+}
+
+// WriteText записывает сводку в человекочитаемом виде
+func (s *Stats) WriteText(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	if s.Device != "" {
+		fmt.Fprintf(writer, "Устройство:      %s\n", s.Device)
+	}
+	fmt.Fprintf(writer, "Длительность:    %.6f с\n", s.Duration)
+	fmt.Fprintf(writer, "События:         %d\n", s.Events)
+	fmt.Fprintf(writer, "Кадры:           %d\n", s.Frames)
+	fmt.Fprintf(writer, "Частота опроса:  %.1f Гц\n", s.PollingRate)
+
+	fmt.Fprintf(writer, "\nСобытия по типам:\n")
+	for _, t := range s.Types {
+		fmt.Fprintf(writer, "  %-14s %8d\n", t.Name, t.Count)
+	}
+
+	fmt.Fprintf(writer, "\nСобытия по кодам:\n")
+	for _, c := range s.Codes {
+		fmt.Fprintf(writer, "  %-8s %-20s %8d\n", c.TypeName, c.Name, c.Count)
+	}
+
+	if len(s.Buttons) > 0 {
+		fmt.Fprintf(writer, "\nКнопки:              нажатия  мин, с    сред, с   макс, с\n")
+		for _, b := range s.Buttons {
+			fmt.Fprintf(writer, "  %-20s %6d  %8.3f  %8.3f  %8.3f\n", b.Name, b.Presses, b.MinHold, b.AvgHold, b.MaxHold)
+		}
+	}
+
+	if len(s.Axes) > 0 {
+		fmt.Fprintf(writer, "\nОси:                 события  использовано        пределы A:\n")
+		for _, a := range s.Axes {
+			limits := "не объявлены"
+			if a.Declared {
+				limits = fmt.Sprintf("%d..%d", a.LimitMin, a.LimitMax)
+			}
+			fmt.Fprintf(writer, "  %-20s %6d  %-18s  %s\n", a.Name, a.Events, fmt.Sprintf("%d..%d", a.Min, a.Max), limits)
+		}
+	}
+
+	return writer.Flush()
+}
+
+// WriteJSON записывает сводку в формате JSON
+func (s *Stats) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
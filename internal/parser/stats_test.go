@@ -0,0 +1,109 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+// newStatsTestFile создаёт запись с двумя нажатиями A и движением стика
+func newStatsTestFile() *EvemuFile {
+	return &EvemuFile{
+		Header: testDeviceHeader,
+		Events: []Event{
+			NewEvent(1.000, EvKey, 0x130, 1),
+			NewEvent(1.000, EvSyn, SynReport, 0),
+			NewEvent(1.008, EvAbs, 0x00, -1200),
+			NewEvent(1.008, EvSyn, SynReport, 0),
+			NewEvent(1.016, EvAbs, 0x00, 3000),
+			NewEvent(1.016, EvSyn, SynReport, 0),
+			NewEvent(1.100, EvKey, 0x130, 0),
+			NewEvent(1.100, EvSyn, SynReport, 0),
+			NewEvent(1.500, EvKey, 0x130, 1),
+			NewEvent(1.500, EvSyn, SynReport, 0),
+			NewEvent(1.800, EvKey, 0x130, 0),
+			NewEvent(1.800, EvSyn, SynReport, 0),
+			NewEvent(2.000, EvKey, 0x131, 1),
+			NewEvent(2.000, EvSyn, SynReport, 0),
+		},
+	}
+}
+
+// TestComputeStats тестирует сбор сводки по записи
+func TestComputeStats(t *testing.T) {
+	stats := newStatsTestFile().ComputeStats()
+
+	if stats.Device != "Microsoft X-Box 360 pad" || stats.Events != 14 || stats.Frames != 7 {
+		t.Errorf("Unexpected summary: %+v", stats)
+	}
+	if math.Abs(stats.Duration-1.0) > 1e-9 {
+		t.Errorf("Expected duration 1.0, got %f", stats.Duration)
+	}
+	if math.Abs(stats.PollingRate-125) > 0.01 {
+		t.Errorf("Expected polling rate 125 Hz, got %f", stats.PollingRate)
+	}
+
+	if len(stats.Types) != 3 || stats.Types[0].Name != "EV_SYN" || stats.Types[0].Count != 7 {
+		t.Errorf("Unexpected type counts: %+v", stats.Types)
+	}
+	if len(stats.Codes) != 4 || stats.Codes[3].Name != "ABS_X" || stats.Codes[3].Count != 2 {
+		t.Errorf("Unexpected code counts: %+v", stats.Codes)
+	}
+
+	if len(stats.Buttons) != 2 {
+		t.Fatalf("Expected 2 buttons, got %+v", stats.Buttons)
+	}
+	south := stats.Buttons[0]
+	if south.Name != "BTN_SOUTH" || south.Presses != 2 ||
+		math.Abs(south.MinHold-0.1) > 1e-9 || math.Abs(south.MaxHold-0.3) > 1e-9 || math.Abs(south.AvgHold-0.2) > 1e-9 {
+		t.Errorf("Unexpected BTN_SOUTH stats: %+v", south)
+	}
+	if east := stats.Buttons[1]; east.Presses != 1 || east.MaxHold != 0 {
+		t.Errorf("Unreleased press should not have hold time: %+v", east)
+	}
+
+	if len(stats.Axes) != 1 {
+		t.Fatalf("Expected 1 axis, got %+v", stats.Axes)
+	}
+	axis := stats.Axes[0]
+	if axis.Min != -1200 || axis.Max != 3000 || !axis.Declared || axis.LimitMin != -32768 || axis.LimitMax != 32767 {
+		t.Errorf("Unexpected axis stats: %+v", axis)
+	}
+}
+
+// TestStatsOutput тестирует текстовый и JSON вывод сводки
+func TestStatsOutput(t *testing.T) {
+	stats := newStatsTestFile().ComputeStats()
+
+	var text bytes.Buffer
+	if err := stats.WriteText(&text); err != nil {
+		t.Fatalf("WriteText() failed: %v", err)
+	}
+	for _, expected := range []string{"Microsoft X-Box 360 pad", "BTN_SOUTH", "-1200..3000", "-32768..32767"} {
+		if !strings.Contains(text.String(), expected) {
+			t.Errorf("Text output missing %q", expected)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := stats.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() failed: %v", err)
+	}
+	var decoded Stats
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if decoded.Events != 14 || len(decoded.Buttons) != 2 {
+		t.Errorf("Unexpected decoded stats: %+v", decoded)
+	}
+}
+
+// TestComputeStatsEmpty тестирует сводку по пустой записи
+func TestComputeStatsEmpty(t *testing.T) {
+	stats := (&EvemuFile{}).ComputeStats()
+	if stats.Events != 0 || stats.Duration != 0 || stats.Buttons == nil {
+		t.Errorf("Unexpected empty stats: %+v", stats)
+	}
+}
@@ -1,55 +1,115 @@
 package parser
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
+	"strings"
 )
 
-// readFromStdin читает данные из stdin и парсит их как EvemuFile
+// ReadFromStdin читает данные из stdin и парсит их как EvemuFile,
+// определяя формат по содержимому (ReadRecording); данные, сжатые gzip,
+// распаковываются
 func ReadFromStdin() (*EvemuFile, error) {
-	data, err := io.ReadAll(os.Stdin)
+	result, err := ReadRecording(os.Stdin)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка чтения из stdin: %v", err)
 	}
+	return result, nil
+}
 
-	// Создаем временный файл для парсинга
-	tmpfile, err := os.CreateTemp("", "stdin_input")
-	if err != nil {
-		return nil, fmt.Errorf("ошибка создания временного файла: %v", err)
+// WriteToStdout записывает EvemuFile в stdout
+func (file *EvemuFile) WriteToStdout() error {
+	if err := file.Write(os.Stdout); err != nil {
+		return fmt.Errorf("ошибка записи в stdout: %v", err)
 	}
-	defer os.Remove(tmpfile.Name())
+	return nil
+}
 
-	if _, err := tmpfile.Write(data); err != nil {
-		return nil, fmt.Errorf("ошибка записи во временный файл: %v", err)
+// ReadInput читает EvemuFile из файла или из stdin, если путь равен "-".
+// Кроме формата evemu принимаются читаемый вид, вывод evtest, JSON и CSV,
+// файлы, сжатые gzip, распаковываются. Заголовок CSV без преамбулы читается
+// из файла рядом (CSVSidecarPath). Запись из архива указывается как
+// "bundle.zip#имя" (SplitBundlePath).
+func ReadInput(path string) (*EvemuFile, error) {
+	if IsStdio(path) {
+		return ReadFromStdin()
+	}
+	if bundle, clip, ok := SplitBundlePath(path); ok {
+		return ReadBundleClip(bundle, clip)
 	}
 
-	if err := tmpfile.Close(); err != nil {
-		return nil, fmt.Errorf("ошибка закрытия временного файла: %v", err)
+	if clips := bundleClipPaths(path); len(clips) > 0 {
+		return nil, fmt.Errorf("%s - архив записей, укажите запись: %s", path, strings.Join(clips, ", "))
 	}
 
-	return ParseEvemuFile(tmpfile.Name())
-}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия файла: %v", err)
+	}
+	defer file.Close()
+	result, format, err := readRecording(file)
+	if err != nil || len(result.Header) > 0 || format != FormatCSV {
+		return result, err
+	}
 
-// writeToStdout записывает EvemuFile в stdout
-func (file *EvemuFile) WriteToStdout() error {
-	// Создаем временный файл для записи
-	tmpfile, err := os.CreateTemp("", "stdout_output")
+	header, err := os.ReadFile(CSVSidecarPath(path))
+	if os.IsNotExist(err) {
+		return result, nil
+	}
 	if err != nil {
-		return fmt.Errorf("ошибка создания временного файла: %v", err)
+		return nil, fmt.Errorf("ошибка открытия заголовка: %v", err)
 	}
-	defer os.Remove(tmpfile.Name())
+	if header, err = Decompress(header); err != nil {
+		return nil, err
+	}
+	sidecar, err := ParseEvemu(bytes.NewReader(header))
+	if err != nil {
+		return nil, err
+	}
+	result.Header = sidecar.Header
+	return result, nil
+}
+
+// WriteOutput записывает EvemuFile в файл или в stdout, если путь равен "-";
+// сжатие - как в WriteReport
+func (file *EvemuFile) WriteOutput(path string, compress bool) error {
+	return WriteReport(path, compress, file.Write)
+}
 
-	if err := file.WriteToFile(tmpfile.Name()); err != nil {
-		return fmt.Errorf("ошибка записи во временный файл: %v", err)
+// WriteOutputFormat записывает EvemuFile в заданном формате в файл или в stdout,
+// если путь равен "-"; сжатие - как в WriteReport
+func (file *EvemuFile) WriteOutputFormat(path, format string, compress bool) error {
+	return WriteReport(path, compress, func(w io.Writer) error {
+		return file.WriteFormat(w, format)
+	})
+}
+
+// WriteReport записывает отчёт функцией write в файл или в stdout, если путь
+// равен "-". Вывод сжимается gzip, если compress или путь оканчивается на .gz.
+func WriteReport(path string, compress bool, write func(w io.Writer) error) error {
+	if compress || HasGzipSuffix(path) {
+		plain := write
+		write = func(w io.Writer) error {
+			return writeCompressed(w, plain)
+		}
+	}
+	if IsStdio(path) {
+		return write(os.Stdout)
 	}
 
-	// Читаем и выводим содержимое
-	data, err := os.ReadFile(tmpfile.Name())
+	file, err := os.Create(path)
 	if err != nil {
-		return fmt.Errorf("ошибка чтения временного файла: %v", err)
+		return fmt.Errorf("ошибка создания файла: %v", err)
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	// Ошибка сброса на диск проявляется только при закрытии
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("ошибка записи файла: %v", err)
 	}
-
-	fmt.Print(string(data))
 	return nil
 }
@@ -148,3 +148,24 @@ func TestIsStdio(t *testing.T) {
 		})
 	}
 }
+
+// TestWriteReport тестирует запись отчёта в файл
+func TestWriteReport(t *testing.T) {
+	path := t.TempDir() + "/report.txt"
+	err := WriteReport(path, false, func(w io.Writer) error {
+		_, err := io.WriteString(w, "report\n")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WriteReport() failed: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil || string(content) != "report\n" {
+		t.Errorf("Unexpected report content: %q, %v", content, err)
+	}
+
+	if err := WriteReport("/invalid/path/report.txt", false, func(io.Writer) error { return nil }); err == nil {
+		t.Error("WriteReport should return error for invalid path")
+	}
+}
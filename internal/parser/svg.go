@@ -0,0 +1,162 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// Размеры элементов SVG-шкалы в пикселях
+const (
+	svgMargin      = 10
+	svgLabelWidth  = 130
+	svgRulerHeight = 28
+	svgNoteHeight  = 18
+	svgButtonLane  = 22
+	svgAxisLane    = 64
+)
+
+// DefaultSVGWidth - ширина области графика по умолчанию, пиксели
+const DefaultSVGWidth = 1200
+
+// SVGOptions задаёт параметры экспорта шкалы в SVG
+type SVGOptions struct {
+	Width int // ширина области графика в пикселях (0 - DefaultSVGWidth)
+}
+
+// WriteSVG рисует запись как временную шкалу: линейка в секундах,
+// по дорожке на каждую кнопку с полосами нажатий и на каждую ось
+// с кривой значения в пределах из строк "A:", вертикальные отметки
+// маркеров и комментариев.
+func (f *EvemuFile) WriteSVG(w io.Writer, opts SVGOptions) error {
+	width := opts.Width
+	if width <= 0 {
+		width = DefaultSVGWidth
+	}
+	timeline := f.Timeline()
+
+	duration := timeline.Duration()
+	if duration <= 0 {
+		duration = 1
+	}
+	plotLeft := float64(svgMargin + svgLabelWidth)
+	x := func(t float64) float64 {
+		return plotLeft + (t-timeline.Start)/duration*float64(width)
+	}
+
+	lanesTop := svgMargin + svgRulerHeight + svgNoteHeight
+	lanesHeight := len(timeline.Buttons)*svgButtonLane + len(timeline.Axes)*svgAxisLane
+	totalWidth := svgMargin*2 + svgLabelWidth + width
+	totalHeight := lanesTop + lanesHeight + svgMargin
+
+	writer := bufio.NewWriter(w)
+	fmt.Fprintf(writer, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11">`+"\n",
+		totalWidth, totalHeight, totalWidth, totalHeight)
+	if name := ParseDescriptor(f.Header).Name; name != "" {
+		fmt.Fprintf(writer, "<title>%s</title>\n", svgEscape(name))
+	}
+	fmt.Fprintf(writer, `<rect width="%d" height="%d" fill="#ffffff"/>`+"\n", totalWidth, totalHeight)
+
+	// Линейка времени
+	rulerY := svgMargin + svgRulerHeight
+	step := rulerStep(duration)
+	decimals := max(0, int(-math.Floor(math.Log10(step)+1e-9)))
+	fmt.Fprintf(writer, `<g class="ruler" stroke="#999999">`+"\n")
+	fmt.Fprintf(writer, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d"/>`+"\n", plotLeft, rulerY, plotLeft+float64(width), rulerY)
+	for i := 0; float64(i)*step <= duration+1e-9; i++ {
+		tick := float64(i) * step
+		tx := x(timeline.Start + tick)
+		fmt.Fprintf(writer, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d"/>`+"\n", tx, rulerY-6, tx, totalHeight-svgMargin)
+		fmt.Fprintf(writer, `<text x="%.1f" y="%d" stroke="none" fill="#333333" text-anchor="middle">%.*fs</text>`+"\n",
+			tx, rulerY-9, decimals, tick)
+	}
+	fmt.Fprintf(writer, "</g>\n")
+
+	// Дорожки кнопок
+	y := lanesTop
+	for _, lane := range timeline.Buttons {
+		fmt.Fprintf(writer, `<g class="button">`+"\n")
+		fmt.Fprintf(writer, `<text x="%d" y="%d" fill="#333333">%s</text>`+"\n", svgMargin, y+svgButtonLane/2+4, svgEscape(lane.Name))
+		fmt.Fprintf(writer, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#eeeeee"/>`+"\n",
+			plotLeft, y+svgButtonLane, plotLeft+float64(width), y+svgButtonLane)
+		for _, press := range lane.Presses {
+			barWidth := math.Max(x(press.End)-x(press.Start), 1)
+			fmt.Fprintf(writer, `<rect x="%.1f" y="%d" width="%.1f" height="%d" fill="#3b78c4"><title>%s %.3f-%.3f</title></rect>`+"\n",
+				x(press.Start), y+4, barWidth, svgButtonLane-8, svgEscape(lane.Name), press.Start-timeline.Start, press.End-timeline.Start)
+		}
+		fmt.Fprintf(writer, "</g>\n")
+		y += svgButtonLane
+	}
+
+	// Дорожки осей: значение держится до следующего события
+	for _, lane := range timeline.Axes {
+		top, height := float64(y+4), float64(svgAxisLane-8)
+		value := func(v int) float64 {
+			if lane.Max == lane.Min {
+				return top + height/2
+			}
+			return top + height - float64(v-lane.Min)/float64(lane.Max-lane.Min)*height
+		}
+
+		fmt.Fprintf(writer, `<g class="axis">`+"\n")
+		fmt.Fprintf(writer, `<text x="%d" y="%d" fill="#333333">%s</text>`+"\n", svgMargin, y+svgAxisLane/2+4, svgEscape(lane.Name))
+		fmt.Fprintf(writer, `<text x="%d" y="%d" fill="#999999" font-size="9">%d..%d</text>`+"\n", svgMargin, y+svgAxisLane/2+16, lane.Min, lane.Max)
+		fmt.Fprintf(writer, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#eeeeee"/>`+"\n",
+			plotLeft, y+svgAxisLane, plotLeft+float64(width), y+svgAxisLane)
+
+		var path strings.Builder
+		for i, point := range lane.Points {
+			if i == 0 {
+				fmt.Fprintf(&path, "M%.1f %.1f", x(point.Time), value(point.Value))
+				continue
+			}
+			fmt.Fprintf(&path, " H%.1f V%.1f", x(point.Time), value(point.Value))
+		}
+		if len(lane.Points) > 0 {
+			fmt.Fprintf(&path, " H%.1f", x(timeline.End))
+		}
+		fmt.Fprintf(writer, `<path d="%s" fill="none" stroke="#d0582c" stroke-width="1.5"/>`+"\n", path.String())
+		fmt.Fprintf(writer, "</g>\n")
+		y += svgAxisLane
+	}
+
+	// Маркеры и комментарии
+	noteY := svgMargin + svgRulerHeight + svgNoteHeight - 5
+	for _, note := range timeline.Annotations {
+		color := "#888888"
+		if note.Marker {
+			color = "#2a9d4b"
+		}
+		nx := x(note.Time)
+		fmt.Fprintf(writer, `<g class="annotation"><title>%s</title>`, svgEscape(note.Text))
+		fmt.Fprintf(writer, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="%s" stroke-dasharray="4 3"/>`,
+			nx, noteY-10, nx, totalHeight-svgMargin, color)
+		fmt.Fprintf(writer, `<text x="%.1f" y="%d" fill="%s">%s</text></g>`+"\n", nx+3, noteY, color, svgEscape(note.Text))
+	}
+
+	fmt.Fprintf(writer, "</svg>\n")
+	return writer.Flush()
+}
+
+// rulerStep подбирает шаг делений линейки из ряда 1, 2, 5 × 10^n,
+// чтобы на шкале было около десяти делений
+func rulerStep(duration float64) float64 {
+	raw := duration / 10
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, factor := range []float64{1, 2, 5} {
+		if raw <= factor*magnitude {
+			return factor * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// svgEscape экранирует текст для вставки в SVG
+func svgEscape(text string) string {
+	var buf strings.Builder
+	xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestWriteSVG тестирует, что шкала - корректный XML со всеми дорожками
+func TestWriteSVG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newTimelineTestFile().WriteSVG(&buf, SVGOptions{Width: 400}); err != nil {
+		t.Fatalf("WriteSVG failed: %v", err)
+	}
+
+	counts := make(map[string]int)
+	decoder := xml.NewDecoder(bytes.NewReader(buf.Bytes()))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Invalid SVG: %v\n%s", err, buf.String())
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "class" {
+					counts[attr.Value]++
+				}
+			}
+		}
+	}
+	if counts["button"] != 2 || counts["axis"] != 2 || counts["annotation"] != 2 || counts["ruler"] != 1 {
+		t.Errorf("Unexpected lanes: %v", counts)
+	}
+
+	svg := buf.String()
+	for _, want := range []string{
+		`width="550"`,                           // 2*10 + 130 + 400
+		`<rect x="140.0" y="60"`,                // нажатие A с начала области графика
+		`d="M140.0 157.8 H240.0 V116.1 H540.0"`, // ABS_Z: 10 -> 200 в пределах 0..255
+		">0.0s<", ">2.0s<",
+		"combo &lt;start&gt;", "B &amp; hold",
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("SVG does not contain %q:\n%s", want, svg)
+		}
+	}
+}
+
+// TestRulerStep тестирует подбор шага делений линейки
+func TestRulerStep(t *testing.T) {
+	tests := map[float64]float64{1: 0.1, 2: 0.2, 4: 0.5, 7: 1, 30: 5, 0.05: 0.005}
+	for duration, expected := range tests {
+		if step := rulerStep(duration); step < expected*0.999 || step > expected*1.001 {
+			t.Errorf("rulerStep(%v): expected %v, got %v", duration, expected, step)
+		}
+	}
+}
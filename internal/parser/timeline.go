@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+)
+
+// Span - интервал удержания кнопки
+type Span struct {
+	Start float64
+	End   float64
+}
+
+// ButtonLane - дорожка кнопки с интервалами нажатий
+type ButtonLane struct {
+	Code    int
+	Name    string
+	Presses []Span
+}
+
+// AxisPoint - значение оси, действующее с момента Time
+type AxisPoint struct {
+	Time  float64
+	Value int
+}
+
+// AxisLane - дорожка оси. Min и Max берутся из строк "A:" заголовка,
+// а для необъявленных осей - из значений записи.
+type AxisLane struct {
+	Code   int
+	Name   string
+	Min    int
+	Max    int
+	Points []AxisPoint
+}
+
+// Annotation - комментарий (без символа "#") или маркер на временной шкале
+type Annotation struct {
+	Time   float64
+	Text   string
+	Marker bool // Text содержит имя маркера
+}
+
+// Timeline - представление записи в виде дорожек для визуализации
+type Timeline struct {
+	Start       float64
+	End         float64
+	Buttons     []ButtonLane
+	Axes        []AxisLane
+	Annotations []Annotation
+}
+
+// Duration возвращает длительность шкалы в секундах
+func (t *Timeline) Duration() float64 {
+	return t.End - t.Start
+}
+
+// Timeline строит дорожки кнопок и осей. Кнопки, не отпущенные до конца
+// записи, считаются удерживаемыми до последнего события.
+func (f *EvemuFile) Timeline() *Timeline {
+	timeline := &Timeline{}
+	if len(f.Events) == 0 {
+		return timeline
+	}
+	timeline.Start = f.Events[0].Timestamp
+	timeline.End = f.Events[len(f.Events)-1].Timestamp
+
+	descriptor := ParseDescriptor(f.Header)
+	buttons := make(map[int]*ButtonLane)
+	pressedAt := make(map[int]float64)
+	axes := make(map[int]*AxisLane)
+
+	for _, event := range f.Events {
+		code, value := event.CodeNum(), event.IntValue()
+		switch event.TypeNum() {
+		case EvKey:
+			lane, ok := buttons[code]
+			if !ok {
+				lane = &ButtonLane{Code: code, Name: CodeLabel(EvKey, code)}
+				buttons[code] = lane
+			}
+			start, held := pressedAt[code]
+			if value != 0 && !held {
+				pressedAt[code] = event.Timestamp
+			} else if value == 0 && held {
+				lane.Presses = append(lane.Presses, Span{Start: start, End: event.Timestamp})
+				delete(pressedAt, code)
+			}
+		case EvAbs:
+			lane, ok := axes[code]
+			if !ok {
+				lane = &AxisLane{Code: code, Name: CodeLabel(EvAbs, code), Min: value, Max: value}
+				axes[code] = lane
+			}
+			lane.Min = min(lane.Min, value)
+			lane.Max = max(lane.Max, value)
+			if n := len(lane.Points); n > 0 && lane.Points[n-1].Time == event.Timestamp {
+				lane.Points[n-1].Value = value
+			} else {
+				lane.Points = append(lane.Points, AxisPoint{Time: event.Timestamp, Value: value})
+			}
+		}
+	}
+
+	for code, start := range pressedAt {
+		buttons[code].Presses = append(buttons[code].Presses, Span{Start: start, End: timeline.End})
+	}
+	for _, lane := range buttons {
+		timeline.Buttons = append(timeline.Buttons, *lane)
+	}
+	sort.Slice(timeline.Buttons, func(i, j int) bool { return timeline.Buttons[i].Code < timeline.Buttons[j].Code })
+
+	for code, lane := range axes {
+		if info, ok := descriptor.AbsRange(code); ok && info.Min < info.Max {
+			lane.Min, lane.Max = info.Min, info.Max
+		}
+		timeline.Axes = append(timeline.Axes, *lane)
+	}
+	sort.Slice(timeline.Axes, func(i, j int) bool { return timeline.Axes[i].Code < timeline.Axes[j].Code })
+
+	for _, comment := range f.Comments {
+		if name, ok := comment.Marker(); ok {
+			timeline.Annotations = append(timeline.Annotations, Annotation{Time: comment.Timestamp, Text: name, Marker: true})
+		} else {
+			timeline.Annotations = append(timeline.Annotations, Annotation{
+				Time: comment.Timestamp,
+				Text: strings.TrimSpace(strings.TrimPrefix(comment.Text, "#")),
+			})
+		}
+	}
+	return timeline
+}
@@ -0,0 +1,66 @@
+package parser
+
+import "testing"
+
+// newTimelineTestFile создаёт запись с нажатиями, движением оси и маркером
+func newTimelineTestFile() *EvemuFile {
+	return &EvemuFile{
+		Header: testDeviceHeader,
+		Events: []Event{
+			NewEvent(1.0, EvKey, 0x130, 1),
+			NewEvent(1.0, EvAbs, 0x02, 10),
+			NewEvent(1.0, EvSyn, SynReport, 0),
+			NewEvent(1.5, EvKey, 0x130, 0),
+			NewEvent(1.5, EvAbs, 0x02, 200),
+			NewEvent(1.5, EvSyn, SynReport, 0),
+			NewEvent(2.0, EvKey, 0x131, 1),
+			NewEvent(2.0, EvAbs, 0x40, 7),
+			NewEvent(2.0, EvSyn, SynReport, 0),
+			NewEvent(3.0, EvAbs, 0x40, 9),
+			NewEvent(3.0, EvSyn, SynReport, 0),
+		},
+		Comments: []Comment{
+			{Timestamp: 1.0, Text: "# marker: combo <start>"},
+			{Timestamp: 2.0, Text: "# B & hold"},
+		},
+	}
+}
+
+// TestTimeline тестирует построение дорожек записи
+func TestTimeline(t *testing.T) {
+	timeline := newTimelineTestFile().Timeline()
+
+	if timeline.Start != 1.0 || timeline.End != 3.0 || timeline.Duration() != 2.0 {
+		t.Errorf("Unexpected bounds: %f..%f", timeline.Start, timeline.End)
+	}
+
+	if len(timeline.Buttons) != 2 {
+		t.Fatalf("Expected 2 button lanes, got %+v", timeline.Buttons)
+	}
+	south, east := timeline.Buttons[0], timeline.Buttons[1]
+	if south.Name != "BTN_SOUTH" || len(south.Presses) != 1 || south.Presses[0] != (Span{Start: 1.0, End: 1.5}) {
+		t.Errorf("Unexpected BTN_SOUTH lane: %+v", south)
+	}
+	// Не отпущенная кнопка удерживается до конца записи
+	if len(east.Presses) != 1 || east.Presses[0] != (Span{Start: 2.0, End: 3.0}) {
+		t.Errorf("Unexpected BTN_EAST lane: %+v", east)
+	}
+
+	if len(timeline.Axes) != 2 {
+		t.Fatalf("Expected 2 axis lanes, got %+v", timeline.Axes)
+	}
+	z := timeline.Axes[0]
+	if z.Name != "ABS_Z" || z.Min != 0 || z.Max != 255 || len(z.Points) != 2 || z.Points[1] != (AxisPoint{Time: 1.5, Value: 200}) {
+		t.Errorf("Unexpected ABS_Z lane: %+v", z)
+	}
+	// Для необъявленной оси пределы берутся из записи
+	if other := timeline.Axes[1]; other.Min != 7 || other.Max != 9 {
+		t.Errorf("Unexpected undeclared axis lane: %+v", other)
+	}
+
+	if len(timeline.Annotations) != 2 ||
+		timeline.Annotations[0] != (Annotation{Time: 1.0, Text: "combo <start>", Marker: true}) ||
+		timeline.Annotations[1] != (Annotation{Time: 2.0, Text: "B & hold"}) {
+		t.Errorf("Unexpected annotations: %+v", timeline.Annotations)
+	}
+}
@@ -0,0 +1,274 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Severity задаёт важность нарушения правила проверки
+type Severity string
+
+const (
+	SeverityError   Severity = "error"   // запись воспроизводится некорректно
+	SeverityWarning Severity = "warning" // подозрительное место, которое стоит проверить
+	SeverityInfo    Severity = "info"    // замечание, не влияющее на воспроизведение
+	SeverityOff     Severity = "off"     // правило отключено
+)
+
+// Правила проверки записи
+const (
+	RuleTimestamps = "timestamps"  // время событий не убывает
+	RuleSynReport  = "syn-report"  // каждый кадр завершается SYN_REPORT
+	RuleKeyBalance = "key-balance" // нажатия и отпускания кнопок парные
+	RuleAbsRange   = "abs-range"   // значения осей в пределах строк "A:" заголовка
+	RuleUndeclared = "undeclared"  // устройство объявляет код в строках "B:" заголовка
+)
+
+// DefaultSeverities - важность правил по умолчанию
+var DefaultSeverities = map[string]Severity{
+	RuleTimestamps: SeverityError,
+	RuleSynReport:  SeverityError,
+	RuleKeyBalance: SeverityWarning,
+	RuleAbsRange:   SeverityError,
+	RuleUndeclared: SeverityWarning,
+}
+
+// ValidateOptions задаёт параметры проверки записи
+type ValidateOptions struct {
+	Severities map[string]Severity // переопределения важности правил
+}
+
+// severity возвращает важность правила с учётом переопределений
+func (o ValidateOptions) severity(rule string) Severity {
+	if level, ok := o.Severities[rule]; ok {
+		return level
+	}
+	return DefaultSeverities[rule]
+}
+
+// Issue описывает одно нарушение правила. Line - номер строки события
+// в исходном файле (0, если событие отсутствует в файле).
+type Issue struct {
+	Rule      string   `json:"rule"`
+	Severity  Severity `json:"severity"`
+	Line      int      `json:"line"`
+	Timestamp float64  `json:"timestamp"`
+	Message   string   `json:"message"`
+	Fixable   bool     `json:"fixable"`
+}
+
+// ValidationReport содержит результат проверки записи
+type ValidationReport struct {
+	Issues []Issue `json:"issues"`
+}
+
+// HasErrors сообщает, есть ли среди нарушений ошибки
+func (r *ValidationReport) HasErrors() bool {
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate проверяет запись по всем правилам
+func (f *EvemuFile) Validate(opts ValidateOptions) *ValidationReport {
+	issues, _ := validateEvents(f, opts, false)
+	return &ValidationReport{Issues: issues}
+}
+
+// Fix исправляет безопасно устранимые нарушения включённых правил: упорядочивает
+// время, вставляет недостающие SYN_REPORT, ограничивает значения осей диапазоном,
+// убирает повторные нажатия и отпускания (и кадры, в которых кроме них ничего
+// не было) и отпускает кнопки в конце записи.
+// Возвращает исправленную запись и список найденных нарушений.
+func (f *EvemuFile) Fix(opts ValidateOptions) (*EvemuFile, *ValidationReport) {
+	issues, events := validateEvents(f, opts, true)
+	return &EvemuFile{Header: f.Header, Events: events, Comments: f.Comments}, &ValidationReport{Issues: issues}
+}
+
+// validateEvents проходит по событиям, собирая нарушения. При fix возвращает
+// исправленную последовательность событий.
+func validateEvents(f *EvemuFile, opts ValidateOptions, fix bool) ([]Issue, []Event) {
+	descriptor := ParseDescriptor(f.Header)
+	checkCodes := len(descriptor.Bits) > 0
+
+	var issues []Issue
+	report := func(rule string, event Event, fixable bool, format string, args ...any) bool {
+		level := opts.severity(rule)
+		if level == SeverityOff {
+			return false
+		}
+		issues = append(issues, Issue{
+			Rule:      rule,
+			Severity:  level,
+			Line:      event.Line,
+			Timestamp: event.Timestamp,
+			Message:   fmt.Sprintf(format, args...),
+			Fixable:   fixable,
+		})
+		return fix && fixable
+	}
+
+	var result []Event
+	prevTime := math.Inf(-1)
+	var pending *Event // последнее событие незавершённого кадра
+	dropped := false   // из текущего кадра удалены события
+	pressed := make(map[int]Event)
+
+	for _, event := range f.Events {
+		typ, code := event.TypeNum(), event.CodeNum()
+
+		if event.Timestamp < prevTime {
+			if report(RuleTimestamps, event, true, "время %.6f меньше времени предыдущего события %.6f", event.Timestamp, prevTime) {
+				event.Timestamp = prevTime
+			}
+		}
+
+		if pending != nil && typ != EvSyn && event.Timestamp != pending.Timestamp {
+			if report(RuleSynReport, *pending, true, "кадр %.6f не завершён SYN_REPORT", pending.Timestamp) {
+				result = append(result, NewEvent(pending.Timestamp, EvSyn, SynReport, 0))
+			}
+			pending, dropped = nil, false
+		}
+
+		if checkCodes && typ != EvSyn && !descriptor.HasCode(typ, code) {
+			report(RuleUndeclared, event, false, "устройство не объявляет %s %s", TypeLabel(typ), CodeLabel(typ, code))
+		}
+
+		switch typ {
+		case EvKey:
+			press := event.IntValue() == 1
+			_, held := pressed[code]
+			switch {
+			case press && held:
+				if report(RuleKeyBalance, event, true, "повторное нажатие %s без отпускания", CodeLabel(typ, code)) {
+					dropped = true
+					continue
+				}
+			case event.IntValue() == 0 && !held:
+				if report(RuleKeyBalance, event, true, "отпускание %s без нажатия", CodeLabel(typ, code)) {
+					dropped = true
+					continue
+				}
+			case press:
+				pressed[code] = event
+			case event.IntValue() == 0:
+				delete(pressed, code)
+			}
+		case EvAbs:
+			if info, ok := descriptor.AbsRange(code); ok {
+				value := event.IntValue()
+				if clamped := info.Clamp(value); clamped != value {
+					if report(RuleAbsRange, event, true, "значение %s %d вне диапазона [%d, %d]", CodeLabel(typ, code), value, info.Min, info.Max) {
+						event.Value = fmt.Sprintf("%04d", clamped)
+					}
+				}
+			}
+		}
+
+		if event.IsSynReport() {
+			// Кадр, из которого удалены все события, удаляется целиком
+			empty := dropped && frameEmpty(result)
+			pending, dropped = nil, false
+			if empty {
+				prevTime = event.Timestamp
+				continue
+			}
+		} else if typ != EvSyn {
+			last := event
+			pending = &last
+		}
+		result = append(result, event)
+		prevTime = event.Timestamp
+	}
+
+	if pending != nil {
+		if report(RuleSynReport, *pending, true, "кадр %.6f не завершён SYN_REPORT", pending.Timestamp) {
+			result = append(result, NewEvent(pending.Timestamp, EvSyn, SynReport, 0))
+		}
+	}
+
+	var unreleased []Event
+	for _, event := range pressed {
+		unreleased = append(unreleased, event)
+	}
+	sort.Slice(unreleased, func(i, j int) bool {
+		return unreleased[i].Line < unreleased[j].Line
+	})
+	var release []int
+	for _, event := range unreleased {
+		code := event.CodeNum()
+		if report(RuleKeyBalance, event, true, "%s не отпущена до конца записи", CodeLabel(EvKey, code)) {
+			release = append(release, code)
+		}
+	}
+	if len(release) > 0 {
+		sort.Ints(release)
+		result = append(result, releaseEvents(prevTime, release)...)
+	}
+
+	return issues, result
+}
+
+// frameEmpty проверяет, что в незавершённом кадре в конце events нет событий
+func frameEmpty(events []Event) bool {
+	return len(events) == 0 || events[len(events)-1].IsSynReport()
+}
+
+// ParseSeverities разбирает переопределения важности вида "правило:уровень,..."
+func ParseSeverities(value string) (map[string]Severity, error) {
+	result := make(map[string]Severity)
+	if value == "" {
+		return result, nil
+	}
+	for _, item := range strings.Split(value, ",") {
+		rule, level, ok := strings.Cut(strings.TrimSpace(item), ":")
+		if !ok {
+			return nil, fmt.Errorf("ожидается правило:уровень: %s", item)
+		}
+		if _, known := DefaultSeverities[rule]; !known {
+			return nil, fmt.Errorf("неизвестное правило: %s", rule)
+		}
+		switch Severity(level) {
+		case SeverityError, SeverityWarning, SeverityInfo, SeverityOff:
+			result[rule] = Severity(level)
+		default:
+			return nil, fmt.Errorf("неизвестный уровень важности: %s", level)
+		}
+	}
+	return result, nil
+}
+
+// WriteText записывает нарушения в виде "строка: уровень: правило: сообщение"
+func (r *ValidationReport) WriteText(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	counts := make(map[Severity]int)
+	for _, issue := range r.Issues {
+		counts[issue.Severity]++
+		fixable := ""
+		if issue.Fixable {
+			fixable = " (исправимо)"
+		}
+		fmt.Fprintf(writer, "%d: %s: %s: %s%s\n", issue.Line, issue.Severity, issue.Rule, issue.Message, fixable)
+	}
+	fmt.Fprintf(writer, "Ошибок: %d, предупреждений: %d, замечаний: %d\n",
+		counts[SeverityError], counts[SeverityWarning], counts[SeverityInfo])
+	return writer.Flush()
+}
+
+// WriteJSON записывает нарушения в формате JSON
+func (r *ValidationReport) WriteJSON(w io.Writer) error {
+	if r.Issues == nil {
+		r.Issues = []Issue{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
@@ -0,0 +1,188 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// newValidateTestFile разбирает запись с нарушением каждого правила
+func newValidateTestFile(t *testing.T) *EvemuFile {
+	t.Helper()
+	text := strings.Join(testDeviceHeader, "") +
+		"E: 1.000000 0001 0130 0001\n" + // нажатие A
+		"E: 1.000000 0000 0000 0000\n" +
+		"E: 1.010000 0003 0002 0300\n" + // ABS_Z вне диапазона 0..255, нет SYN_REPORT
+		"E: 1.005000 0001 0130 0001\n" + // время назад, повторное нажатие A
+		"E: 1.005000 0000 0000 0000\n" +
+		"E: 1.020000 0001 0131 0000\n" + // отпускание B без нажатия
+		"E: 1.020000 0001 0100 0001\n" + // BTN_0 не объявлена устройством
+		"E: 1.020000 0000 0000 0000\n"
+	f, err := ParseEvemu(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	return f
+}
+
+// TestValidate тестирует обнаружение нарушений и номера строк
+func TestValidate(t *testing.T) {
+	f := newValidateTestFile(t)
+	first := len(testDeviceHeader) + 1
+	report := f.Validate(ValidateOptions{})
+
+	expected := []struct {
+		rule string
+		line int
+	}{
+		{RuleAbsRange, first + 2},
+		{RuleTimestamps, first + 3},
+		{RuleSynReport, first + 2},
+		{RuleKeyBalance, first + 3},
+		{RuleKeyBalance, first + 5},
+		{RuleUndeclared, first + 6},
+		{RuleKeyBalance, first},
+		{RuleKeyBalance, first + 6},
+	}
+	if len(report.Issues) != len(expected) {
+		t.Fatalf("Expected %d issues, got %+v", len(expected), report.Issues)
+	}
+	for i, want := range expected {
+		issue := report.Issues[i]
+		if issue.Rule != want.rule || issue.Line != want.line {
+			t.Errorf("Issue %d: expected %s at line %d, got %+v", i, want.rule, want.line, issue)
+		}
+		if issue.Severity != DefaultSeverities[want.rule] {
+			t.Errorf("Issue %d: unexpected severity %s", i, issue.Severity)
+		}
+	}
+	if !report.HasErrors() {
+		t.Error("Expected errors in report")
+	}
+}
+
+// TestValidateSeverities тестирует переопределение важности правил
+func TestValidateSeverities(t *testing.T) {
+	severities, err := ParseSeverities("abs-range:off,timestamps:warning,syn-report:info")
+	if err != nil {
+		t.Fatalf("Failed to parse severities: %v", err)
+	}
+	report := newValidateTestFile(t).Validate(ValidateOptions{Severities: severities})
+
+	for _, issue := range report.Issues {
+		if issue.Rule == RuleAbsRange {
+			t.Errorf("Disabled rule reported: %+v", issue)
+		}
+		if issue.Rule == RuleTimestamps && issue.Severity != SeverityWarning {
+			t.Errorf("Expected warning for timestamps, got %+v", issue)
+		}
+	}
+	if report.HasErrors() {
+		t.Errorf("Expected no errors, got %+v", report.Issues)
+	}
+
+	for _, value := range []string{"abs-range", "unknown:error", "abs-range:fatal"} {
+		if _, err := ParseSeverities(value); err == nil {
+			t.Errorf("Expected error for %q", value)
+		}
+	}
+}
+
+// TestFix тестирует исправление безопасно устранимых нарушений
+func TestFix(t *testing.T) {
+	fixed, report := newValidateTestFile(t).Fix(ValidateOptions{})
+	if len(report.Issues) != 8 {
+		t.Errorf("Expected 8 issues, got %d", len(report.Issues))
+	}
+
+	expected := []Event{
+		NewEvent(1.000, EvKey, 0x130, 1),
+		NewEvent(1.000, EvSyn, SynReport, 0),
+		NewEvent(1.010, EvAbs, 0x02, 255),
+		NewEvent(1.010, EvSyn, SynReport, 0),
+		NewEvent(1.020, EvKey, 0x100, 1),
+		NewEvent(1.020, EvSyn, SynReport, 0),
+		NewEvent(1.020, EvKey, 0x100, 0),
+		NewEvent(1.020, EvKey, 0x130, 0),
+		NewEvent(1.020, EvSyn, SynReport, 0),
+	}
+	if len(fixed.Events) != len(expected) {
+		t.Fatalf("Expected %d events, got %+v", len(expected), fixed.Events)
+	}
+	for i, event := range fixed.Events {
+		event.Line = 0
+		if event != expected[i] {
+			t.Errorf("Event %d: expected %+v, got %+v", i, expected[i], event)
+		}
+	}
+
+	// В исправленной записи остаются только неисправимые нарушения
+	for _, issue := range fixed.Validate(ValidateOptions{}).Issues {
+		if issue.Fixable {
+			t.Errorf("Fixable issue left after fix: %+v", issue)
+		}
+	}
+}
+
+// TestFixDropsEmptyFrames тестирует, что кадры, из которых удалены все
+// события, не остаются одиночными SYN_REPORT
+func TestFixDropsEmptyFrames(t *testing.T) {
+	text := strings.Join(testDeviceHeader, "") +
+		"E: 1.000000 0001 0130 0001\n" +
+		"E: 1.000000 0000 0000 0000\n" +
+		"E: 1.010000 0001 0130 0001\n" + // повторное нажатие A: кадр пустеет
+		"E: 1.010000 0000 0000 0000\n" +
+		"E: 1.020000 0001 0131 0000\n" + // отпускание B без нажатия и отпускание A
+		"E: 1.020000 0001 0130 0000\n" +
+		"E: 1.020000 0000 0000 0000\n" +
+		"E: 1.030000 0000 0000 0000\n" // пустой кадр исходной записи сохраняется
+	f, err := ParseEvemu(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	fixed, _ := f.Fix(ValidateOptions{})
+	expected := []Event{
+		NewEvent(1.000, EvKey, 0x130, 1),
+		NewEvent(1.000, EvSyn, SynReport, 0),
+		NewEvent(1.020, EvKey, 0x130, 0),
+		NewEvent(1.020, EvSyn, SynReport, 0),
+		NewEvent(1.030, EvSyn, SynReport, 0),
+	}
+	if len(fixed.Events) != len(expected) {
+		t.Fatalf("Expected %d events, got %+v", len(expected), fixed.Events)
+	}
+	for i, event := range fixed.Events {
+		event.Line = 0
+		if event != expected[i] {
+			t.Errorf("Event %d: expected %+v, got %+v", i, expected[i], event)
+		}
+	}
+}
+
+// TestValidationReportOutput тестирует текстовый и JSON-отчёт
+func TestValidationReportOutput(t *testing.T) {
+	report := newValidateTestFile(t).Validate(ValidateOptions{})
+
+	var text bytes.Buffer
+	if err := report.WriteText(&text); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	if !strings.Contains(text.String(), ": error: abs-range: значение ABS_Z 300 вне диапазона [0, 255]") ||
+		!strings.Contains(text.String(), "Ошибок: 3, предупреждений: 5") {
+		t.Errorf("Unexpected text report:\n%s", text.String())
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	var decoded ValidationReport
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(decoded.Issues) != len(report.Issues) || decoded.Issues[0].Line != report.Issues[0].Line {
+		t.Errorf("Unexpected JSON report: %s", buf.String())
+	}
+}
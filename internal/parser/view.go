@@ -0,0 +1,219 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Параметры текстовой шкалы по умолчанию
+const (
+	DefaultViewResolution = 0.05 // секунд на колонку
+	DefaultViewWidth      = 100  // колонок шкалы на странице
+)
+
+// ViewOptions задаёт параметры текстовой шкалы
+type ViewOptions struct {
+	Resolution float64 // секунд на колонку (0 - DefaultViewResolution)
+	Width      int     // колонок на странице (0 - DefaultViewWidth)
+}
+
+// withDefaults подставляет значения по умолчанию
+func (o ViewOptions) withDefaults() ViewOptions {
+	if o.Resolution <= 0 {
+		o.Resolution = DefaultViewResolution
+	}
+	if o.Width <= 0 {
+		o.Width = DefaultViewWidth
+	}
+	return o
+}
+
+// Символы текстовой шкалы
+const (
+	viewPressed  = '█'
+	viewReleased = '·'
+	viewTick     = '+'
+	viewRule     = '-'
+	viewMarker   = '^'
+	viewComment  = '*'
+)
+
+// viewLevels - уровни спарклайна оси снизу вверх
+var viewLevels = []rune("▁▂▃▄▅▆▇█")
+
+// WriteView выводит одну страницу шкалы, начиная с offset секунд от начала
+// записи: строки кнопок с нажатиями, спарклайны осей и отметки маркеров.
+// В колонке оси показывается значение, действующее в конце интервала колонки.
+func (t *Timeline) WriteView(w io.Writer, opts ViewOptions, offset float64) error {
+	opts = opts.withDefaults()
+	writer := bufio.NewWriter(w)
+
+	labelWidth := 10
+	for _, lane := range t.Buttons {
+		labelWidth = max(labelWidth, len(lane.Name))
+	}
+	for _, lane := range t.Axes {
+		labelWidth = max(labelWidth, len(lane.Name))
+	}
+
+	from := t.Start + offset
+	column := func(i int) (float64, float64) {
+		return from + float64(i)*opts.Resolution, from + float64(i+1)*opts.Resolution
+	}
+
+	fmt.Fprintf(writer, "%.3f-%.3f с, %s на колонку\n",
+		offset, offset+float64(opts.Width)*opts.Resolution, formatSeconds(opts.Resolution))
+
+	// Линейка: деление каждые 10 колонок с подписью времени
+	ruler := make([]rune, opts.Width)
+	labels := []rune(strings.Repeat(" ", opts.Width))
+	for i := range ruler {
+		ruler[i] = viewRule
+		if i%10 == 0 {
+			ruler[i] = viewTick
+			label := []rune(formatSeconds(offset + float64(i)*opts.Resolution))
+			if i+len(label) <= opts.Width {
+				copy(labels[i:], label)
+			}
+		}
+	}
+	fmt.Fprintf(writer, "%-*s %s\n", labelWidth, "", string(labels))
+	fmt.Fprintf(writer, "%-*s %s\n", labelWidth, "", string(ruler))
+
+	notes := make([]rune, opts.Width)
+	var listed []Annotation
+	for i := range notes {
+		notes[i] = ' '
+	}
+	for _, note := range t.Annotations {
+		i := int(math.Floor((note.Time - from) / opts.Resolution))
+		if i < 0 || i >= opts.Width {
+			continue
+		}
+		listed = append(listed, note)
+		if note.Marker {
+			notes[i] = viewMarker
+		} else if notes[i] != viewMarker {
+			notes[i] = viewComment
+		}
+	}
+	if len(listed) > 0 {
+		fmt.Fprintf(writer, "%-*s %s\n", labelWidth, "", string(notes))
+	}
+
+	for _, lane := range t.Buttons {
+		row := make([]rune, opts.Width)
+		for i := range row {
+			start, end := column(i)
+			row[i] = viewReleased
+			for _, press := range lane.Presses {
+				if press.Start < end && (press.End > start || press.Start >= start) {
+					row[i] = viewPressed
+					break
+				}
+			}
+		}
+		fmt.Fprintf(writer, "%-*s %s\n", labelWidth, lane.Name, string(row))
+	}
+
+	for _, lane := range t.Axes {
+		row := make([]rune, opts.Width)
+		point := -1
+		for i := range row {
+			begin, end := column(i)
+			for point+1 < len(lane.Points) && lane.Points[point+1].Time < end {
+				point++
+			}
+			switch {
+			case point < 0 || begin > t.End:
+				row[i] = ' '
+			case lane.Max == lane.Min:
+				row[i] = viewLevels[0]
+			default:
+				level := float64(lane.Points[point].Value-lane.Min) / float64(lane.Max-lane.Min)
+				row[i] = viewLevels[int(math.Round(level*float64(len(viewLevels)-1)))]
+			}
+		}
+		fmt.Fprintf(writer, "%-*s %s\n", labelWidth, lane.Name, string(row))
+	}
+
+	for _, note := range listed {
+		symbol := viewComment
+		if note.Marker {
+			symbol = viewMarker
+		}
+		fmt.Fprintf(writer, "  %c %s %s\n", symbol, formatSeconds(note.Time-t.Start), note.Text)
+	}
+	return writer.Flush()
+}
+
+// WriteViewPages выводит всю запись страницами подряд
+func (t *Timeline) WriteViewPages(w io.Writer, opts ViewOptions) error {
+	opts = opts.withDefaults()
+	page := float64(opts.Width) * opts.Resolution
+	for offset := 0.0; ; offset += page {
+		if err := t.WriteView(w, opts, offset); err != nil {
+			return err
+		}
+		if offset+page > t.Duration() {
+			return nil
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+}
+
+// RunViewPager показывает шкалу постранично, читая команды из in:
+// Enter или n - следующая страница, p - предыдущая, + и - - приблизить
+// и отдалить вдвое, g <секунды> - перейти ко времени, q - выход.
+func (t *Timeline) RunViewPager(in io.Reader, out io.Writer, opts ViewOptions) error {
+	opts = opts.withDefaults()
+	scanner := bufio.NewScanner(in)
+	offset := 0.0
+	for {
+		if err := t.WriteView(out, opts, offset); err != nil {
+			return err
+		}
+		fmt.Fprint(out, "[Enter/n] далее  [p] назад  [+/-] масштаб  [g <с>] переход  [q] выход: ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+
+		page := float64(opts.Width) * opts.Resolution
+		command, argument, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		switch command {
+		case "", "n":
+			if offset+page <= t.Duration() {
+				offset += page
+			}
+		case "p":
+			offset = math.Max(0, offset-page)
+		case "+":
+			opts.Resolution /= 2
+		case "-":
+			opts.Resolution *= 2
+		case "g":
+			seconds, err := strconv.ParseFloat(strings.TrimSpace(argument), 64)
+			if err != nil || seconds < 0 {
+				fmt.Fprintf(out, "некорректное время: %s\n", argument)
+				continue
+			}
+			offset = seconds
+		case "q":
+			return nil
+		default:
+			fmt.Fprintf(out, "неизвестная команда: %s\n", command)
+		}
+	}
+}
+
+// formatSeconds форматирует время в секундах без лишних нулей
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(math.Round(seconds*1e6)/1e6, 'f', -1, 64) + "s"
+}
@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestWriteView тестирует отрисовку страницы текстовой шкалы
+func TestWriteView(t *testing.T) {
+	timeline := newTimelineTestFile().Timeline()
+
+	var buf bytes.Buffer
+	if err := timeline.WriteView(&buf, ViewOptions{Resolution: 0.1, Width: 20}, 0); err != nil {
+		t.Fatalf("WriteView failed: %v", err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+
+	expected := []string{
+		"0.000-2.000 с, 0.1s на колонку",
+		"           0s        1s        ",
+		"           +---------+---------",
+		"           ^         *         ",
+		"BTN_SOUTH  █████···············",
+		"BTN_EAST   ··········██████████",
+		"ABS_Z      ▁▁▁▁▁▆▆▆▆▆▆▆▆▆▆▆▆▆▆▆",
+		"0x40                 ▁▁▁▁▁▁▁▁▁▁",
+		"  ^ 0s combo <start>",
+		"  * 1s B & hold",
+	}
+	for i, want := range expected {
+		if i >= len(lines) || lines[i] != want {
+			t.Errorf("Line %d: expected %q, got %q", i, want, lines[i])
+		}
+	}
+}
+
+// TestWriteViewPages тестирует вывод всей записи страницами
+func TestWriteViewPages(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newTimelineTestFile().Timeline().WriteViewPages(&buf, ViewOptions{Resolution: 0.1, Width: 10}); err != nil {
+		t.Fatalf("WriteViewPages failed: %v", err)
+	}
+	// Запись длиной 2 с: страницы 0-1, 1-2 и 2-3 (последнее событие в 2 с)
+	if pages := strings.Count(buf.String(), "на колонку"); pages != 3 {
+		t.Errorf("Expected 3 pages, got %d:\n%s", pages, buf.String())
+	}
+}
+
+// TestRunViewPager тестирует команды постраничного просмотра
+func TestRunViewPager(t *testing.T) {
+	timeline := newTimelineTestFile().Timeline()
+	in := strings.NewReader("n\n+\np\ng 1.5\n-\nx\nq\n")
+
+	var out bytes.Buffer
+	if err := timeline.RunViewPager(in, &out, ViewOptions{Resolution: 0.1, Width: 10}); err != nil {
+		t.Fatalf("RunViewPager failed: %v", err)
+	}
+
+	headers := regexp.MustCompile(`[0-9.]+-[0-9.]+ с, [0-9.]+s на колонку`).FindAllString(out.String(), -1)
+	expected := []string{
+		"0.000-1.000 с, 0.1s на колонку",
+		"1.000-2.000 с, 0.1s на колонку",
+		"1.000-1.500 с, 0.05s на колонку",
+		"0.500-1.000 с, 0.05s на колонку",
+		"1.500-2.000 с, 0.05s на колонку",
+		"1.500-2.500 с, 0.1s на колонку",
+		"1.500-2.500 с, 0.1s на колонку",
+	}
+	if len(headers) != len(expected) {
+		t.Fatalf("Expected %d pages, got %q", len(expected), headers)
+	}
+	for i, want := range expected {
+		if headers[i] != want {
+			t.Errorf("Page %d: expected %q, got %q", i, want, headers[i])
+		}
+	}
+	if !strings.Contains(out.String(), "неизвестная команда: x") {
+		t.Error("Expected unknown command message")
+	}
+}
@@ -0,0 +1,366 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Виды узлов YAML
+const (
+	yamlScalar = iota
+	yamlMap
+	yamlList
+)
+
+// yamlNode - узел документа YAML: скаляр, отображение или список
+type yamlNode struct {
+	Kind  int
+	Value string
+	Keys  []string // ключи отображения в порядке записи
+	Map   map[string]*yamlNode
+	List  []*yamlNode
+	Line  int
+}
+
+// yamlComment - комментарий, занимающий всю строку документа
+type yamlComment struct {
+	Line int
+	Text string
+}
+
+// yamlLine - значимая строка документа без комментария
+type yamlLine struct {
+	indent int
+	text   string
+	number int
+}
+
+// yamlParser разбирает подмножество YAML, которое пишут генераторы вроде
+// libinput record: блочные отображения и списки с отступами пробелами,
+// однострочные списки [a, b] и отображения {a: b}, скаляры в кавычках
+// и без. Якоря, теги и многострочные скаляры не поддерживаются.
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// parseYAML разбирает документ. Комментарии, занимающие всю строку,
+// возвращаются отдельно с номерами строк.
+func parseYAML(data []byte) (*yamlNode, []yamlComment, error) {
+	p := &yamlParser{}
+	var comments []yamlComment
+	for i, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		trimmed := strings.TrimSpace(raw)
+		if trimmed == "" || trimmed == "---" || trimmed == "..." {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			comments = append(comments, yamlComment{Line: i + 1, Text: trimmed})
+			continue
+		}
+		indent := len(raw) - len(strings.TrimLeft(raw, " "))
+		p.lines = append(p.lines, yamlLine{indent: indent, text: stripYAMLComment(trimmed), number: i + 1})
+	}
+
+	if len(p.lines) == 0 {
+		return &yamlNode{Kind: yamlMap, Map: make(map[string]*yamlNode)}, comments, nil
+	}
+	root, err := p.block(p.lines[0].indent)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, nil, fmt.Errorf("строка %d: неожиданный отступ", p.lines[p.pos].number)
+	}
+	return root, comments, nil
+}
+
+// get возвращает значение ключа отображения или nil
+func (n *yamlNode) get(key string) *yamlNode {
+	if n == nil || n.Kind != yamlMap {
+		return nil
+	}
+	return n.Map[key]
+}
+
+// ints возвращает элементы списка целыми числами
+func (n *yamlNode) ints() ([]int, error) {
+	if n == nil || n.Kind != yamlList {
+		return nil, fmt.Errorf("ожидается список чисел")
+	}
+	result := make([]int, len(n.List))
+	for i, item := range n.List {
+		value, err := item.int()
+		if err != nil {
+			return nil, err
+		}
+		result[i] = value
+	}
+	return result, nil
+}
+
+// int возвращает скаляр целым числом. Ведущие нули, как в YAML 1.2, не
+// делают число восьмеричным; допускаются префиксы 0x и 0o.
+func (n *yamlNode) int() (int, error) {
+	if n == nil || n.Kind != yamlScalar {
+		return 0, fmt.Errorf("ожидается число")
+	}
+	if value, err := strconv.Atoi(n.Value); err == nil {
+		return value, nil
+	}
+	value, err := strconv.ParseInt(n.Value, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("строка %d: некорректное число: %s", n.Line, n.Value)
+	}
+	return int(value), nil
+}
+
+// valueOrEmpty возвращает значение скаляра или пустую строку
+func (n *yamlNode) valueOrEmpty() string {
+	if n == nil || n.Kind != yamlScalar {
+		return ""
+	}
+	return n.Value
+}
+
+// block разбирает отображение или список с заданным отступом
+func (p *yamlParser) block(indent int) (*yamlNode, error) {
+	if isYAMLSequenceItem(p.lines[p.pos].text) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+// nested разбирает вложенный блок после строки с отступом parent;
+// если вложенного блока нет, значение пустое
+func (p *yamlParser) nested(parent, line int) (*yamlNode, error) {
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > parent {
+		return p.block(p.lines[p.pos].indent)
+	}
+	return &yamlNode{Kind: yamlScalar, Line: line}, nil
+}
+
+// sequence разбирает блочный список "- элемент"
+func (p *yamlParser) sequence(indent int) (*yamlNode, error) {
+	node := &yamlNode{Kind: yamlList, Line: p.lines[p.pos].number}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent != indent || !isYAMLSequenceItem(line.text) {
+			break
+		}
+		rest := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+
+		var item *yamlNode
+		var err error
+		switch {
+		case rest == "":
+			p.pos++
+			item, err = p.nested(indent, line.number)
+		case isYAMLSequenceItem(rest) || isYAMLMappingEntry(rest):
+			// "- ключ: значение" открывает блок с отступом содержимого
+			inner := indent + len(line.text) - len(rest)
+			p.lines[p.pos] = yamlLine{indent: inner, text: rest, number: line.number}
+			item, err = p.block(inner)
+		default:
+			p.pos++
+			item, err = parseYAMLValue(rest, line.number)
+		}
+		if err != nil {
+			return nil, err
+		}
+		node.List = append(node.List, item)
+	}
+	return node, nil
+}
+
+// mapping разбирает блочное отображение "ключ: значение"
+func (p *yamlParser) mapping(indent int) (*yamlNode, error) {
+	node := &yamlNode{Kind: yamlMap, Map: make(map[string]*yamlNode), Line: p.lines[p.pos].number}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent || (line.indent == indent && isYAMLSequenceItem(line.text)) {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("строка %d: неожиданный отступ", line.number)
+		}
+		key, value, ok := splitYAMLEntry(line.text)
+		if !ok {
+			return nil, fmt.Errorf("строка %d: ожидается \"ключ: значение\": %s", line.number, line.text)
+		}
+		p.pos++
+
+		var child *yamlNode
+		var err error
+		if value != "" {
+			child, err = parseYAMLValue(value, line.number)
+		} else if p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLSequenceItem(p.lines[p.pos].text) {
+			// Список может начинаться с того же отступа, что и ключ
+			child, err = p.sequence(indent)
+		} else {
+			child, err = p.nested(indent, line.number)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := node.Map[key]; !ok {
+			node.Keys = append(node.Keys, key)
+		}
+		node.Map[key] = child
+	}
+	return node, nil
+}
+
+// parseYAMLValue разбирает значение в строке: однострочный список,
+// однострочное отображение или скаляр
+func parseYAMLValue(text string, line int) (*yamlNode, error) {
+	switch {
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("строка %d: незакрытый список: %s", line, text)
+		}
+		node := &yamlNode{Kind: yamlList, Line: line}
+		for _, item := range splitYAMLFlow(text[1 : len(text)-1]) {
+			child, err := parseYAMLValue(item, line)
+			if err != nil {
+				return nil, err
+			}
+			node.List = append(node.List, child)
+		}
+		return node, nil
+	case strings.HasPrefix(text, "{"):
+		if !strings.HasSuffix(text, "}") {
+			return nil, fmt.Errorf("строка %d: незакрытое отображение: %s", line, text)
+		}
+		node := &yamlNode{Kind: yamlMap, Map: make(map[string]*yamlNode), Line: line}
+		for _, item := range splitYAMLFlow(text[1 : len(text)-1]) {
+			key, value, ok := splitYAMLEntry(item)
+			if !ok {
+				return nil, fmt.Errorf("строка %d: ожидается \"ключ: значение\": %s", line, item)
+			}
+			child, err := parseYAMLValue(value, line)
+			if err != nil {
+				return nil, err
+			}
+			node.Keys = append(node.Keys, key)
+			node.Map[key] = child
+		}
+		return node, nil
+	default:
+		return &yamlNode{Kind: yamlScalar, Value: unquoteYAML(text), Line: line}, nil
+	}
+}
+
+// splitYAMLFlow разбивает содержимое однострочного списка или отображения
+// по запятым верхнего уровня
+func splitYAMLFlow(text string) []string {
+	var items []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && isYAMLTokenStart(text, i):
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(text[start:]); last != "" || len(items) > 0 {
+		items = append(items, last)
+	}
+	return items
+}
+
+// splitYAMLEntry разделяет строку "ключ: значение"
+func splitYAMLEntry(text string) (string, string, bool) {
+	if strings.HasPrefix(text, `"`) || strings.HasPrefix(text, "'") {
+		end := strings.IndexByte(text[1:], text[0])
+		if end < 0 {
+			return "", "", false
+		}
+		key, rest := text[:end+2], text[end+2:]
+		if rest != ":" && !strings.HasPrefix(rest, ": ") {
+			return "", "", false
+		}
+		return unquoteYAML(key), strings.TrimSpace(rest[1:]), true
+	}
+	if key, ok := strings.CutSuffix(text, ":"); ok && !strings.Contains(key, ": ") {
+		return strings.TrimSpace(key), "", true
+	}
+	key, value, ok := strings.Cut(text, ": ")
+	return strings.TrimSpace(key), strings.TrimSpace(value), ok
+}
+
+// isYAMLSequenceItem проверяет, что строка - элемент блочного списка
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// isYAMLMappingEntry проверяет, что элемент списка начинает отображение
+func isYAMLMappingEntry(text string) bool {
+	if strings.ContainsAny(text[:1], `[{"'`) {
+		return false
+	}
+	_, _, ok := splitYAMLEntry(text)
+	return ok
+}
+
+// unquoteYAML снимает кавычки со скаляра
+func unquoteYAML(text string) string {
+	if len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"' {
+		if value, err := strconv.Unquote(text); err == nil {
+			return value
+		}
+		return text[1 : len(text)-1]
+	}
+	if len(text) >= 2 && text[0] == '\'' && text[len(text)-1] == '\'' {
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'")
+	}
+	return text
+}
+
+// stripYAMLComment удаляет комментарий в конце строки вне кавычек
+func stripYAMLComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && isYAMLTokenStart(text, i):
+			quote = c
+		case c == '#' && i > 0 && (text[i-1] == ' ' || text[i-1] == '\t'):
+			return strings.TrimSpace(text[:i])
+		}
+	}
+	return text
+}
+
+// isYAMLTokenStart проверяет, что символ начинает значение: кавычка внутри
+// скаляра без кавычек (апостроф в слове) строку в кавычках не открывает
+func isYAMLTokenStart(text string, i int) bool {
+	return i == 0 || strings.ContainsRune(" \t[{,:", rune(text[i-1]))
+}
+
+// quoteYAML записывает строку в двойных кавычках
+func quoteYAML(text string) string {
+	return strconv.Quote(text)
+}
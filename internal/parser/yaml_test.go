@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"testing"
+)
+
+// TestParseYAML тестирует разбор подмножества YAML
+func TestParseYAML(t *testing.T) {
+	text := "# заголовок\n" +
+		"version: 1\n" +
+		"name: \"Pad: \\\"wireless\\\" # 2\" # комментарий\n" +
+		"owner: 'it''s'\n" +
+		"note: it's # plain\n" +
+		"empty:\n" +
+		"flow: {a: 1, b: [2, 3]}\n" +
+		"items:\n" +
+		"- node: /dev/input/event5\n" +
+		"  codes:\n" +
+		"    1: [304, 305] # EV_KEY\n" +
+		"  events:\n" +
+		"  - evdev:\n" +
+		"    - [  0, 000016,   1, 304,   1]\n" +
+		"    - [  0, 000016,   0,   0,   0]\n" +
+		"  # evemu: 0.5 # marker\n" +
+		"-\n" +
+		"  - nested\n"
+
+	root, comments, err := parseYAML([]byte(text))
+	if err != nil {
+		t.Fatalf("parseYAML failed: %v", err)
+	}
+	if len(comments) != 2 || comments[1].Line != 16 || comments[1].Text != "# evemu: 0.5 # marker" {
+		t.Errorf("Unexpected comments: %+v", comments)
+	}
+	for key, expected := range map[string]string{
+		"version": "1",
+		"name":    `Pad: "wireless" # 2`,
+		"owner":   "it's",
+		"note":    "it's",
+		"empty":   "",
+	} {
+		if value := root.get(key).valueOrEmpty(); value != expected {
+			t.Errorf("%s: expected %q, got %q", key, expected, value)
+		}
+	}
+	if b, err := root.get("flow").get("b").ints(); err != nil || len(b) != 2 || b[1] != 3 {
+		t.Errorf("Unexpected flow mapping: %v, %v", b, err)
+	}
+
+	items := root.get("items")
+	if items == nil || len(items.List) != 2 {
+		t.Fatalf("Expected 2 items, got %+v", items)
+	}
+	device := items.List[0]
+	if device.get("node").valueOrEmpty() != "/dev/input/event5" {
+		t.Errorf("Unexpected node: %+v", device.get("node"))
+	}
+	if codes, err := device.get("codes").get("1").ints(); err != nil || len(codes) != 2 || codes[0] != 304 {
+		t.Errorf("Unexpected codes: %v, %v", codes, err)
+	}
+	frames := device.get("events")
+	if frames == nil || len(frames.List) != 1 {
+		t.Fatalf("Expected 1 frame, got %+v", frames)
+	}
+	rows := frames.List[0].get("evdev")
+	if rows == nil || len(rows.List) != 2 || rows.List[1].Line != 15 {
+		t.Fatalf("Unexpected frame: %+v", rows)
+	}
+	if row, err := rows.List[0].ints(); err != nil || row[1] != 16 || row[3] != 304 {
+		t.Errorf("Unexpected row: %v, %v", row, err)
+	}
+	if nested := items.List[1]; nested.Kind != yamlList || nested.List[0].Value != "nested" {
+		t.Errorf("Unexpected nested list: %+v", nested)
+	}
+
+	for _, bad := range []string{"a: 1\n   b: 2\n", "a: [1, 2\n", "just text\n"} {
+		if _, _, err := parseYAML([]byte(bad)); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}